@@ -3,15 +3,24 @@ package api
 import (
 	"log"
 
+	"github.com/RicochetStudios/aurora/api/middleware"
 	"github.com/RicochetStudios/aurora/api/routes"
+	"github.com/RicochetStudios/aurora/config"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 )
 
 func Start() {
-	app := fiber.New()
+	// Bodies are streamed, so uploads are not held in memory, and LimitBody rejects those over the limit.
+	limit := config.Current().UploadLimit * 1024 * 1024
+	app := fiber.New(fiber.Config{
+		BodyLimit:                    limit,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
 	app.Use(cors.New())
+	app.Use(middleware.LimitBody(limit))
 
 	api := app.Group("/api")
 
@@ -27,6 +36,33 @@ func Start() {
 	// Run the auth router.
 	routes.AuthRouter(api)
 
+	// Run the audit router.
+	routes.AuditRouter(api)
+
+	// Run the cluster router.
+	routes.ClusterRouter(api)
+
+	// Run the mods router.
+	routes.ModsRouter(api)
+
+	// Run the world router.
+	routes.WorldRouter(api)
+
+	// Run the files router.
+	routes.FilesRouter(api)
+
+	// Run the players router.
+	routes.PlayersRouter(api)
+
+	// Run the schemas router.
+	routes.SchemasRouter(api)
+
+	// Run the jobs router.
+	routes.JobsRouter(api)
+
+	// Run the admin router.
+	routes.AdminRouter(api)
+
 	// Start the API.
-	log.Fatal(app.Listen(":6969"))
+	log.Fatal(app.Listen(config.Current().Listen))
 }
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/RicochetStudios/aurora/audit"
+	"github.com/RicochetStudios/aurora/db"
+	"github.com/RicochetStudios/aurora/jobs"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// auditInstanceKey is the local holding the instance affected by the request.
+	auditInstanceKey string = "auditInstance"
+
+	// auditBeforeKey is the local holding the state before the request.
+	auditBeforeKey string = "auditBefore"
+
+	// auditAfterKey is the local holding the state after the request.
+	auditAfterKey string = "auditAfter"
+
+	// auditJobKey is the local holding the job started by the request.
+	auditJobKey string = "auditJob"
+)
+
+// AuditInstance records the instance affected by the current request.
+func AuditInstance(ctx *fiber.Ctx, id string) {
+	ctx.Locals(auditInstanceKey, id)
+}
+
+// AuditBefore records the state before the current request modifies it.
+func AuditBefore(ctx *fiber.Ctx, before any) {
+	ctx.Locals(auditBeforeKey, before)
+}
+
+// AuditAfter records the state after the current request has modified it.
+func AuditAfter(ctx *fiber.Ctx, after any) {
+	ctx.Locals(auditAfterKey, after)
+}
+
+// AuditJob records the job started to carry out the current request, whose outcome is audited once it finishes.
+func AuditJob(ctx *fiber.Ctx, job types.Job) {
+	ctx.Locals(auditJobKey, job.ID)
+}
+
+// Audit records an audit event for the action once the rest of the chain has run.
+// Handlers describe what they changed with AuditInstance, AuditBefore and AuditAfter.
+func Audit(action string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		// Run the handler first, so the outcome is known.
+		chainErr := ctx.Next()
+
+		event := types.AuditEvent{
+			Actor:      audit.Anonymous,
+			Action:     action,
+			Result:     audit.ResultSuccess,
+			Timestamp:  time.Now().UTC(),
+			SourceIP:   ctx.IP(),
+			AuthMethod: "none",
+		}
+		if actor, ok := ctx.Locals(ActorKey).(string); ok && actor != "" {
+			event.Actor = actor
+		}
+		if method, ok := ctx.Locals(AuthMethodKey).(string); ok && method != "" {
+			event.AuthMethod = method
+		}
+		if id, ok := ctx.Locals(auditInstanceKey).(string); ok {
+			event.InstanceID = id
+		}
+
+		// Work out whether the handler failed, and why.
+		if chainErr != nil {
+			event.Result = audit.ResultFailure
+			event.Error = chainErr.Error()
+		} else if ctx.Response().StatusCode() >= fiber.StatusBadRequest {
+			event.Result = audit.ResultFailure
+			var body struct {
+				Error string `json:"error"`
+			}
+			if err := json.Unmarshal(ctx.Response().Body(), &body); err == nil {
+				event.Error = body.Error
+			}
+		}
+
+		// A started job has not succeeded yet, so its outcome is recorded when it finishes.
+		jobID, _ := ctx.Locals(auditJobKey).(string)
+		if jobID != "" && event.Result == audit.ResultSuccess {
+			event.Result = audit.ResultAccepted
+			event.JobID = jobID
+		}
+
+		// Describe the requested changes, once the handler knows what they are.
+		if ctx.Locals(auditAfterKey) != nil {
+			changes, err := audit.Diff(ctx.Locals(auditBeforeKey), ctx.Locals(auditAfterKey))
+			if err != nil {
+				log.Printf("error calculating audit changes for %v: %v\n", action, err)
+			}
+			event.Changes = changes
+		}
+
+		// Failing to audit must not change the response the user receives.
+		if _, err := db.AddAuditEvent(ctx.Context(), event); err != nil {
+			log.Printf("error recording audit event for %v: %v\n", action, err)
+		}
+		if event.JobID != "" {
+			go auditOutcome(event)
+		}
+
+		return chainErr
+	}
+}
+
+// auditOutcome waits for the job of an accepted request to finish, then records an audit event
+// for its outcome, with the same actor, action, instance and changes as the request.
+func auditOutcome(event types.AuditEvent) {
+	updates, _, ok := jobs.Subscribe(event.JobID)
+	if !ok {
+		log.Printf("error recording outcome of job %v for %v: job not found\n", event.JobID, event.Action)
+		return
+	}
+	var job types.Job
+	for job = range updates {
+	}
+
+	event.ID = ""
+	event.Timestamp = job.UpdatedAt.UTC()
+	event.Result = audit.ResultSuccess
+	if job.Status == jobs.StatusFailed {
+		event.Result = audit.ResultFailure
+		event.Error = job.Error
+	}
+	if _, err := db.AddAuditEvent(context.Background(), event); err != nil {
+		log.Printf("error recording outcome of job %v for %v: %v\n", event.JobID, event.Action, err)
+	}
+}
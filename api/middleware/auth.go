@@ -7,6 +7,17 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
+const (
+	// ActorKey is the local holding the uid of the authenticated user.
+	ActorKey string = "actor"
+
+	// AuthMethodKey is the local holding how the user was authenticated.
+	AuthMethodKey string = "authMethod"
+
+	// AuthMethodFirebase is the auth method for users with a Firebase ID token.
+	AuthMethodFirebase string = "firebase"
+)
+
 // ProtectRoute will check the user claims from jwt token
 func ProtectRoute(ctx *fiber.Ctx) error {
 
@@ -25,6 +36,10 @@ func ProtectRoute(ctx *fiber.Ctx) error {
 		return err
 	}
 
+	// remember who made the request, so it can be audited
+	ctx.Locals(ActorKey, decoded.UID)
+	ctx.Locals(AuthMethodKey, AuthMethodFirebase)
+
 	// get user claims from token
 	claims := decoded.Claims
 
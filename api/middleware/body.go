@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/RicochetStudios/aurora/api/presenter"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LimitBody rejects requests with bodies larger than a limit in bytes, before any of the body is read.
+// Request bodies are streamed, so fiber passes on bodies larger than its body limit instead of rejecting them,
+// and bodies of unknown length are refused as they could not be limited.
+func LimitBody(limit int) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		length := ctx.Request().Header.ContentLength()
+		if length == -1 {
+			ctx.Set(fiber.HeaderConnection, "close")
+			ctx.Status(http.StatusLengthRequired)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("request body must have a Content-Length")))
+		}
+		if length > limit {
+			ctx.Set(fiber.HeaderConnection, "close")
+			ctx.Status(http.StatusRequestEntityTooLarge)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("request body of %v bytes is larger than the limit of %v bytes", length, limit)))
+		}
+
+		chainErr := ctx.Next()
+
+		// Whatever a handler leaves unread of a streamed body would be read as the next request on the connection.
+		// The rest of an accepted body is skipped, but refused requests close the connection rather than read it.
+		if stream := ctx.Request().BodyStream(); stream != nil {
+			if ctx.Response().StatusCode() >= http.StatusBadRequest {
+				ctx.Set(fiber.HeaderConnection, "close")
+			} else if _, err := io.Copy(io.Discard, stream); err != nil {
+				ctx.Set(fiber.HeaderConnection, "close")
+			}
+		}
+		return chainErr
+	}
+}
@@ -0,0 +1,16 @@
+package presenter
+
+import (
+	"github.com/RicochetStudios/aurora/config"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SettingsSuccessResponse is the SuccessResponse that will be passed in the response by handler.
+func SettingsSuccessResponse(data config.Settings) *fiber.Map {
+	return &fiber.Map{
+		"status": true,
+		"data":   data,
+		"error":  nil,
+	}
+}
@@ -0,0 +1,25 @@
+package presenter
+
+import (
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AuditSuccessResponse is the SuccessResponse that will be passed in the response by handler.
+func AuditSuccessResponse(data []types.AuditEvent) *fiber.Map {
+	return &fiber.Map{
+		"status": true,
+		"data":   data,
+		"error":  nil,
+	}
+}
+
+// AuditErrorResponse is the singular ErrorResponse that will be passed in the response by handler.
+func AuditErrorResponse(err error) *fiber.Map {
+	return &fiber.Map{
+		"status": false,
+		"data":   "",
+		"error":  err.Error(),
+	}
+}
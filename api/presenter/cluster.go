@@ -0,0 +1,34 @@
+package presenter
+
+import (
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ClusterSuccessResponse is the SuccessResponse that will be passed in the response by handler.
+func ClusterSuccessResponse(data types.Cluster) *fiber.Map {
+	return &fiber.Map{
+		"status": true,
+		"data":   data,
+		"error":  nil,
+	}
+}
+
+// ScheduleSuccessResponse is the SuccessResponse for a scheduled server that will be passed in the response by handler.
+func ScheduleSuccessResponse(data types.ScheduleResult) *fiber.Map {
+	return &fiber.Map{
+		"status": true,
+		"data":   data,
+		"error":  nil,
+	}
+}
+
+// ClusterErrorResponse is the singular ErrorResponse that will be passed in the response by handler.
+func ClusterErrorResponse(err error) *fiber.Map {
+	return &fiber.Map{
+		"status": false,
+		"data":   "",
+		"error":  err.Error(),
+	}
+}
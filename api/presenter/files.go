@@ -0,0 +1,25 @@
+package presenter
+
+import (
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// FilesSuccessResponse is the SuccessResponse for the files of a directory that will be passed in the response by handler.
+func FilesSuccessResponse(data []types.File) *fiber.Map {
+	return &fiber.Map{
+		"status": true,
+		"data":   data,
+		"error":  nil,
+	}
+}
+
+// FileSuccessResponse is the SuccessResponse for a single file that will be passed in the response by handler.
+func FileSuccessResponse(data types.File) *fiber.Map {
+	return &fiber.Map{
+		"status": true,
+		"data":   data,
+		"error":  nil,
+	}
+}
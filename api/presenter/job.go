@@ -0,0 +1,36 @@
+package presenter
+
+import (
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// JobSuccessResponse is the SuccessResponse that will be passed in the response by handler.
+func JobSuccessResponse(data types.Job) *fiber.Map {
+	return &fiber.Map{
+		"status": true,
+		"data":   data,
+		"error":  nil,
+	}
+}
+
+// JobErrorResponse is the singular ErrorResponse that will be passed in the response by handler.
+func JobErrorResponse(err error) *fiber.Map {
+	return &fiber.Map{
+		"status": false,
+		"data":   "",
+		"error":  err.Error(),
+	}
+}
+
+// ModpackImportResponse is the SuccessResponse that will be passed in the response by handler,
+// with the job creating the server and a summary of the modpack it was imported from.
+func ModpackImportResponse(data types.Job, modpack types.Modpack) *fiber.Map {
+	return &fiber.Map{
+		"status":  true,
+		"data":    data,
+		"modpack": modpack,
+		"error":   nil,
+	}
+}
@@ -0,0 +1,16 @@
+package presenter
+
+import (
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ModsSuccessResponse is the SuccessResponse that will be passed in the response by handler.
+func ModsSuccessResponse(data types.ModManifest) *fiber.Map {
+	return &fiber.Map{
+		"status": true,
+		"data":   data,
+		"error":  nil,
+	}
+}
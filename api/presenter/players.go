@@ -0,0 +1,16 @@
+package presenter
+
+import (
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// PlayersSuccessResponse is the SuccessResponse for the players of a list that will be passed in the response by handler.
+func PlayersSuccessResponse(data []types.Player) *fiber.Map {
+	return &fiber.Map{
+		"status": true,
+		"data":   data,
+		"error":  nil,
+	}
+}
@@ -0,0 +1,16 @@
+package presenter
+
+import (
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// PropertiesSuccessResponse is the SuccessResponse for the game properties of a server that will be passed in the response by handler.
+func PropertiesSuccessResponse(data []types.Property) *fiber.Map {
+	return &fiber.Map{
+		"status": true,
+		"data":   data,
+		"error":  nil,
+	}
+}
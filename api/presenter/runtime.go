@@ -0,0 +1,25 @@
+package presenter
+
+import (
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RuntimeStatusSuccessResponse is the SuccessResponse that will be passed in the response by handler.
+func RuntimeStatusSuccessResponse(data types.RuntimeStatus) *fiber.Map {
+	return &fiber.Map{
+		"status": true,
+		"data":   data,
+		"error":  nil,
+	}
+}
+
+// StatsSuccessResponse is the SuccessResponse that will be passed in the response by handler.
+func StatsSuccessResponse(data types.Stats) *fiber.Map {
+	return &fiber.Map{
+		"status": true,
+		"data":   data,
+		"error":  nil,
+	}
+}
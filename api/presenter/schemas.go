@@ -0,0 +1,34 @@
+package presenter
+
+import (
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SchemasSuccessResponse is the SuccessResponse for the game schemas a server may be created from that will be passed in the response by handler.
+func SchemasSuccessResponse(data []types.GameSchema) *fiber.Map {
+	return &fiber.Map{
+		"status": true,
+		"data":   data,
+		"error":  nil,
+	}
+}
+
+// SchemaSuccessResponse is the SuccessResponse for a game schema that will be passed in the response by handler.
+func SchemaSuccessResponse(data types.GameSchema) *fiber.Map {
+	return &fiber.Map{
+		"status": true,
+		"data":   data,
+		"error":  nil,
+	}
+}
+
+// SchemaErrorResponse is the ErrorResponse that will be passed in the response by handler.
+func SchemaErrorResponse(err error) *fiber.Map {
+	return &fiber.Map{
+		"status": false,
+		"data":   "",
+		"error":  err.Error(),
+	}
+}
@@ -15,6 +15,24 @@ func ServerSuccessResponse(data *types.Server) *fiber.Map {
 	}
 }
 
+// InstanceSuccessResponse is the SuccessResponse for a single instance that will be passed in the response by handler.
+func InstanceSuccessResponse(data types.Instance) *fiber.Map {
+	return &fiber.Map{
+		"status": true,
+		"data":   data,
+		"error":  nil,
+	}
+}
+
+// InstancesSuccessResponse is the SuccessResponse for a list of instances that will be passed in the response by handler.
+func InstancesSuccessResponse(data []types.Instance) *fiber.Map {
+	return &fiber.Map{
+		"status": true,
+		"data":   data,
+		"error":  nil,
+	}
+}
+
 // ServerEmptyResponse is a successful response where no server is found that will be passed in the response by handler.
 func ServerEmptyResponse() *fiber.Map {
 	return &fiber.Map{
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/RicochetStudios/aurora/api/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminRouter is the router for all admin methods.
+func AdminRouter(app fiber.Router) {
+	// Get the resolved settings.
+	app.Get("/admin/config", services.GetSettings())
+}
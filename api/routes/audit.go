@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/RicochetStudios/aurora/api/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AuditRouter is the router for all audit methods.
+func AuditRouter(app fiber.Router) {
+	// List audit events.
+	app.Get("/audit", services.GetAuditEvents())
+}
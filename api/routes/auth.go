@@ -1,8 +1,10 @@
 package routes
 
 import (
+	"github.com/RicochetStudios/aurora/api/middleware"
 	"github.com/RicochetStudios/aurora/api/models"
 	"github.com/RicochetStudios/aurora/api/services"
+	"github.com/RicochetStudios/aurora/audit"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -10,7 +12,7 @@ import (
 // AuthRouter is the router for all auth methods.
 func AuthRouter(app fiber.Router) {
 	// set user auth.
-	app.Post("/setAuthUser", func(c *fiber.Ctx) error {
+	app.Post("/setAuthUser", middleware.Audit(audit.ActionAuthGrant), func(c *fiber.Ctx) error {
 
 		// get uid from body.
 		response := new(models.UserUid)
@@ -33,6 +35,9 @@ func AuthRouter(app fiber.Router) {
 			})
 		}
 
+		// record the granted membership.
+		middleware.AuditAfter(c, fiber.Map{"uid": uid, "member": added})
+
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
 			"status": true,
 			"error": nil,
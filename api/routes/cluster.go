@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/RicochetStudios/aurora/api/middleware"
+	"github.com/RicochetStudios/aurora/api/services"
+	"github.com/RicochetStudios/aurora/audit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ClusterRouter is the router for all cluster methods.
+func ClusterRouter(app fiber.Router) {
+	// Get the cluster and its instances.
+	app.Get("/cluster", services.GetCluster())
+
+	// Create a server on the best node of the cluster.
+	app.Post("/cluster/servers", middleware.Audit(audit.ActionServerSchedule), services.ScheduleServer())
+}
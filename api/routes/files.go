@@ -0,0 +1,46 @@
+package routes
+
+import (
+	"github.com/RicochetStudios/aurora/api/middleware"
+	"github.com/RicochetStudios/aurora/api/services"
+	"github.com/RicochetStudios/aurora/audit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// FilesRouter is the router for all file manager methods.
+func FilesRouter(app fiber.Router) {
+	// List a directory of a server, or its volumes.
+	app.Get("/servers/:id/files", services.ListFiles())
+
+	// Read and write a file of a server.
+	app.Get("/servers/:id/files/content", services.ReadFile())
+	app.Put("/servers/:id/files/content", middleware.Audit(audit.ActionFileWrite), services.WriteFile())
+
+	// Rename a file or directory of a server.
+	app.Post("/servers/:id/files/rename", middleware.Audit(audit.ActionFileRename), services.RenameFile())
+
+	// Create a directory of a server.
+	app.Post("/servers/:id/files/mkdir", middleware.Audit(audit.ActionFileMkdir), services.MakeDirectory())
+
+	// Delete a file or directory of a server.
+	app.Delete("/servers/:id/files", middleware.Audit(audit.ActionFileDelete), services.DeleteFile())
+
+	// The single server routes act on the first server of this node.
+
+	// List a directory of the server, or its volumes.
+	app.Get("/server/files", services.ListFiles())
+
+	// Read and write a file of the server.
+	app.Get("/server/files/content", services.ReadFile())
+	app.Put("/server/files/content", middleware.Audit(audit.ActionFileWrite), services.WriteFile())
+
+	// Rename a file or directory of the server.
+	app.Post("/server/files/rename", middleware.Audit(audit.ActionFileRename), services.RenameFile())
+
+	// Create a directory of the server.
+	app.Post("/server/files/mkdir", middleware.Audit(audit.ActionFileMkdir), services.MakeDirectory())
+
+	// Delete a file or directory of the server.
+	app.Delete("/server/files", middleware.Audit(audit.ActionFileDelete), services.DeleteFile())
+}
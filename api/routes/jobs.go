@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/RicochetStudios/aurora/api/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// JobsRouter is the router for all job methods.
+func JobsRouter(app fiber.Router) {
+	// Get the progress of a job.
+	app.Get("/jobs/:id", services.GetJob())
+
+	// Stream the progress of a job as server sent events.
+	app.Get("/jobs/:id/events", services.StreamJob())
+}
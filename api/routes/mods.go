@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"github.com/RicochetStudios/aurora/api/middleware"
+	"github.com/RicochetStudios/aurora/api/services"
+	"github.com/RicochetStudios/aurora/audit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ModsRouter is the router for all mod methods.
+func ModsRouter(app fiber.Router) {
+	// List the mods of a server.
+	app.Get("/servers/:id/mods", services.ListMods())
+
+	// Upload a mod to a server.
+	app.Post("/servers/:id/mods", middleware.Audit(audit.ActionModAdd), services.AddMod())
+
+	// Enable and disable a mod of a server.
+	app.Post("/servers/:id/mods/:name/enable", middleware.Audit(audit.ActionModEnable), services.EnableMod())
+	app.Post("/servers/:id/mods/:name/disable", middleware.Audit(audit.ActionModDisable), services.DisableMod())
+
+	// Remove a mod from a server.
+	app.Delete("/servers/:id/mods/:name", middleware.Audit(audit.ActionModRemove), services.RemoveMod())
+
+	// The single server routes act on the first server of this node.
+
+	// List the mods of the server.
+	app.Get("/server/mods", services.ListMods())
+
+	// Upload a mod to the server.
+	app.Post("/server/mods", middleware.Audit(audit.ActionModAdd), services.AddMod())
+
+	// Enable and disable a mod of the server.
+	app.Post("/server/mods/:name/enable", middleware.Audit(audit.ActionModEnable), services.EnableMod())
+	app.Post("/server/mods/:name/disable", middleware.Audit(audit.ActionModDisable), services.DisableMod())
+
+	// Remove a mod from the server.
+	app.Delete("/server/mods/:name", middleware.Audit(audit.ActionModRemove), services.RemoveMod())
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/RicochetStudios/aurora/api/middleware"
+	"github.com/RicochetStudios/aurora/api/services"
+	"github.com/RicochetStudios/aurora/audit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// PlayersRouter is the router for all player list methods.
+func PlayersRouter(app fiber.Router) {
+	// List, add and remove the players on a list of a server.
+	app.Get("/servers/:id/players/:list", services.ListPlayers())
+	app.Post("/servers/:id/players/:list", middleware.Audit(audit.ActionPlayerAdd), services.AddPlayer())
+	app.Delete("/servers/:id/players/:list/:player", middleware.Audit(audit.ActionPlayerRemove), services.RemovePlayer())
+
+	// The single server routes act on the first server of this node.
+
+	// List, add and remove the players on a list of the server.
+	app.Get("/server/players/:list", services.ListPlayers())
+	app.Post("/server/players/:list", middleware.Audit(audit.ActionPlayerAdd), services.AddPlayer())
+	app.Delete("/server/players/:list/:player", middleware.Audit(audit.ActionPlayerRemove), services.RemovePlayer())
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/RicochetStudios/aurora/api/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SchemasRouter is the router for all game schema methods.
+func SchemasRouter(app fiber.Router) {
+	// List the games servers may be created from.
+	app.Get("/schemas", services.ListSchemas())
+
+	// Get the JSON Schema of game schema files.
+	// It is registered before the game route, so it is not taken for a game named meta.
+	app.Get("/schemas/meta", services.GetSchemaMeta())
+
+	// Get a game servers may be created from.
+	app.Get("/schemas/:game", services.GetGameSchema())
+}
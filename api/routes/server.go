@@ -1,19 +1,79 @@
 package routes
 
 import (
+	"github.com/RicochetStudios/aurora/api/middleware"
 	"github.com/RicochetStudios/aurora/api/services"
+	"github.com/RicochetStudios/aurora/audit"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 // ServerRouter is the router for all server methods.
 func ServerRouter(app fiber.Router) {
+	// List servers.
+	app.Get("/servers", services.ListServers())
+
+	// Create a server.
+	app.Post("/servers", middleware.Audit(audit.ActionServerCreate), services.CreateServer())
+
+	// Create a server from a modpack archive.
+	app.Post("/servers/import", middleware.Audit(audit.ActionServerImport), services.ImportModpack())
+
+	// Get server details.
+	app.Get("/servers/:id", services.GetServer())
+
+	// Update server details.
+	app.Put("/servers/:id", middleware.Audit(audit.ActionServerUpdate), services.UpdateServer())
+
+	// Remove server.
+	app.Delete("/servers/:id", middleware.Audit(audit.ActionServerRemove), services.RemoveServer())
+
+	// Render the Kubernetes manifests of a server.
+	app.Get("/servers/:id/manifests", services.GetManifests())
+
+	// Start and stop a server.
+	app.Post("/servers/:id/start", middleware.Audit(audit.ActionServerStart), services.StartServer())
+	app.Post("/servers/:id/stop", middleware.Audit(audit.ActionServerStop), services.StopServer())
+
+	// Move a server to the current digest of its image.
+	app.Post("/servers/:id/image", middleware.Audit(audit.ActionServerImageUpdate), services.UpdateServerImage())
+
+	// Get the state, logs and resource usage of a server.
+	app.Get("/servers/:id/status", services.GetServerStatus())
+	app.Get("/servers/:id/logs", services.GetServerLogs())
+	app.Get("/servers/:id/stats", services.GetServerStats())
+
+	// Get and change the game properties of a server.
+	app.Get("/servers/:id/settings", services.GetProperties())
+	app.Patch("/servers/:id/settings", middleware.Audit(audit.ActionServerProperties), services.UpdateProperties())
+
+	// The single server routes act on the first server of this node.
+
 	// Get server details.
 	app.Post("/server", services.GetServer())
 
 	// Update server details.
-	app.Put("/server", services.UpdateServer())
+	app.Put("/server", middleware.Audit(audit.ActionServerUpdate), services.UpdateServer())
 
 	// Remove server.
-	app.Delete("/server", services.RemoveServer())
+	app.Delete("/server", middleware.Audit(audit.ActionServerRemove), services.RemoveServer())
+
+	// Render the Kubernetes manifests of the server.
+	app.Get("/server/manifests", services.GetManifests())
+
+	// Start and stop the server.
+	app.Post("/server/start", middleware.Audit(audit.ActionServerStart), services.StartServer())
+	app.Post("/server/stop", middleware.Audit(audit.ActionServerStop), services.StopServer())
+
+	// Move the server to the current digest of its image.
+	app.Post("/server/image", middleware.Audit(audit.ActionServerImageUpdate), services.UpdateServerImage())
+
+	// Get the state, logs and resource usage of the server.
+	app.Get("/server/status", services.GetServerStatus())
+	app.Get("/server/logs", services.GetServerLogs())
+	app.Get("/server/stats", services.GetServerStats())
+
+	// Get and change the game properties of the server.
+	app.Get("/server/settings", services.GetProperties())
+	app.Patch("/server/settings", middleware.Audit(audit.ActionServerProperties), services.UpdateProperties())
 }
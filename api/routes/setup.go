@@ -1,7 +1,9 @@
 package routes
 
 import (
+	"github.com/RicochetStudios/aurora/api/middleware"
 	"github.com/RicochetStudios/aurora/api/services"
+	"github.com/RicochetStudios/aurora/audit"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -9,5 +11,5 @@ import (
 // SetupRouter is the router for all setup methods.
 func SetupRouter(app fiber.Router) {
 	// Run setup.
-	app.Post("/setup", services.Setup())
+	app.Post("/setup", middleware.Audit(audit.ActionSetup), services.Setup())
 }
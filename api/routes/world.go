@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/RicochetStudios/aurora/api/middleware"
+	"github.com/RicochetStudios/aurora/api/services"
+	"github.com/RicochetStudios/aurora/audit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// WorldRouter is the router for all world methods.
+func WorldRouter(app fiber.Router) {
+	// Download the world of a server.
+	app.Get("/servers/:id/world", services.DownloadWorld())
+
+	// Replace the world of a server with an uploaded archive.
+	app.Put("/servers/:id/world", middleware.Audit(audit.ActionWorldUpload), services.UploadWorld())
+
+	// The single server routes act on the first server of this node.
+
+	// Download the world of the server.
+	app.Get("/server/world", services.DownloadWorld())
+
+	// Replace the world of the server with an uploaded archive.
+	app.Put("/server/world", middleware.Audit(audit.ActionWorldUpload), services.UploadWorld())
+}
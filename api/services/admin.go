@@ -0,0 +1,28 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/RicochetStudios/aurora/api/middleware"
+	"github.com/RicochetStudios/aurora/api/presenter"
+	"github.com/RicochetStudios/aurora/config"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetSettings returns the settings Aurora is running with, with secrets redacted.
+func GetSettings() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		ctx.Status(http.StatusOK)
+		return ctx.JSON(presenter.SettingsSuccessResponse(config.Current().Redacted()))
+	}
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/RicochetStudios/aurora/api/middleware"
+	"github.com/RicochetStudios/aurora/api/presenter"
+	"github.com/RicochetStudios/aurora/audit"
+	"github.com/RicochetStudios/aurora/db"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetAuditEvents lists the recorded audit events, newest first.
+// Events can be filtered with the actor, action, since and until query parameters,
+// where since and until are RFC 3339 timestamps.
+func GetAuditEvents() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		// Build the filter from the query parameters.
+		filter := audit.Filter{
+			Actor:  ctx.Query("actor"),
+			Action: ctx.Query("action"),
+			Limit:  ctx.QueryInt("limit", 0),
+		}
+		if since := ctx.Query("since"); since != "" {
+			if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+				ctx.Status(http.StatusBadRequest)
+				return ctx.JSON(presenter.AuditErrorResponse(fmt.Errorf("error in provided since: \n%v", err)))
+			}
+		}
+		if until := ctx.Query("until"); until != "" {
+			if filter.Until, err = time.Parse(time.RFC3339, until); err != nil {
+				ctx.Status(http.StatusBadRequest)
+				return ctx.JSON(presenter.AuditErrorResponse(fmt.Errorf("error in provided until: \n%v", err)))
+			}
+		}
+
+		// Read the matching events.
+		events, err := db.GetAuditEvents(ctx.Context(), filter)
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.AuditErrorResponse(fmt.Errorf("error reading audit events from the database: \n%v", err)))
+		}
+
+		ctx.Status(http.StatusOK)
+		return ctx.JSON(presenter.AuditSuccessResponse(events))
+	}
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/RicochetStudios/aurora/api/middleware"
+	"github.com/RicochetStudios/aurora/api/presenter"
+	"github.com/RicochetStudios/aurora/capacity"
+	"github.com/RicochetStudios/aurora/config"
+	"github.com/RicochetStudios/aurora/db"
+	"github.com/RicochetStudios/aurora/scheduler"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetCluster gets the cluster this node belongs to and every node and instance registered to it.
+func GetCluster() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		// Get the cluster ID.
+		cfg, err := config.Read()
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.ClusterErrorResponse(fmt.Errorf("error reading from config: \n%v", err)))
+		}
+
+		// Read every node in the cluster.
+		nodes, err := db.ListNodes(ctx.Context())
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.ClusterErrorResponse(fmt.Errorf("error reading nodes from the database: \n%v", err)))
+		}
+
+		// Read every instance in the cluster.
+		instances, err := db.ListServers(ctx.Context())
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.ClusterErrorResponse(fmt.Errorf("error reading instances from the database: \n%v", err)))
+		}
+
+		ctx.Status(http.StatusOK)
+		return ctx.JSON(presenter.ClusterSuccessResponse(types.Cluster{
+			ID:          cfg.ClusterID,
+			Environment: config.Current().Environment,
+			Nodes:       nodes,
+			Instances:   instances,
+		}))
+	}
+}
+
+// ScheduleServer creates a server on the node of the cluster best able to host it,
+// within the requested placement constraints.
+func ScheduleServer() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		var request types.ScheduleRequest
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		// Check for errors in body.
+		if err := ctx.BodyParser(&request); err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ClusterErrorResponse(fmt.Errorf("error in provided body: \n%v", err)))
+		}
+
+		// Work out the resources the server needs.
+		gameSchema, err := schema.GetSchema(request.Server.Game.Name)
+		if err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ClusterErrorResponse(fmt.Errorf("error reading schema: \n%v", err)))
+		}
+		if _, err := schema.ChooseGame(gameSchema, request.Server.Game); err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ClusterErrorResponse(err))
+		}
+		requested, err := capacity.ForServer(gameSchema, request.Server)
+		if err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ClusterErrorResponse(fmt.Errorf("error reading server resources: \n%v", err)))
+		}
+
+		// Choose the node.
+		nodes, err := db.ListNodes(ctx.Context())
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.ClusterErrorResponse(fmt.Errorf("error reading nodes from the database: \n%v", err)))
+		}
+		chosen, err := scheduler.Place(nodes, requested, request.Placement)
+		if err != nil {
+			ctx.Status(http.StatusConflict)
+			return ctx.JSON(presenter.ClusterErrorResponse(fmt.Errorf("error placing server: \n%v", err)))
+		}
+
+		// Hand the server to the chosen node, as the caller.
+		job, err := scheduler.Dispatch(ctx.Context(), chosen, request.Server, ctx.Get("Authorization"))
+		if err != nil {
+			ctx.Status(http.StatusBadGateway)
+			return ctx.JSON(presenter.ClusterErrorResponse(fmt.Errorf("error creating server on node %v: \n%v", chosen.ID, err)))
+		}
+
+		result := types.ScheduleResult{
+			NodeID:   chosen.ID,
+			Instance: types.Instance{ID: job.InstanceID, Server: request.Server},
+			Job:      job,
+		}
+		middleware.AuditInstance(ctx, job.InstanceID)
+		middleware.AuditAfter(ctx, result)
+
+		ctx.Status(http.StatusAccepted)
+		return ctx.JSON(presenter.ScheduleSuccessResponse(result))
+	}
+}
@@ -0,0 +1,323 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/RicochetStudios/aurora/api/middleware"
+	"github.com/RicochetStudios/aurora/api/presenter"
+	"github.com/RicochetStudios/aurora/config"
+	"github.com/RicochetStudios/aurora/db"
+	"github.com/RicochetStudios/aurora/engine"
+	"github.com/RicochetStudios/aurora/files"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ListFiles gets the files of the directory in the path query parameter of a server,
+// or the volumes of the server if no path is given.
+func ListFiles() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		runtime, id, gameSchema, status, err := filesInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+
+		if ctx.Query("path") == "" {
+			volumes := make([]types.File, len(gameSchema.Volumes))
+			for i, volume := range gameSchema.Volumes {
+				volumes[i] = types.File{Name: volume.Name, Path: path.Clean(volume.Path), Dir: true}
+			}
+			ctx.Status(http.StatusOK)
+			return ctx.JSON(presenter.FilesSuccessResponse(volumes))
+		}
+
+		dir, status, err := filePath(ctx, gameSchema, ctx.Query("path"), false)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+
+		list, err := files.List(ctx.Context(), runtime, id, dir)
+		if err != nil {
+			ctx.Status(fileErrorStatus(err))
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error listing %v: \n%v", dir, err)))
+		}
+		for i := range list {
+			list[i].Writable = schema.Writable(gameSchema, list[i].Path)
+		}
+
+		ctx.Status(http.StatusOK)
+		return ctx.JSON(presenter.FilesSuccessResponse(list))
+	}
+}
+
+// ReadFile gets the content of the file in the path query parameter of a server, up to the file limit.
+func ReadFile() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		runtime, id, gameSchema, status, err := filesInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		file, status, err := filePath(ctx, gameSchema, ctx.Query("path"), false)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+
+		content, err := files.Read(ctx.Context(), runtime, id, file, fileLimit())
+		if err != nil {
+			ctx.Status(fileErrorStatus(err))
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+
+		ctx.Set(fiber.HeaderContentType, fiber.MIMEOctetStream)
+		ctx.Status(http.StatusOK)
+		return ctx.Send(content)
+	}
+}
+
+// WriteFile creates or replaces the file in the path query parameter of a server with the request body,
+// up to the file limit. The directory of the file must already exist.
+func WriteFile() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		runtime, id, gameSchema, status, err := filesInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditInstance(ctx, id)
+
+		file, status, err := filePath(ctx, gameSchema, ctx.Query("path"), true)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+
+		// The length is checked before reading the body, which LimitBody has ensured is given.
+		if length := int64(ctx.Request().Header.ContentLength()); length > fileLimit() {
+			ctx.Status(http.StatusRequestEntityTooLarge)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("%w of %v bytes: the body is %v bytes", files.ErrTooLarge, fileLimit(), length)))
+		}
+		content := ctx.Body()
+		if int64(len(content)) > fileLimit() {
+			ctx.Status(http.StatusRequestEntityTooLarge)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("%w of %v bytes", files.ErrTooLarge, fileLimit())))
+		}
+		middleware.AuditAfter(ctx, fiber.Map{"path": file, "size": len(content)})
+
+		if err := files.Write(ctx.Context(), runtime, id, file, content); err != nil {
+			ctx.Status(fileErrorStatus(err))
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+
+		ctx.Status(http.StatusOK)
+		return ctx.JSON(presenter.FileSuccessResponse(types.File{
+			Name:       path.Base(file),
+			Path:       file,
+			Size:       int64(len(content)),
+			ModifiedAt: time.Now().UTC(),
+			Writable:   true,
+		}))
+	}
+}
+
+// RenameFile moves a file or directory of a server from one path to another, which must not exist.
+func RenameFile() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		var request types.RenameRequest
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		// Check for errors in body.
+		if err := ctx.BodyParser(&request); err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error in provided body: \n%v", err)))
+		}
+
+		runtime, id, gameSchema, status, err := filesInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditInstance(ctx, id)
+
+		from, status, err := filePath(ctx, gameSchema, request.From, true)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		to, status, err := filePath(ctx, gameSchema, request.To, true)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditBefore(ctx, fiber.Map{"path": from})
+		middleware.AuditAfter(ctx, fiber.Map{"path": to})
+
+		if err := files.Rename(ctx.Context(), runtime, id, from, to); err != nil {
+			ctx.Status(fileErrorStatus(err))
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error renaming %v: \n%v", from, err)))
+		}
+
+		ctx.Status(http.StatusOK)
+		return ctx.JSON(presenter.FileSuccessResponse(types.File{Name: path.Base(to), Path: to, Writable: true}))
+	}
+}
+
+// DeleteFile deletes the file in the path query parameter of a server, or the directory and everything inside it.
+func DeleteFile() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		runtime, id, gameSchema, status, err := filesInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditInstance(ctx, id)
+
+		file, status, err := filePath(ctx, gameSchema, ctx.Query("path"), true)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditBefore(ctx, fiber.Map{"path": file})
+
+		if err := files.Remove(ctx.Context(), runtime, id, file); err != nil {
+			ctx.Status(fileErrorStatus(err))
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error deleting %v: \n%v", file, err)))
+		}
+
+		ctx.Status(http.StatusOK)
+		return ctx.JSON(presenter.ServerEmptyResponse())
+	}
+}
+
+// MakeDirectory creates the directory in the path query parameter of a server, with any parents it is missing.
+func MakeDirectory() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		runtime, id, gameSchema, status, err := filesInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditInstance(ctx, id)
+
+		dir, status, err := filePath(ctx, gameSchema, ctx.Query("path"), true)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditAfter(ctx, fiber.Map{"path": dir})
+
+		if err := files.Mkdir(ctx.Context(), runtime, id, dir); err != nil {
+			ctx.Status(fileErrorStatus(err))
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error creating %v: \n%v", dir, err)))
+		}
+
+		ctx.Status(http.StatusOK)
+		return ctx.JSON(presenter.FileSuccessResponse(types.File{Name: path.Base(dir), Path: dir, Dir: true, Writable: true}))
+	}
+}
+
+// filesInstance finds the instance of a request, the runtime of its workload and the game schema
+// its volumes are declared by.
+func filesInstance(ctx *fiber.Ctx) (engine.Runtime, string, schema.Schema, int, error) {
+	runtime, id, status, err := runtimeInstance(ctx)
+	if err != nil {
+		return nil, "", schema.Schema{}, status, err
+	}
+
+	server, err := db.GetServer(ctx.Context(), id)
+	if err != nil {
+		return nil, "", schema.Schema{}, http.StatusInternalServerError, fmt.Errorf("error reading server details from the database: \n%v", err)
+	}
+	gameSchema, err := schema.GetSchema(server.Game.Name)
+	if err != nil {
+		return nil, "", schema.Schema{}, http.StatusInternalServerError, fmt.Errorf("error reading schema: \n%v", err)
+	}
+
+	return runtime, id, gameSchema, http.StatusOK, nil
+}
+
+// filePath checks a path of a request is inside the volumes of a game schema, returning it cleaned.
+// Paths to be changed must also be writable by the file manager.
+func filePath(ctx *fiber.Ctx, gameSchema schema.Schema, p string, write bool) (string, int, error) {
+	clean, err := schema.VolumePath(gameSchema, p)
+	if err != nil {
+		return "", http.StatusBadRequest, err
+	}
+	if write && !schema.Writable(gameSchema, clean) {
+		return "", http.StatusForbidden, fmt.Errorf("path %v is read-only", clean)
+	}
+	return clean, http.StatusOK, nil
+}
+
+// fileLimit returns the largest file the file manager reads or writes, in bytes.
+func fileLimit() int64 {
+	return int64(config.Current().FileLimit) * 1024 * 1024
+}
+
+// fileErrorStatus returns the status of a failed file operation: commands which fail are the fault of the request,
+// such as for a path which does not exist, while anything else is a failure of the runtime.
+func fileErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, files.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, files.ErrFailed):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/RicochetStudios/aurora/api/middleware"
+	"github.com/RicochetStudios/aurora/api/presenter"
+	"github.com/RicochetStudios/aurora/jobs"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetJob gets the progress of a job, and its error if it failed.
+func GetJob() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		job, ok := jobs.Get(ctx.Params("id"))
+		if !ok {
+			ctx.Status(http.StatusNotFound)
+			return ctx.JSON(presenter.JobErrorResponse(fmt.Errorf("job %v does not exist on this node", ctx.Params("id"))))
+		}
+
+		ctx.Status(http.StatusOK)
+		return ctx.JSON(presenter.JobSuccessResponse(job))
+	}
+}
+
+// StreamJob streams the progress of a job as server sent events, until it finishes.
+// Each event is named after the job status, with the job as its data.
+func StreamJob() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		updates, cancel, ok := jobs.Subscribe(ctx.Params("id"))
+		if !ok {
+			ctx.Status(http.StatusNotFound)
+			return ctx.JSON(presenter.JobErrorResponse(fmt.Errorf("job %v does not exist on this node", ctx.Params("id"))))
+		}
+
+		ctx.Set(fiber.HeaderContentType, "text/event-stream")
+		ctx.Set(fiber.HeaderCacheControl, "no-cache")
+		ctx.Set(fiber.HeaderConnection, "keep-alive")
+		ctx.Status(http.StatusOK)
+
+		ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			// Stop following the job when the client goes away.
+			defer cancel()
+			for job := range updates {
+				if err := writeEvent(w, job); err != nil {
+					return
+				}
+			}
+		})
+		return nil
+	}
+}
+
+// writeEvent writes the state of a job as a server sent event and flushes it to the client.
+func writeEvent(w *bufio.Writer, job types.Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %v\ndata: %s\n\n", job.Status, data); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// submit submits a job to carry out the request on an instance, responding with the job,
+// or with a conflict if another job is still acting on the instance.
+func submit(ctx *fiber.Ctx, action string, id string, run jobs.Func) error {
+	job, err := jobs.Submit(action, id, run)
+	if errors.Is(err, jobs.ErrActive) {
+		ctx.Status(http.StatusConflict)
+		return ctx.JSON(presenter.JobErrorResponse(err))
+	}
+	return accepted(ctx, job)
+}
+
+// accepted responds with a job which was submitted to carry out the request.
+// The outcome of the job is audited once it finishes.
+func accepted(ctx *fiber.Ctx, job types.Job) error {
+	middleware.AuditJob(ctx, job)
+	ctx.Location("/api/jobs/" + job.ID)
+	ctx.Status(http.StatusAccepted)
+	return ctx.JSON(presenter.JobSuccessResponse(job))
+}
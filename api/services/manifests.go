@@ -0,0 +1,66 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/RicochetStudios/aurora/api/middleware"
+	"github.com/RicochetStudios/aurora/api/presenter"
+	"github.com/RicochetStudios/aurora/db"
+	"github.com/RicochetStudios/aurora/kubernetes"
+	"github.com/RicochetStudios/aurora/schema"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetManifests renders the Kubernetes manifests which would run a server instance, as yaml.
+func GetManifests() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		// Get instance ID.
+		id, status, err := instanceID(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		} else if len(id) == 0 {
+			ctx.Status(http.StatusNotFound)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("no server exists on this node")))
+		}
+
+		// Read the current server configuration.
+		server, err := db.GetServer(ctx.Context(), id)
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error reading server details from the database: \n%v", err)))
+		}
+
+		// Get the game schema.
+		gameSchema, err := schema.GetSchema(server.Game.Name)
+		if err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error getting schema: \n%v", err)))
+		}
+
+		manifests, err := kubernetes.Render(id, gameSchema, server)
+		if err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error rendering manifests: \n%v", err)))
+		}
+		out, err := manifests.YAML()
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error encoding manifests: \n%v", err)))
+		}
+
+		ctx.Set(fiber.HeaderContentType, "application/yaml")
+		ctx.Status(http.StatusOK)
+		return ctx.Send(out)
+	}
+}
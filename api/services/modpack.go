@@ -0,0 +1,97 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/RicochetStudios/aurora/api/middleware"
+	"github.com/RicochetStudios/aurora/api/presenter"
+	"github.com/RicochetStudios/aurora/audit"
+	"github.com/RicochetStudios/aurora/engine"
+	"github.com/RicochetStudios/aurora/jobs"
+	"github.com/RicochetStudios/aurora/lifecycle"
+	"github.com/RicochetStudios/aurora/modpack"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ImportModpack creates a new server instance on this node from the modpack archive in the file field
+// of a multipart form, installing its files once it is deployed and restarting it to load them.
+// The name and size form fields are used for the server, which is named after the modpack by default.
+func ImportModpack() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		file, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error reading the file field: \n%v", err)))
+		}
+		upload, err := file.Open()
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error opening upload: \n%v", err)))
+		}
+		defer upload.Close()
+
+		// The archive is spooled to disk, and its files streamed from it once the instance is deployed.
+		pack, err := modpack.Read(upload)
+		if err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		closePack := func() {
+			if err := pack.Close(); err != nil {
+				log.Printf("error removing modpack %v: %v", pack.Name, err)
+			}
+		}
+		server := pack.Server(types.Server{Name: ctx.FormValue("name"), Size: ctx.FormValue("size")})
+
+		// Files are copied relative to the data volume, and jars in the mods directory are recorded as mods.
+		gameSchema, err := schema.GetSchema(server.Game.Name)
+		if err != nil {
+			closePack()
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error reading schema: \n%v", err)))
+		}
+		if server.Game, err = schema.ChooseGame(gameSchema, server.Game); err != nil {
+			closePack()
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		dataDir, err := schema.DataDir(gameSchema)
+		if err != nil {
+			closePack()
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		modsDir, err := schema.ModsDir(gameSchema, server)
+		if err != nil && pack.HasJars() {
+			closePack()
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("modpack has jars, but %v", err)))
+		}
+
+		job, status, err := createInstance(ctx, server, audit.ActionServerImport, func(runtime engine.Runtime, id string) []jobs.Step {
+			steps := modpack.Install(runtime, id, dataDir, modsDir, pack.Files)
+			return append(steps, lifecycle.Restart(runtime, id, lifecycle.Spec{Schema: gameSchema, Server: server})...)
+		}, closePack)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+
+		ctx.Location("/api/jobs/" + job.ID)
+		ctx.Status(http.StatusAccepted)
+		return ctx.JSON(presenter.ModpackImportResponse(job, pack.Modpack))
+	}
+}
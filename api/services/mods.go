@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/RicochetStudios/aurora/api/middleware"
+	"github.com/RicochetStudios/aurora/api/presenter"
+	"github.com/RicochetStudios/aurora/audit"
+	"github.com/RicochetStudios/aurora/db"
+	"github.com/RicochetStudios/aurora/engine"
+	"github.com/RicochetStudios/aurora/jobs"
+	"github.com/RicochetStudios/aurora/lifecycle"
+	"github.com/RicochetStudios/aurora/mods"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ListMods gets the mods added to a server.
+func ListMods() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		_, id, status, err := runtimeInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+
+		manifest, err := db.GetMods(ctx.Context(), id)
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error reading mods from the database: \n%v", err)))
+		}
+
+		ctx.Status(http.StatusOK)
+		return ctx.JSON(presenter.ModsSuccessResponse(manifest))
+	}
+}
+
+// AddMod uploads the jar in the file field of a multipart form to the mods directory of a server in the background.
+// The server is restarted to load it, unless the restart query parameter is false.
+func AddMod() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		runtime, id, dir, manifest, status, err := modsInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditInstance(ctx, id)
+
+		file, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error reading the file field: \n%v", err)))
+		}
+		if err := mods.ValidName(file.Filename); err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		if _, ok := mods.Find(manifest, file.Filename); ok {
+			ctx.Status(http.StatusConflict)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("mod %v has already been added, remove it first", file.Filename)))
+		}
+
+		upload, err := file.Open()
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error opening upload: \n%v", err)))
+		}
+		defer upload.Close()
+		content, err := io.ReadAll(upload)
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error reading upload: \n%v", err)))
+		}
+		middleware.AuditAfter(ctx, fiber.Map{"name": file.Filename, "size": len(content)})
+
+		steps := mods.Add(runtime, id, dir, file.Filename, content)
+		return submitModSteps(ctx, runtime, audit.ActionModAdd, id, steps)
+	}
+}
+
+// EnableMod enables a disabled mod of a server in the background.
+// The server is restarted to load it, unless the restart query parameter is false.
+func EnableMod() fiber.Handler {
+	return setModEnabled(audit.ActionModEnable, true)
+}
+
+// DisableMod disables a mod of a server in the background, keeping its jar.
+// The server is restarted to unload it, unless the restart query parameter is false.
+func DisableMod() fiber.Handler {
+	return setModEnabled(audit.ActionModDisable, false)
+}
+
+// setModEnabled returns the handler which enables or disables the mod named in the path.
+func setModEnabled(action string, enabled bool) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		runtime, id, dir, manifest, status, err := modsInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditInstance(ctx, id)
+
+		mod, status, err := namedMod(ctx, manifest)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditBefore(ctx, mod)
+
+		steps := mods.SetEnabled(runtime, id, dir, mod.Name, enabled)
+		return submitModSteps(ctx, runtime, action, id, steps)
+	}
+}
+
+// RemoveMod deletes a mod from a server in the background.
+// The server is restarted to unload it, unless the restart query parameter is false.
+func RemoveMod() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		runtime, id, dir, manifest, status, err := modsInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditInstance(ctx, id)
+
+		mod, status, err := namedMod(ctx, manifest)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditBefore(ctx, mod)
+
+		steps := mods.Remove(runtime, id, dir, mod.Name)
+		return submitModSteps(ctx, runtime, audit.ActionModRemove, id, steps)
+	}
+}
+
+// modsInstance finds the instance of a request, the runtime of its workload, the directory its modloader
+// loads mods from and its mod manifest.
+func modsInstance(ctx *fiber.Ctx) (engine.Runtime, string, string, types.ModManifest, int, error) {
+	runtime, id, status, err := runtimeInstance(ctx)
+	if err != nil {
+		return nil, "", "", types.ModManifest{}, status, err
+	}
+
+	server, err := db.GetServer(ctx.Context(), id)
+	if err != nil {
+		return nil, "", "", types.ModManifest{}, http.StatusInternalServerError, fmt.Errorf("error reading server details from the database: \n%v", err)
+	}
+	gameSchema, err := schema.GetSchema(server.Game.Name)
+	if err != nil {
+		return nil, "", "", types.ModManifest{}, http.StatusInternalServerError, fmt.Errorf("error reading schema: \n%v", err)
+	}
+	dir, err := schema.ModsDir(gameSchema, server)
+	if err != nil {
+		return nil, "", "", types.ModManifest{}, http.StatusBadRequest, err
+	}
+
+	manifest, err := db.GetMods(ctx.Context(), id)
+	if err != nil {
+		return nil, "", "", types.ModManifest{}, http.StatusInternalServerError, fmt.Errorf("error reading mods from the database: \n%v", err)
+	}
+
+	return runtime, id, dir, manifest, http.StatusOK, nil
+}
+
+// namedMod finds the mod named in the path of a request in a manifest.
+func namedMod(ctx *fiber.Ctx, manifest types.ModManifest) (types.Mod, int, error) {
+	name, err := url.PathUnescape(ctx.Params("name"))
+	if err != nil {
+		return types.Mod{}, http.StatusBadRequest, fmt.Errorf("error decoding mod name: \n%v", err)
+	}
+	mod, ok := mods.Find(manifest, name)
+	if !ok {
+		return types.Mod{}, http.StatusNotFound, fmt.Errorf("mod %v has not been added", name)
+	}
+	return mod, http.StatusOK, nil
+}
+
+// submitModSteps runs the steps which change the mods of an instance in the background,
+// restarting it afterwards unless the restart query parameter is false.
+func submitModSteps(ctx *fiber.Ctx, runtime engine.Runtime, action string, id string, steps []jobs.Step) error {
+	if ctx.QueryBool("restart", true) {
+		current, _, status, err := instanceSpec(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		steps = append(steps, lifecycle.Restart(runtime, id, current)...)
+	}
+
+	return submit(ctx, action, id, func(jobCtx context.Context) error {
+		return jobs.RunSteps(jobCtx, steps)
+	})
+}
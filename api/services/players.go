@@ -0,0 +1,162 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/RicochetStudios/aurora/api/middleware"
+	"github.com/RicochetStudios/aurora/api/presenter"
+	"github.com/RicochetStudios/aurora/db"
+	"github.com/RicochetStudios/aurora/engine"
+	"github.com/RicochetStudios/aurora/players"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ListPlayers gets the players on the list of a server named in the path, such as its allow list, operators or bans.
+func ListPlayers() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		runtime, id, _, list, status, err := playersInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+
+		return playerList(ctx, runtime, id, list)
+	}
+}
+
+// AddPlayer adds the player in the request body to the list of a server named in the path.
+// The command of the list is run on a running server, and the file of the list is changed on a stopped server.
+func AddPlayer() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		var player types.Player
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		// Check for errors in body.
+		if err := ctx.BodyParser(&player); err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error in provided body: \n%v", err)))
+		}
+
+		runtime, id, gameSchema, list, status, err := playersInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditInstance(ctx, id)
+
+		if err := schema.CheckPlayer(gameSchema, player); err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditAfter(ctx, fiber.Map{"list": list.Name, "player": player})
+
+		if err := players.Add(ctx.Context(), runtime, id, list, player, fileLimit()); err != nil {
+			ctx.Status(playerErrorStatus(err))
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error adding %v to %v: \n%v", player.Name, list.Name, err)))
+		}
+
+		return playerList(ctx, runtime, id, list)
+	}
+}
+
+// RemovePlayer removes the player named in the path from a list of a server, in the same way as AddPlayer.
+func RemovePlayer() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		runtime, id, gameSchema, list, status, err := playersInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditInstance(ctx, id)
+
+		player := types.Player{Name: ctx.Params("player")}
+		if err := schema.CheckPlayer(gameSchema, player); err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditBefore(ctx, fiber.Map{"list": list.Name, "player": player})
+
+		if err := players.Remove(ctx.Context(), runtime, id, list, player.Name, fileLimit()); err != nil {
+			ctx.Status(playerErrorStatus(err))
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error removing %v from %v: \n%v", player.Name, list.Name, err)))
+		}
+
+		return playerList(ctx, runtime, id, list)
+	}
+}
+
+// playersInstance finds the instance of a request, the runtime of its workload, its game schema
+// and the player list named in the path.
+func playersInstance(ctx *fiber.Ctx) (engine.Runtime, string, schema.Schema, schema.PlayerList, int, error) {
+	runtime, id, status, err := runtimeInstance(ctx)
+	if err != nil {
+		return nil, "", schema.Schema{}, schema.PlayerList{}, status, err
+	}
+
+	server, err := db.GetServer(ctx.Context(), id)
+	if err != nil {
+		return nil, "", schema.Schema{}, schema.PlayerList{}, http.StatusInternalServerError, fmt.Errorf("error reading server details from the database: \n%v", err)
+	}
+	gameSchema, err := schema.GetSchema(server.Game.Name)
+	if err != nil {
+		return nil, "", schema.Schema{}, schema.PlayerList{}, http.StatusInternalServerError, fmt.Errorf("error reading schema: \n%v", err)
+	}
+	list, err := schema.FindPlayerList(gameSchema, ctx.Params("list"))
+	if err != nil {
+		return nil, "", schema.Schema{}, schema.PlayerList{}, http.StatusNotFound, err
+	}
+
+	return runtime, id, gameSchema, list, http.StatusOK, nil
+}
+
+// playerList responds with the players on a list of a server.
+func playerList(ctx *fiber.Ctx, runtime engine.Runtime, id string, list schema.PlayerList) error {
+	entries, err := players.List(ctx.Context(), runtime, id, list, fileLimit())
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error listing %v: \n%v", list.Name, err)))
+	}
+
+	ctx.Status(http.StatusOK)
+	return ctx.JSON(presenter.PlayersSuccessResponse(entries))
+}
+
+// playerErrorStatus returns the status of a failed change to a player list: lists which can not be changed
+// in the state of the server conflict with it, and failed commands are the fault of the request.
+func playerErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, players.ErrUnavailable):
+		return http.StatusConflict
+	case errors.Is(err, players.ErrFailed):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/RicochetStudios/aurora/api/middleware"
+	"github.com/RicochetStudios/aurora/api/presenter"
+	"github.com/RicochetStudios/aurora/audit"
+	"github.com/RicochetStudios/aurora/db"
+	"github.com/RicochetStudios/aurora/engine"
+	"github.com/RicochetStudios/aurora/jobs"
+	"github.com/RicochetStudios/aurora/lifecycle"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetProperties gets the game properties declared by the schema of a server, with the values the server has,
+// including those pending until it is next started or restarted.
+func GetProperties() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		spec, _, status, err := instanceSpec(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+
+		ctx.Status(http.StatusOK)
+		spec = lifecycle.WithPending(spec)
+		return ctx.JSON(presenter.PropertiesSuccessResponse(schema.Properties(spec.Schema, spec.Server)))
+	}
+}
+
+// UpdateProperties changes the game properties of a server named in the request body. Properties set to null are reset to their default.
+// The properties are saved to be applied when the server is next started or restarted, or with the restart query parameter,
+// applied at once in the background by restarting the server.
+func UpdateProperties() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		var changes map[string]any
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		// Check for errors in body.
+		if err := ctx.BodyParser(&changes); err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error in provided body: \n%v", err)))
+		}
+
+		current, id, status, err := instanceSpec(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditInstance(ctx, id)
+
+		// Changes are made over those already pending.
+		pending := lifecycle.WithPending(current)
+		middleware.AuditBefore(ctx, fiber.Map{"properties": pending.Server.Properties})
+		properties, err := schema.ChangeProperties(current.Schema, pending.Server.Properties, changes)
+		if err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditAfter(ctx, fiber.Map{"properties": properties})
+
+		if ctx.QueryBool("restart", false) {
+			next := pending
+			next.Server.Properties = properties
+			return submit(ctx, audit.ActionServerProperties, id, func(jobCtx context.Context) error {
+				runtime, err := engine.Current()
+				if err != nil {
+					return fmt.Errorf("error creating runtime: \n%v", err)
+				}
+				return jobs.RunSteps(jobCtx, lifecycle.UpdateProperties(runtime, id, current, next))
+			})
+		}
+
+		// The pending properties are saved by a job, so jobs saving the server cannot overwrite them.
+		return submit(ctx, audit.ActionServerProperties, id, func(jobCtx context.Context) error {
+			server, err := db.GetServer(jobCtx, id)
+			if err != nil {
+				return fmt.Errorf("error reading server details from the database: \n%v", err)
+			}
+			server.Pending = &types.Pending{Properties: properties}
+			if _, err := db.SetServer(jobCtx, id, server); err != nil {
+				return fmt.Errorf("error writing server details to the database: \n%v", err)
+			}
+			return nil
+		})
+	}
+}
+
+// instanceSpec finds the instance of a request, and the server and game schema it runs with.
+func instanceSpec(ctx *fiber.Ctx) (lifecycle.Spec, string, int, error) {
+	id, status, err := instanceID(ctx)
+	if err != nil {
+		return lifecycle.Spec{}, "", status, err
+	} else if len(id) == 0 {
+		return lifecycle.Spec{}, "", http.StatusNotFound, fmt.Errorf("no server exists on this node")
+	}
+
+	server, err := db.GetServer(ctx.Context(), id)
+	if err != nil {
+		return lifecycle.Spec{}, "", http.StatusInternalServerError, fmt.Errorf("error reading server details from the database: \n%v", err)
+	}
+	gameSchema, err := schema.GetSchema(server.Game.Name)
+	if err != nil {
+		return lifecycle.Spec{}, "", http.StatusInternalServerError, fmt.Errorf("error reading schema: \n%v", err)
+	}
+
+	return lifecycle.Spec{Schema: gameSchema, Server: server}, id, http.StatusOK, nil
+}
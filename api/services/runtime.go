@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/RicochetStudios/aurora/api/middleware"
+	"github.com/RicochetStudios/aurora/api/presenter"
+	"github.com/RicochetStudios/aurora/audit"
+	"github.com/RicochetStudios/aurora/db"
+	"github.com/RicochetStudios/aurora/engine"
+	"github.com/RicochetStudios/aurora/jobs"
+	"github.com/RicochetStudios/aurora/lifecycle"
+	"github.com/RicochetStudios/aurora/schema"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// defaultLogLines is the number of log lines returned when no tail is requested.
+const defaultLogLines int = 100
+
+// StartServer starts the stopped workload of a server in the background.
+func StartServer() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		runtime, id, status, err := runtimeInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditInstance(ctx, id)
+		current, _, status, err := instanceSpec(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+
+		return submit(ctx, audit.ActionServerStart, id, func(jobCtx context.Context) error {
+			return jobs.RunSteps(jobCtx, lifecycle.Start(runtime, id, current))
+		})
+	}
+}
+
+// StopServer stops the workload of a server in the background, keeping its data.
+func StopServer() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		runtime, id, status, err := runtimeInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditInstance(ctx, id)
+
+		return submit(ctx, audit.ActionServerStop, id, func(jobCtx context.Context) error {
+			return jobs.RunSteps(jobCtx, lifecycle.Stop(runtime, id))
+		})
+	}
+}
+
+// UpdateServerImage pins a server to the current digest of the image of its game in the background,
+// recreating its workload if the image has changed.
+func UpdateServerImage() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		runtime, id, status, err := runtimeInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditInstance(ctx, id)
+
+		server, err := db.GetServer(ctx.Context(), id)
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error reading server details from the database: \n%v", err)))
+		}
+		middleware.AuditBefore(ctx, server)
+		gameSchema, err := schema.GetSchema(server.Game.Name)
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error reading schema: \n%v", err)))
+		}
+
+		current := lifecycle.Spec{Schema: gameSchema, Server: server}
+		return submit(ctx, audit.ActionServerImageUpdate, id, func(jobCtx context.Context) error {
+			return jobs.RunSteps(jobCtx, lifecycle.UpdateImage(runtime, id, current))
+		})
+	}
+}
+
+// GetServerStatus gets the state of the workload of a server.
+func GetServerStatus() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		runtime, id, status, err := runtimeInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+
+		return serverStatus(ctx, runtime, id)
+	}
+}
+
+// GetServerLogs gets the last lines of output of a server as plain text.
+// The number of lines is given by the tail query parameter.
+func GetServerLogs() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		runtime, id, status, err := runtimeInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+
+		logs, err := runtime.Logs(ctx.Context(), id, ctx.QueryInt("tail", defaultLogLines))
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error getting logs: \n%v", err)))
+		}
+		defer logs.Close()
+
+		out, err := io.ReadAll(logs)
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error reading logs: \n%v", err)))
+		}
+
+		ctx.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		ctx.Status(http.StatusOK)
+		return ctx.Send(out)
+	}
+}
+
+// GetServerStats gets the resource usage of a server.
+func GetServerStats() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		runtime, id, status, err := runtimeInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+
+		stats, err := runtime.Stats(ctx.Context(), id)
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error getting stats: \n%v", err)))
+		}
+
+		ctx.Status(http.StatusOK)
+		return ctx.JSON(presenter.StatsSuccessResponse(stats))
+	}
+}
+
+// serverStatus responds with the state of the workload of an instance.
+func serverStatus(ctx *fiber.Ctx, runtime engine.Runtime, id string) error {
+	status, err := runtime.Status(ctx.Context(), id)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error getting status: \n%v", err)))
+	}
+
+	ctx.Status(http.StatusOK)
+	return ctx.JSON(presenter.RuntimeStatusSuccessResponse(status))
+}
+
+// runtimeInstance returns the runtime and the existing instance a request acts on,
+// with a status code for any error.
+func runtimeInstance(ctx *fiber.Ctx) (engine.Runtime, string, int, error) {
+	id, status, err := instanceID(ctx)
+	if err != nil {
+		return nil, "", status, err
+	} else if len(id) == 0 {
+		return nil, "", http.StatusNotFound, fmt.Errorf("no server exists on this node")
+	}
+
+	runtime, err := engine.Current()
+	if err != nil {
+		return nil, "", http.StatusInternalServerError, fmt.Errorf("error creating runtime: \n%v", err)
+	}
+
+	return runtime, id, http.StatusOK, nil
+}
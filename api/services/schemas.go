@@ -0,0 +1,94 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/RicochetStudios/aurora/api/middleware"
+	"github.com/RicochetStudios/aurora/api/presenter"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ListSchemas lists the games servers may be created from, with the sizes, versions, modloaders
+// and properties each may choose. Schemas which are not valid are left out, as they can not be deployed.
+func ListSchemas() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		games, err := schema.Games()
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.SchemaErrorResponse(fmt.Errorf("error listing game schemas: \n%v", err)))
+		}
+
+		catalog := []types.GameSchema{}
+		for _, game := range games {
+			gameSchema, err := schema.GetSchema(game)
+			if err != nil {
+				log.Printf("error getting game schema of %v: %v", game, err)
+				continue
+			}
+			catalog = append(catalog, schema.Catalog(gameSchema))
+		}
+
+		ctx.Status(http.StatusOK)
+		return ctx.JSON(presenter.SchemasSuccessResponse(catalog))
+	}
+}
+
+// GetGameSchema gets a game servers may be created from, with the sizes, versions, modloaders
+// and properties it may choose.
+func GetGameSchema() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		game := ctx.Params("game")
+		games, err := schema.Games()
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.SchemaErrorResponse(fmt.Errorf("error listing game schemas: \n%v", err)))
+		}
+		found := false
+		for _, name := range games {
+			found = found || name == game
+		}
+		if !found {
+			ctx.Status(http.StatusNotFound)
+			return ctx.JSON(presenter.SchemaErrorResponse(fmt.Errorf("game %q does not exist", game)))
+		}
+
+		gameSchema, err := schema.GetSchema(game)
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.SchemaErrorResponse(fmt.Errorf("error getting game schema: \n%v", err)))
+		}
+
+		ctx.Status(http.StatusOK)
+		return ctx.JSON(presenter.SchemaSuccessResponse(schema.Catalog(gameSchema)))
+	}
+}
+
+// GetSchemaMeta returns the JSON Schema of game schema files.
+// It is not protected, so editors can check schema files with it.
+func GetSchemaMeta() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		ctx.Set(fiber.HeaderContentType, "application/schema+json")
+		return ctx.Send(schema.Meta())
+	}
+}
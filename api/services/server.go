@@ -1,13 +1,21 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 
 	"github.com/RicochetStudios/aurora/api/presenter"
+	"github.com/RicochetStudios/aurora/audit"
+	"github.com/RicochetStudios/aurora/capacity"
 	"github.com/RicochetStudios/aurora/config"
 	"github.com/RicochetStudios/aurora/db"
-	"github.com/RicochetStudios/aurora/docker"
+	"github.com/RicochetStudios/aurora/engine"
+	"github.com/RicochetStudios/aurora/jobs"
+	"github.com/RicochetStudios/aurora/lifecycle"
+	"github.com/RicochetStudios/aurora/node"
 	"github.com/RicochetStudios/aurora/schema"
 	"github.com/RicochetStudios/aurora/types"
 	"github.com/google/uuid"
@@ -17,7 +25,72 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-// GetServer gets details about the currently configured game server instance.
+// errInsufficientCapacity is returned when a new instance does not fit on this node.
+var errInsufficientCapacity = errors.New("insufficient capacity")
+
+// ListServers gets details about every game server instance on this node.
+func ListServers() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		// Get instance IDs.
+		ids, err := config.GetInstances()
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error getting config instances: \n%v", err)))
+		}
+
+		// Read the configuration of each server.
+		instances := []types.Instance{}
+		for _, id := range ids {
+			server, err := db.GetServer(ctx.Context(), id)
+			if err != nil {
+				ctx.Status(http.StatusInternalServerError)
+				return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error reading server %v details from the database: \n%v", id, err)))
+			}
+			instances = append(instances, types.Instance{ID: id, Server: server})
+		}
+
+		ctx.Status(http.StatusOK)
+		return ctx.JSON(presenter.InstancesSuccessResponse(instances))
+	}
+}
+
+// CreateServer creates a new server instance on this node.
+func CreateServer() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		var server types.Server
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		// Check for errors in body.
+		if err := ctx.BodyParser(&server); err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error in provided body: \n%v", err)))
+		}
+
+		job, status, err := createInstance(ctx, server, audit.ActionServerCreate, nil, nil)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+
+		return accepted(ctx, job)
+	}
+}
+
+// GetServer gets details about a game server instance.
 func GetServer() fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
 
@@ -29,10 +102,10 @@ func GetServer() fiber.Handler {
 		}
 
 		// Get instance ID.
-		id, err := config.GetId()
+		id, status, err := instanceID(ctx)
 		if err != nil {
-			ctx.Status(http.StatusInternalServerError)
-			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error getting config id: \n%v", err)))
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
 		} else if len(id) == 0 {
 			// Return empty if no ID is set.
 			ctx.Status(http.StatusOK)
@@ -51,7 +124,8 @@ func GetServer() fiber.Handler {
 	}
 }
 
-// UpdateServer creates or updates a server.
+// UpdateServer updates a server.
+// On the single instance route, the server is created if this node has no instances.
 func UpdateServer() fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
 		var server types.Server
@@ -70,55 +144,85 @@ func UpdateServer() fiber.Handler {
 		}
 
 		// Get instance ID.
-		id, err := config.GetId()
+		id, status, err := instanceID(ctx)
 		if err != nil {
-			ctx.Status(http.StatusInternalServerError)
-			return ctx.JSON(presenter.SetupErrorResponse(fmt.Errorf("error reading from config: \n%v", err)))
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
 		}
 
-		// Get the game schema.
-		schema, err := schema.GetSchema("minecraft_java")
+		// If no ID is set, create an instance.
+		if len(id) == 0 {
+			job, status, err := createInstance(ctx, server, audit.ActionServerCreate, nil, nil)
+			if err != nil {
+				ctx.Status(status)
+				return ctx.JSON(presenter.ServerErrorResponse(err))
+			}
+
+			return accepted(ctx, job)
+		}
+
+		// Keep the existing server, to roll back to and for the audit log.
+		middleware.AuditInstance(ctx, id)
+		existing, err := db.GetServer(ctx.Context(), id)
 		if err != nil {
 			ctx.Status(http.StatusInternalServerError)
-			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error reading schema: \n%v", err)))
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error reading server details from the database: \n%v", err)))
 		}
-
-		// Create a container config.
-		containerConfig, err := docker.NewContainerConfig(id, schema, server)
+		middleware.AuditBefore(ctx, existing)
+		existingSchema, err := schema.GetSchema(existing.Game.Name)
 		if err != nil {
 			ctx.Status(http.StatusInternalServerError)
-			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error creating container config: \n%v", err)))
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error reading existing schema: \n%v", err)))
 		}
 
-		// If no ID is set, create an id and container.
-		if len(id) == 0 {
-			id = uuid.New().String()
-
-			// Deploy and start the container.
-			if _, err := docker.RunServer(ctx.Context(), containerConfig); err != nil {
-				ctx.Status(http.StatusInternalServerError)
-				return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error deploying container: \n%v", err)))
-			}
+		// Check the new configuration before accepting it.
+		gameSchema, err := schema.GetSchema(server.Game.Name)
+		if err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error reading schema: \n%v", err)))
+		}
+		if server.Game, err = schema.ChooseGame(gameSchema, server.Game); err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
 
-			// Add or update the instance ID in the config.
-			if _, err = config.UpdateId(id); err != nil {
+		// Game properties are changed on their own route, and those the new game does not declare are dropped.
+		// Pending properties stay pending until the server is next started or restarted.
+		if server.Properties, err = schema.ChangeProperties(gameSchema, existing.Properties, nil); err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		server.Pending = nil
+		if existing.Pending != nil {
+			pending, err := schema.ChangeProperties(gameSchema, existing.Pending.Properties, nil)
+			if err != nil {
 				ctx.Status(http.StatusInternalServerError)
-				return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error updating id in config: \n%v", err)))
+				return ctx.JSON(presenter.ServerErrorResponse(err))
 			}
+			server.Pending = &types.Pending{Properties: pending}
 		}
 
-		// Add the server details.
+		// Add the server details. The image stays pinned unless the game, modloader or version
+		// runs a different image, and can only be moved to a newer digest by updating the image.
 		server.Status = "running"
-
-		// Create or update the current server configuration.
-		server, err = db.SetServer(ctx.Context(), id, server)
-		if err != nil {
-			ctx.Status(http.StatusInternalServerError)
-			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error updating server details in the database: \n%v", err)))
+		server.Image = ""
+		unpinned := existing
+		unpinned.Image = ""
+		if schema.Image(gameSchema, server) == schema.Image(existingSchema, unpinned) {
+			server.Image = existing.Image
 		}
+		middleware.AuditAfter(ctx, server)
 
-		ctx.Status(http.StatusOK)
-		return ctx.JSON(presenter.ServerSuccessResponse(&server))
+		// Apply the new configuration to the workload in the background.
+		previous := lifecycle.Spec{Schema: existingSchema, Server: existing}
+		next := lifecycle.Spec{Schema: gameSchema, Server: server}
+		return submit(ctx, audit.ActionServerUpdate, id, func(jobCtx context.Context) error {
+			runtime, err := engine.Current()
+			if err != nil {
+				return fmt.Errorf("error creating runtime: \n%v", err)
+			}
+			return jobs.RunSteps(jobCtx, lifecycle.Update(runtime, id, previous, next))
+		})
 	}
 }
 
@@ -133,38 +237,177 @@ func RemoveServer() fiber.Handler {
 			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
 		}
 
-		// Remove the container.
-		if err := docker.RemoveServer(ctx.Context()); err != nil {
-			ctx.Status(http.StatusInternalServerError)
-			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error removing container: \n%v", err)))
-		}
-
 		// Get instance ID.
-		id, err := config.GetId()
+		id, status, err := instanceID(ctx)
 		if err != nil {
-			ctx.Status(http.StatusInternalServerError)
-			return ctx.JSON(presenter.SetupErrorResponse(fmt.Errorf("error reading from config: \n%v", err)))
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
 		} else if len(id) == 0 {
 			// Return empty if no ID is set.
 			ctx.Status(http.StatusOK)
 			return ctx.JSON(presenter.ServerEmptyResponse())
 		}
 
-		// Delete the current server configuration.
-		if err = db.RemoveServer(ctx.Context(), id); err != nil {
+		// Keep the existing server, to restore and for the audit log.
+		middleware.AuditInstance(ctx, id)
+		var previous *types.Server
+		existing, err := db.GetServer(ctx.Context(), id)
+		if err == nil {
+			previous = &existing
+			middleware.AuditBefore(ctx, existing)
+		} else if !errors.Is(err, db.ErrNotFound) {
 			ctx.Status(http.StatusInternalServerError)
-			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error removing instance from the database: \n%v", err)))
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error reading server details from the database: \n%v", err)))
 		}
 
-		// Remove instance ID from the config.
-		_, err = config.UpdateId("")
+		middleware.AuditAfter(ctx, types.Server{})
+
+		// Remove the workload and its records in the background.
+		return submit(ctx, audit.ActionServerRemove, id, func(jobCtx context.Context) error {
+			runtime, err := engine.Current()
+			if err != nil {
+				return fmt.Errorf("error creating runtime: \n%v", err)
+			}
+			return jobs.RunSteps(jobCtx, lifecycle.Remove(runtime, id, previous))
+		})
+	}
+}
+
+// instanceID returns the instance a request acts on, with a status code for any error.
+// This is the id in the path, or on the single instance routes, the first instance of this node.
+// An empty id is returned when the single instance routes are used on a node without instances.
+func instanceID(ctx *fiber.Ctx) (string, int, error) {
+	if id := ctx.Params("id"); len(id) != 0 {
+		// Only act on instances that belong to this node.
+		has, err := config.HasInstance(id)
 		if err != nil {
-			ctx.Status(http.StatusInternalServerError)
-			return ctx.JSON(presenter.SetupErrorResponse(fmt.Errorf("error removing instance ID from config: \n%v", err)))
+			return "", http.StatusInternalServerError, fmt.Errorf("error reading from config: \n%v", err)
+		} else if !has {
+			return "", http.StatusNotFound, fmt.Errorf("instance %v does not exist on this node", id)
 		}
+		return id, http.StatusOK, nil
+	}
 
-		// Return success if the server is deleted.
-		ctx.Status(http.StatusOK)
-		return ctx.JSON(presenter.ServerSuccessResponse(&types.Server{}))
+	ids, err := config.GetInstances()
+	if err != nil {
+		return "", http.StatusInternalServerError, fmt.Errorf("error reading from config: \n%v", err)
+	} else if len(ids) == 0 {
+		return "", http.StatusOK, nil
+	}
+	return ids[0], http.StatusOK, nil
+}
+
+// createInstance checks a new server instance fits on this node, then deploys it in the background,
+// followed by any further steps such as installing files. It returns the job deploying the instance,
+// or a status code and error. Finish, if set, is called once the job has finished, or straight away if none is submitted,
+// to release what the further steps need such as the files they install.
+func createInstance(ctx *fiber.Ctx, server types.Server, action string, then func(runtime engine.Runtime, id string) []jobs.Step, finish func()) (types.Job, int, error) {
+	submitted := false
+	if finish != nil {
+		defer func() {
+			if !submitted {
+				finish()
+			}
+		}()
+	}
+
+	// Get the game schema.
+	gameSchema, err := schema.GetSchema(server.Game.Name)
+	if err != nil {
+		return types.Job{}, http.StatusBadRequest, fmt.Errorf("error reading schema: \n%v", err)
+	}
+	if server.Game, err = schema.ChooseGame(gameSchema, server.Game); err != nil {
+		return types.Job{}, http.StatusBadRequest, err
+	}
+	requestedProperties := map[string]any{}
+	for name, value := range server.Properties {
+		requestedProperties[name] = value
+	}
+	if server.Properties, err = schema.ChangeProperties(gameSchema, nil, requestedProperties); err != nil {
+		return types.Job{}, http.StatusBadRequest, err
+	}
+
+	// Check the server fits alongside the existing instances, holding its resources until it is deployed.
+	requested, err := capacity.ForServer(gameSchema, server)
+	if err != nil {
+		return types.Job{}, http.StatusBadRequest, fmt.Errorf("error reading server resources: \n%v", err)
+	}
+	id := uuid.New().String()
+	if err := reserveCapacity(ctx.Context(), id, requested); errors.Is(err, errInsufficientCapacity) {
+		return types.Job{}, http.StatusConflict, err
+	} else if err != nil {
+		return types.Job{}, http.StatusInternalServerError, fmt.Errorf("error checking capacity: \n%v", err)
+	}
+
+	middleware.AuditInstance(ctx, id)
+
+	// Add the server details. The image is pinned when it is deployed, and nothing is pending for a new server.
+	server.Status = "running"
+	server.Image = ""
+	server.Pending = nil
+	middleware.AuditAfter(ctx, server)
+
+	spec := lifecycle.Spec{Schema: gameSchema, Server: server}
+	job, err := jobs.Submit(action, id, func(jobCtx context.Context) error {
+		// Once the job has finished, the instance is either saved and counted as allocated, or undone.
+		defer func() {
+			if _, err := config.ReleaseInstance(id); err != nil {
+				log.Printf("error releasing resources of %v: %v", id, err)
+			}
+			if finish != nil {
+				finish()
+			}
+		}()
+
+		runtime, err := engine.Current()
+		if err != nil {
+			return fmt.Errorf("error creating runtime: \n%v", err)
+		}
+		steps := lifecycle.Create(runtime, id, spec)
+		if then != nil {
+			steps = append(steps, then(runtime, id)...)
+		}
+		return jobs.RunSteps(jobCtx, steps)
+	})
+	if err != nil {
+		// The resources are only held while the instance is being created.
+		if _, releaseErr := config.ReleaseInstance(id); releaseErr != nil {
+			log.Printf("error releasing resources of %v: %v", id, releaseErr)
+		}
+		return types.Job{}, http.StatusConflict, err
+	}
+
+	submitted = true
+	return job, http.StatusAccepted, nil
+}
+
+// reserveCapacity holds the requested resources for a new instance, returning errInsufficientCapacity
+// if they do not fit on the host alongside the instances and reservations already on this node.
+func reserveCapacity(ctx context.Context, id string, requested types.Resources) error {
+	runtime, err := engine.Current()
+	if err != nil {
+		return fmt.Errorf("error creating runtime: \n%v", err)
+	}
+	host, err := runtime.Resources(ctx)
+	if err != nil {
+		return fmt.Errorf("error reading host resources: \n%v", err)
+	}
+
+	// Add up the resources of the existing instances and reservations, under the config lock
+	// so no other instance is reserved in between.
+	var checkErr error
+	_, err = config.ReserveInstance(id, requested, func(cfg config.Config) error {
+		used, err := node.AllocatedTo(ctx, cfg)
+		if err != nil {
+			checkErr = fmt.Errorf("error reading allocated resources: \n%v", err)
+		} else if err := capacity.Check(host, used, requested); err != nil {
+			checkErr = fmt.Errorf("%w: %v", errInsufficientCapacity, err)
+		}
+		return checkErr
+	})
+	// The error of the check is returned as it is, rather than wrapped by the config.
+	if checkErr != nil {
+		return checkErr
 	}
+	return err
 }
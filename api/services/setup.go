@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -29,13 +30,23 @@ func Setup() fiber.Handler {
 			return ctx.JSON(presenter.SetupErrorResponse(fmt.Errorf("error in provided body: \n%v", err)))
 		}
 
+		// Keep the existing config for the audit log.
+		if oldConfig, err := config.Read(); err == nil {
+			middleware.AuditBefore(ctx, oldConfig)
+		}
+
 		// Add or update the cluster ID in the config.
-		newConfig, err = config.Update(config.Config{ClusterID: newConfig.ClusterID})
-		if err != nil {
+		newConfig, err = config.UpdateClusterId(newConfig.ClusterID)
+		if errors.Is(err, config.ErrClusterInUse) {
+			ctx.Status(http.StatusConflict)
+			return ctx.JSON(presenter.SetupErrorResponse(config.ErrClusterInUse))
+		} else if err != nil {
 			ctx.Status(http.StatusInternalServerError)
 			return ctx.JSON(presenter.SetupErrorResponse(fmt.Errorf("error updating local config: \n%v", err)))
 		}
 
+		middleware.AuditAfter(ctx, newConfig)
+
 		ctx.Status(http.StatusOK)
 		return ctx.JSON(presenter.SetupSuccessResponse(newConfig))
 	}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/RicochetStudios/aurora/api/middleware"
+	"github.com/RicochetStudios/aurora/api/presenter"
+	"github.com/RicochetStudios/aurora/audit"
+	"github.com/RicochetStudios/aurora/config"
+	"github.com/RicochetStudios/aurora/db"
+	"github.com/RicochetStudios/aurora/engine"
+	"github.com/RicochetStudios/aurora/jobs"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/world"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// UploadWorld replaces the world of a server with the zip or tar.gz archive in the request body in the background.
+// The archive is checked before the job is submitted, and the server is stopped while its world is replaced.
+func UploadWorld() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		runtime, id, dir, status, err := worldInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditInstance(ctx, id)
+
+		// The upload is not saved when it could not be applied.
+		if jobs.Active(id) {
+			ctx.Status(http.StatusConflict)
+			return ctx.JSON(presenter.JobErrorResponse(jobs.ErrActive))
+		}
+
+		// Large bodies are streamed, so the archive is spooled to disk rather than held in memory.
+		var body io.Reader = ctx.Request().BodyStream()
+		if body == nil {
+			body = bytes.NewReader(ctx.Body())
+		}
+		archive, err := world.Read(body, int64(config.Current().WorldLimit)*1024*1024)
+		if errors.Is(err, world.ErrTooLarge) {
+			ctx.Status(http.StatusRequestEntityTooLarge)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		} else if err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+		middleware.AuditAfter(ctx, fiber.Map{"format": archive.Format, "size": archive.Size})
+
+		// The archive is removed by the job, or here if another job started while it was saved.
+		steps := world.Upload(runtime, id, dir, archive)
+		job, err := jobs.Submit(audit.ActionWorldUpload, id, func(jobCtx context.Context) error {
+			defer archive.Close()
+			return jobs.RunSteps(jobCtx, steps)
+		})
+		if err != nil {
+			archive.Close()
+			ctx.Status(http.StatusConflict)
+			return ctx.JSON(presenter.JobErrorResponse(err))
+		}
+		return accepted(ctx, job)
+	}
+}
+
+// DownloadWorld streams the world of a server as an archive of the format query parameter, zip by default.
+// The world is copied as it is, so a running server may be saving while it is downloaded.
+func DownloadWorld() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+
+		// Check User Role.
+		err := middleware.ProtectRoute(ctx)
+		if err != nil {
+			ctx.Status(http.StatusForbidden)
+			return ctx.JSON(presenter.AuthErrorResponse(fmt.Errorf("error authenticating request: %v", err)))
+		}
+
+		format := ctx.Query("format", world.FormatZip)
+		if format != world.FormatZip && format != world.FormatTarGz {
+			ctx.Status(http.StatusBadRequest)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("format %q must be %v or %v", format, world.FormatZip, world.FormatTarGz)))
+		}
+
+		runtime, id, dir, status, err := worldInstance(ctx)
+		if err != nil {
+			ctx.Status(status)
+			return ctx.JSON(presenter.ServerErrorResponse(err))
+		}
+
+		archive, err := runtime.CopyFrom(ctx.Context(), id, dir)
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return ctx.JSON(presenter.ServerErrorResponse(fmt.Errorf("error copying world: \n%v", err)))
+		}
+
+		// The status has been sent by the time the archive fails, so the download is cut short instead.
+		ctx.Attachment("world-" + id + "." + format)
+		ctx.Status(http.StatusOK)
+		ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			defer archive.Close()
+			if err := world.Export(archive, w, format); err != nil {
+				log.Printf("error downloading world of %v: %v", id, err)
+				return
+			}
+			w.Flush()
+		})
+		return nil
+	}
+}
+
+// worldInstance finds the instance of a request, the runtime of its workload and the directory of its world.
+func worldInstance(ctx *fiber.Ctx) (engine.Runtime, string, string, int, error) {
+	runtime, id, status, err := runtimeInstance(ctx)
+	if err != nil {
+		return nil, "", "", status, err
+	}
+
+	server, err := db.GetServer(ctx.Context(), id)
+	if err != nil {
+		return nil, "", "", http.StatusInternalServerError, fmt.Errorf("error reading server details from the database: \n%v", err)
+	}
+	gameSchema, err := schema.GetSchema(server.Game.Name)
+	if err != nil {
+		return nil, "", "", http.StatusInternalServerError, fmt.Errorf("error reading schema: \n%v", err)
+	}
+	dir, err := schema.WorldDir(gameSchema)
+	if err != nil {
+		return nil, "", "", http.StatusBadRequest, err
+	}
+
+	return runtime, id, dir, http.StatusOK, nil
+}
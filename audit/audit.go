@@ -0,0 +1,223 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/RicochetStudios/aurora/types"
+)
+
+const (
+	// ActionSetup is recorded when the local configuration is initialised or changed.
+	ActionSetup string = "setup.update"
+
+	// ActionServerCreate is recorded when a server is created.
+	ActionServerCreate string = "server.create"
+
+	// ActionServerImport is recorded when a server is created from a modpack.
+	ActionServerImport string = "server.import"
+
+	// ActionServerSchedule is recorded when a server is scheduled onto a node of the cluster.
+	ActionServerSchedule string = "server.schedule"
+
+	// ActionServerUpdate is recorded when a server is updated, or created on the single server routes.
+	ActionServerUpdate string = "server.update"
+
+	// ActionServerRemove is recorded when a server is stopped and deleted.
+	ActionServerRemove string = "server.remove"
+
+	// ActionServerStart is recorded when a stopped server is started.
+	ActionServerStart string = "server.start"
+
+	// ActionServerStop is recorded when a server is stopped, keeping its data.
+	ActionServerStop string = "server.stop"
+
+	// ActionServerImageUpdate is recorded when a server is moved to the current digest of its image.
+	ActionServerImageUpdate string = "server.image.update"
+
+	// ActionServerProperties is recorded when the game properties of a server are changed.
+	ActionServerProperties string = "server.properties"
+
+	// ActionModAdd is recorded when a mod or plugin is uploaded to a server.
+	ActionModAdd string = "mod.add"
+
+	// ActionModEnable is recorded when a disabled mod of a server is enabled.
+	ActionModEnable string = "mod.enable"
+
+	// ActionModDisable is recorded when a mod of a server is disabled, keeping its jar.
+	ActionModDisable string = "mod.disable"
+
+	// ActionModRemove is recorded when a mod is deleted from a server.
+	ActionModRemove string = "mod.remove"
+
+	// ActionWorldUpload is recorded when the world of a server is replaced with an uploaded archive.
+	ActionWorldUpload string = "world.upload"
+
+	// ActionFileWrite is recorded when a file of a server is written with the file manager.
+	ActionFileWrite string = "file.write"
+
+	// ActionFileRename is recorded when a file or directory of a server is renamed with the file manager.
+	ActionFileRename string = "file.rename"
+
+	// ActionFileDelete is recorded when a file or directory of a server is deleted with the file manager.
+	ActionFileDelete string = "file.delete"
+
+	// ActionFileMkdir is recorded when a directory of a server is created with the file manager.
+	ActionFileMkdir string = "file.mkdir"
+
+	// ActionPlayerAdd is recorded when a player is added to a list of a server, such as its allow list, operators or bans.
+	ActionPlayerAdd string = "player.add"
+
+	// ActionPlayerRemove is recorded when a player is removed from a list of a server.
+	ActionPlayerRemove string = "player.remove"
+
+	// ActionReconcileRedeploy is recorded when the reconciler recreates a missing workload.
+	ActionReconcileRedeploy string = "reconcile.redeploy"
+
+	// ActionReconcileUpdate is recorded when the reconciler reapplies the stored configuration to a drifted workload.
+	ActionReconcileUpdate string = "reconcile.update"
+
+	// ActionReconcileRestart is recorded when the reconciler restarts a workload which stopped unexpectedly.
+	ActionReconcileRestart string = "reconcile.restart"
+
+	// ActionReconcileStop is recorded when the reconciler stops a workload which should be stopped.
+	ActionReconcileStop string = "reconcile.stop"
+
+	// ActionAuthGrant is recorded when a user is given membership.
+	ActionAuthGrant string = "auth.grant"
+
+	// ResultSuccess is the result of an operation which completed.
+	ResultSuccess string = "success"
+
+	// ResultFailure is the result of an operation which returned an error.
+	ResultFailure string = "failure"
+
+	// ResultAccepted is the result of a request which started a job. The outcome of the job
+	// is recorded by a further event with the same job id, once it has finished.
+	ResultAccepted string = "accepted"
+
+	// Anonymous is the actor recorded when a request is not authenticated.
+	Anonymous string = "anonymous"
+
+	// Reconciler is the actor recorded for corrective actions taken by the reconciler.
+	Reconciler string = "system:reconciler"
+)
+
+// Filter narrows down which audit events are returned.
+// Empty fields are ignored.
+type Filter struct {
+	Actor  string    // Only include events performed by this actor.
+	Action string    // Only include events of this action.
+	Since  time.Time // Only include events at or after this time.
+	Until  time.Time // Only include events at or before this time.
+	Limit  int       // The maximum number of events to return, 0 is unlimited.
+}
+
+// Match reports whether an event satisfies the filter.
+func (f Filter) Match(event types.AuditEvent) bool {
+	if f.Actor != "" && event.Actor != f.Actor {
+		return false
+	}
+	if f.Action != "" && event.Action != f.Action {
+		return false
+	}
+	if !f.Since.IsZero() && event.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && event.Timestamp.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Diff compares two values field by field and returns the fields which differ.
+// Either value may be nil, in which case every field of the other is reported.
+func Diff(before, after any) ([]types.AuditChange, error) {
+	oldFields, err := flatten(before)
+	if err != nil {
+		return nil, fmt.Errorf("Diff() error flattening before: %v", err)
+	}
+	newFields, err := flatten(after)
+	if err != nil {
+		return nil, fmt.Errorf("Diff() error flattening after: %v", err)
+	}
+
+	// Collect every field present on either side.
+	fields := map[string]struct{}{}
+	for field := range oldFields {
+		fields[field] = struct{}{}
+	}
+	for field := range newFields {
+		fields[field] = struct{}{}
+	}
+
+	// Sort the fields so the changes are returned in a stable order.
+	var keys []string
+	for field := range fields {
+		keys = append(keys, field)
+	}
+	sort.Strings(keys)
+
+	changes := []types.AuditChange{}
+	for _, field := range keys {
+		if oldFields[field] != newFields[field] {
+			changes = append(changes, types.AuditChange{
+				Field: field,
+				Old:   oldFields[field],
+				New:   newFields[field],
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+// flatten converts a value into a map of dot separated json paths to their values.
+func flatten(v any) (map[string]string, error) {
+	out := map[string]string{}
+	if v == nil {
+		return out, nil
+	}
+
+	// Round trip through json so the paths match the api field names.
+	as_json, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic any
+	if err := json.Unmarshal(as_json, &generic); err != nil {
+		return nil, err
+	}
+
+	flattenInto(out, "", generic)
+	return out, nil
+}
+
+// flattenInto walks a decoded json value, writing every leaf into out.
+func flattenInto(out map[string]string, prefix string, v any) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch value := v.(type) {
+	case map[string]any:
+		for key, child := range value {
+			flattenInto(out, join(key), child)
+		}
+	case []any:
+		for i, child := range value {
+			flattenInto(out, join(fmt.Sprint(i)), child)
+		}
+	case nil:
+		// Treat null the same as a missing field.
+	default:
+		if prefix != "" {
+			out[prefix] = fmt.Sprint(value)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestDiff calls Diff with two servers,
+// checking only the modified fields are returned.
+func TestDiff(t *testing.T) {
+	before := types.Server{
+		Name: "mytest",
+		Size: "xs",
+		Game: types.Game{Name: "minecraft_java", Modloader: "vanilla"},
+	}
+	after := types.Server{
+		Name: "mytest",
+		Size: "s",
+		Game: types.Game{Name: "minecraft_java", Modloader: "forge"},
+	}
+
+	var want []types.AuditChange = []types.AuditChange{
+		{Field: "game.modloader", Old: "vanilla", New: "forge"},
+		{Field: "size", Old: "xs", New: "s"},
+	}
+
+	got, err := Diff(before, after)
+
+	if err != nil {
+		t.Fatalf("Diff() returned an error: \n%v", err)
+	}
+	// Use cmp for more complex types.
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Diff() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestDiffNil calls Diff with no previous value,
+// checking every populated field is returned.
+func TestDiffNil(t *testing.T) {
+	var want []types.AuditChange = []types.AuditChange{
+		{Field: "clusterId", Old: "", New: "myclusterid"},
+		{Field: "id", Old: "", New: "00000001"},
+	}
+
+	got, err := Diff(nil, map[string]string{"id": "00000001", "clusterId": "myclusterid"})
+
+	if err != nil {
+		t.Fatalf("Diff() returned an error: \n%v", err)
+	}
+	// Use cmp for more complex types.
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Diff() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestFilterMatch calls Match with a range of filters,
+// checking events are only matched when every populated field matches.
+func TestFilterMatch(t *testing.T) {
+	now := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	event := types.AuditEvent{
+		Actor:     "user-1",
+		Action:    ActionServerRemove,
+		Timestamp: now,
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"empty", Filter{}, true},
+		{"actor", Filter{Actor: "user-1"}, true},
+		{"other actor", Filter{Actor: "user-2"}, false},
+		{"action", Filter{Action: ActionServerRemove}, true},
+		{"other action", Filter{Action: ActionServerUpdate}, false},
+		{"since", Filter{Since: now.Add(-time.Hour)}, true},
+		{"since after", Filter{Since: now.Add(time.Hour)}, false},
+		{"until", Filter{Until: now.Add(time.Hour)}, true},
+		{"until before", Filter{Until: now.Add(-time.Hour)}, false},
+		{"all", Filter{Actor: "user-1", Action: ActionServerRemove, Since: now, Until: now}, true},
+	}
+
+	for _, test := range tests {
+		if got := test.filter.Match(event); got != test.want {
+			t.Fatalf("Match() (%v) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -1,10 +1,43 @@
 package main
 
 import (
+	"context"
+	"log"
+	"os"
+
 	"github.com/RicochetStudios/aurora/api"
+	"github.com/RicochetStudios/aurora/cli"
+	"github.com/RicochetStudios/aurora/config"
+	"github.com/RicochetStudios/aurora/node"
+	"github.com/RicochetStudios/aurora/reconciler"
 )
 
 func main() {
+	// Resolve the settings from defaults, the settings file, environment and flags.
+	_, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Run a subcommand instead of the node, if one was given.
+	if len(args) > 0 {
+		if err := cli.Run(args, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Release resources held by jobs creating instances before the last restart, as they no longer run.
+	if _, err := config.ClearReservations(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Register with the cluster and keep sending heartbeats.
+	go node.Run(context.Background())
+
+	// Keep the servers of this node matching their stored state.
+	go reconciler.Run(context.Background())
+
 	// Start the API.
 	api.Start()
 }
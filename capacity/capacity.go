@@ -0,0 +1,169 @@
+package capacity
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+)
+
+// memorySuffixes are the multipliers of the supported memory quantity suffixes.
+// Binary suffixes are listed first, so "Mi" is matched before "M".
+var memorySuffixes = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"Ki", 1 << 10},
+	{"Mi", 1 << 20},
+	{"Gi", 1 << 30},
+	{"Ti", 1 << 40},
+	{"k", 1000},
+	{"K", 1000},
+	{"M", 1000 * 1000},
+	{"G", 1000 * 1000 * 1000},
+	{"T", 1000 * 1000 * 1000 * 1000},
+}
+
+// ParseCPU converts a CPU quantity into millicores e.g. "1500m" or "1.5" are 1500.
+func ParseCPU(quantity string) (int64, error) {
+	if milli, found := strings.CutSuffix(quantity, "m"); found {
+		n, err := strconv.ParseInt(milli, 10, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid cpu quantity %q", quantity)
+		}
+		return n, nil
+	}
+
+	cores, err := strconv.ParseFloat(quantity, 64)
+	if err != nil || cores < 0 {
+		return 0, fmt.Errorf("invalid cpu quantity %q", quantity)
+	}
+	return int64(cores * 1000), nil
+}
+
+// ParseMemory converts a memory quantity into bytes e.g. "2000Mi" or "2G".
+func ParseMemory(quantity string) (int64, error) {
+	multiplier := int64(1)
+	number := quantity
+	for _, s := range memorySuffixes {
+		if n, found := strings.CutSuffix(quantity, s.suffix); found {
+			multiplier = s.multiplier
+			number = n
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid memory quantity %q", quantity)
+	}
+	return n * multiplier, nil
+}
+
+// Parse converts the resources of a schema size into absolute amounts.
+func Parse(resources schema.Resources) (types.Resources, error) {
+	cpu, err := ParseCPU(resources.CPU)
+	if err != nil {
+		return types.Resources{}, err
+	}
+	memory, err := ParseMemory(resources.Memory)
+	if err != nil {
+		return types.Resources{}, err
+	}
+	return types.Resources{MilliCPU: cpu, Memory: memory}, nil
+}
+
+// ForServer returns the resources a server requires, from the size it requests in its game schema.
+func ForServer(gameSchema schema.Schema, server types.Server) (types.Resources, error) {
+	size, ok := gameSchema.Sizes[server.Size]
+	if !ok {
+		return types.Resources{}, fmt.Errorf("size %q is not supported by %v", server.Size, gameSchema.Name)
+	}
+	return Parse(size.Resources)
+}
+
+// Sum adds resources together.
+func Sum(resources ...types.Resources) types.Resources {
+	var total types.Resources
+	for _, r := range resources {
+		total.MilliCPU += r.MilliCPU
+		total.Memory += r.Memory
+	}
+	return total
+}
+
+// Free returns the resources remaining on a host once the used resources are taken away.
+func Free(host types.Resources, used types.Resources) types.Resources {
+	return types.Resources{
+		MilliCPU: host.MilliCPU - used.MilliCPU,
+		Memory:   host.Memory - used.Memory,
+	}
+}
+
+// Check returns an error describing the shortfall if the requested resources
+// do not fit on a host alongside the resources already used.
+func Check(host types.Resources, used types.Resources, requested types.Resources) error {
+	free := Free(host, used)
+
+	var problems []string
+	if requested.MilliCPU > free.MilliCPU {
+		problems = append(problems, fmt.Sprintf("requires %vm cpu but %vm of %vm is free", requested.MilliCPU, nonNegative(free.MilliCPU), host.MilliCPU))
+	}
+	if requested.Memory > free.Memory {
+		problems = append(problems, fmt.Sprintf("requires %v bytes of memory but %v of %v is free", requested.Memory, nonNegative(free.Memory), host.Memory))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("insufficient capacity: %v", strings.Join(problems, ", "))
+	}
+
+	return nil
+}
+
+// Host returns the total CPU and memory of the machine Aurora is running on.
+// Memory is read from /proc/meminfo, so is only available on linux.
+func Host() (types.Resources, error) {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return types.Resources{}, fmt.Errorf("Host() error reading memory: %v", err)
+	}
+	defer file.Close()
+
+	// The line we need looks like "MemTotal:       16314656 kB".
+	var memory int64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == "MemTotal:" && fields[2] == "kB" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return types.Resources{}, fmt.Errorf("Host() error parsing memory: %v", err)
+			}
+			memory = kb * 1024
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return types.Resources{}, fmt.Errorf("Host() error reading memory: %v", err)
+	}
+	if memory == 0 {
+		return types.Resources{}, fmt.Errorf("Host() total memory not found")
+	}
+
+	return types.Resources{
+		MilliCPU: int64(runtime.NumCPU()) * 1000,
+		Memory:   memory,
+	}, nil
+}
+
+// nonNegative returns n, or 0 if n is negative.
+func nonNegative(n int64) int64 {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
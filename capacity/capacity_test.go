@@ -0,0 +1,102 @@
+package capacity
+
+import (
+	"testing"
+
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestParseCPU calls ParseCPU with a range of quantities,
+// checking for the correct millicores in return.
+func TestParseCPU(t *testing.T) {
+	tests := map[string]int64{
+		"1000m": 1000,
+		"1500m": 1500,
+		"2":     2000,
+		"0.5":   500,
+	}
+	for quantity, want := range tests {
+		got, err := ParseCPU(quantity)
+		if err != nil || got != want {
+			t.Fatalf("ParseCPU(%q) = %v, %v, want %v, nil", quantity, got, err, want)
+		}
+	}
+
+	for _, quantity := range []string{"", "m", "abc", "-1"} {
+		if _, err := ParseCPU(quantity); err == nil {
+			t.Fatalf("ParseCPU(%q) expected an invalid quantity error, got %v", quantity, err)
+		}
+	}
+}
+
+// TestParseMemory calls ParseMemory with a range of quantities,
+// checking for the correct bytes in return.
+func TestParseMemory(t *testing.T) {
+	tests := map[string]int64{
+		"2000Mi": 2000 << 20,
+		"10Gi":   10 << 30,
+		"2G":     2000000000,
+		"512":    512,
+	}
+	for quantity, want := range tests {
+		got, err := ParseMemory(quantity)
+		if err != nil || got != want {
+			t.Fatalf("ParseMemory(%q) = %v, %v, want %v, nil", quantity, got, err, want)
+		}
+	}
+
+	for _, quantity := range []string{"", "Mi", "10Xi", "-1Gi"} {
+		if _, err := ParseMemory(quantity); err == nil {
+			t.Fatalf("ParseMemory(%q) expected an invalid quantity error, got %v", quantity, err)
+		}
+	}
+}
+
+// TestForServer calls ForServer with a schema and server,
+// checking the resources of the requested size are returned.
+func TestForServer(t *testing.T) {
+	gameSchema := schema.Schema{
+		Name: "minecraft_java",
+		Sizes: map[string]schema.Size{
+			"xs": {Resources: schema.Resources{CPU: "1000m", Memory: "2000Mi"}, Players: 8},
+		},
+	}
+
+	var want types.Resources = types.Resources{MilliCPU: 1000, Memory: 2000 << 20}
+
+	got, err := ForServer(gameSchema, types.Server{Size: "xs"})
+
+	if err != nil {
+		t.Fatalf("ForServer() returned an error: \n%v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("ForServer() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := ForServer(gameSchema, types.Server{Size: "xxl"}); err == nil {
+		t.Fatalf("ForServer() expected an unsupported size error, got %v", err)
+	}
+}
+
+// TestCheck calls Check with requests which do and do not fit,
+// checking for an error only when the host is short of resources.
+func TestCheck(t *testing.T) {
+	host := types.Resources{MilliCPU: 4000, Memory: 8 << 30}
+	used := Sum(
+		types.Resources{MilliCPU: 1000, Memory: 2 << 30},
+		types.Resources{MilliCPU: 1000, Memory: 2 << 30},
+	)
+
+	if err := Check(host, used, types.Resources{MilliCPU: 2000, Memory: 4 << 30}); err != nil {
+		t.Fatalf("Check() returned an error for a request which fits: \n%v", err)
+	}
+	if err := Check(host, used, types.Resources{MilliCPU: 2001, Memory: 1 << 30}); err == nil {
+		t.Fatalf("Check() expected an insufficient cpu error, got %v", err)
+	}
+	if err := Check(host, used, types.Resources{MilliCPU: 1000, Memory: 5 << 30}); err == nil {
+		t.Fatalf("Check() expected an insufficient memory error, got %v", err)
+	}
+}
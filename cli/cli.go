@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/RicochetStudios/aurora/kubernetes"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/uuid"
+)
+
+// Run runs the subcommand named by the first argument, writing its output to out.
+func Run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("Run() no subcommand provided")
+	}
+
+	switch args[0] {
+	case "manifests":
+		return manifests(args[1:], out)
+	case "schema":
+		return schemaCommand(args[1:], out)
+	default:
+		return fmt.Errorf("Run() unknown subcommand %q", args[0])
+	}
+}
+
+// manifests prints the Kubernetes manifests for a server described by flags.
+func manifests(args []string, out io.Writer) error {
+	var server types.Server
+
+	fs := flag.NewFlagSet("manifests", flag.ContinueOnError)
+	fs.SetOutput(out)
+	id := fs.String("id", uuid.New().String(), "ID of the server instance.")
+	fs.StringVar(&server.Name, "name", "", "Name of the server.")
+	fs.StringVar(&server.Size, "size", "", "Size of the server, as defined by the game schema.")
+	fs.StringVar(&server.Game.Name, "game", "", "Name of the game schema.")
+	fs.StringVar(&server.Game.Modloader, "modloader", "", "Modloader of the game, the first of the game schema when empty.")
+	fs.StringVar(&server.Game.Version, "version", "", "Version of the game, the default of the game schema when empty.")
+	fs.StringVar(&server.Game.ModloaderVersion, "modloader-version", "", "Version of the modloader, chosen by the image when empty.")
+	fs.StringVar(&server.Network.Type, "network", "private", "Network type of the server, private or public.")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("manifests() error parsing flags: %v", err)
+	}
+	if server.Game.Name == "" || server.Size == "" {
+		return fmt.Errorf("manifests() the game and size flags are required")
+	}
+
+	gameSchema, err := schema.GetSchema(server.Game.Name)
+	if err != nil {
+		return fmt.Errorf("manifests() error getting schema: \n%v", err)
+	}
+	if server.Game, err = schema.ChooseGame(gameSchema, server.Game); err != nil {
+		return fmt.Errorf("manifests() %v", err)
+	}
+
+	rendered, err := kubernetes.Render(*id, gameSchema, server)
+	if err != nil {
+		return fmt.Errorf("manifests() error rendering manifests: \n%v", err)
+	}
+	as_yaml, err := rendered.YAML()
+	if err != nil {
+		return fmt.Errorf("manifests() error encoding manifests: \n%v", err)
+	}
+
+	_, err = out.Write(as_yaml)
+	return err
+}
+
+// schemaCommand runs the schema subcommand named by the first argument.
+func schemaCommand(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("schema() no subcommand provided")
+	}
+
+	switch args[0] {
+	case "lint":
+		return lint(args[1:], out)
+	default:
+		return fmt.Errorf("schema() unknown subcommand %q", args[0])
+	}
+}
+
+// lint validates game schemas, printing every problem with the file and line it is on.
+// Arguments are games or paths of schema files, and every game is linted when none are given.
+func lint(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.SetOutput(out)
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("lint() error parsing flags: %v", err)
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		games, err := schema.Games()
+		if err != nil {
+			return fmt.Errorf("lint() error listing schemas: \n%v", err)
+		}
+		files = games
+	}
+
+	var invalid int
+	for _, file := range files {
+		// Arguments which are not files are games of the schema directory.
+		if _, err := os.Stat(file); err != nil && !strings.ContainsAny(file, `/\.`) {
+			dir, err := schema.Dir()
+			if err != nil {
+				return fmt.Errorf("lint() error finding schemas: %v", err)
+			}
+			file = filepath.Join(dir, file, "schema.yaml")
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("lint() error reading schema: %v", err)
+		}
+		_, err = schema.Validate(content)
+		var problems schema.ValidationErrors
+		if errors.As(err, &problems) {
+			invalid++
+			for _, problem := range problems {
+				message := problem.Message
+				if problem.Field != "" {
+					message = problem.Field + ": " + message
+				}
+				// Problems of the bases the schema extends are reported in their own files.
+				location := file
+				if problem.File != "" {
+					dir, err := schema.Dir()
+					if err != nil {
+						return fmt.Errorf("lint() error finding schemas: %v", err)
+					}
+					location = filepath.Join(dir, filepath.FromSlash(problem.File))
+				}
+				fmt.Fprintf(out, "%v:%v: %v\n", location, problem.Line, message)
+			}
+			continue
+		} else if err != nil {
+			return fmt.Errorf("lint() error validating %v: %v", file, err)
+		}
+		fmt.Fprintf(out, "%v: ok\n", file)
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("lint() %v of %v schemas are not valid", invalid, len(files))
+	}
+	return nil
+}
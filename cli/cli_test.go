@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestRunInvalid calls Run with missing, unknown and incomplete subcommands,
+// checking for an error in return.
+func TestRunInvalid(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"unknown"},
+		{"manifests", "--size", "xs"},
+		{"manifests", "--game", "minecraft_java"},
+	}
+
+	for _, args := range tests {
+		if err := Run(args, io.Discard); err == nil {
+			t.Fatalf("Run(%v) expected an error, got %v", args, err)
+		}
+	}
+}
+
+// TestLint calls Run with the schema lint subcommand on valid and invalid schema files,
+// checking the problems are printed with their lines and an error is returned.
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	valid, err := os.ReadFile(filepath.Join("..", "schema", "minecraft_java", "schema.yaml"))
+	if err != nil {
+		t.Fatalf("TestLint() error reading schema: %v", err)
+	}
+	validFile := filepath.Join(dir, "valid.yaml")
+	invalidFile := filepath.Join(dir, "invalid.yaml")
+	os.WriteFile(validFile, valid, 0644)
+	os.WriteFile(invalidFile, bytes.Replace(valid, []byte("protocol: tcp"), []byte("protocol: sctp"), 1), 0644)
+
+	var out bytes.Buffer
+	if err := Run([]string{"schema", "lint", validFile}, &out); err != nil {
+		t.Fatalf("Run() linting a valid schema returned an error: \n%v\n%s", err, out.String())
+	}
+
+	out.Reset()
+	if err := Run([]string{"schema", "lint", invalidFile}, &out); err == nil {
+		t.Fatalf("Run() linting an invalid schema returned no error")
+	}
+	want := invalidFile + `:34: network[0].protocol: must be tcp or udp, not "sctp"` + "\n"
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Fatalf("Run() lint output mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestLintBase calls Run with the schema lint subcommand on a schema extending a base which is not valid,
+// checking the problem is printed with the file and line of the base.
+func TestLintBase(t *testing.T) {
+	valid, err := os.ReadFile(filepath.Join("..", "schema", "minecraft_java", "schema.yaml"))
+	if err != nil {
+		t.Fatalf("TestLintBase() error reading schema: %v", err)
+	}
+
+	// Bases are read from the schema directory of the working directory.
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("TestLintBase() error getting working directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("TestLintBase() error changing directory: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	baseFile := filepath.Join(dir, "schema", "base", "query.yaml")
+	os.MkdirAll(filepath.Dir(baseFile), 0755)
+	os.WriteFile(baseFile, []byte("network:\n  - name: query\n    port: 70000\n    protocol: udp\n"), 0644)
+	file := filepath.Join(dir, "extended.yaml")
+	os.WriteFile(file, append([]byte("extends: query\n"), valid...), 0644)
+
+	var out bytes.Buffer
+	if err := Run([]string{"schema", "lint", file}, &out); err == nil {
+		t.Fatalf("Run() linting a schema with an invalid base returned no error")
+	}
+	want := baseFile + `:3: network[0].port: must be between 1 and 65535, not 70000` + "\n"
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Fatalf("Run() lint output mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -5,150 +5,353 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/RicochetStudios/aurora/types"
 
 	"dario.cat/mergo"
+	"github.com/google/uuid"
 )
 
-// configPath is the path to the config file.
+// configPath is the default path to the config file, relative to the working directory.
 const configPath string = "/aurora-config.json"
 
 // Config is a struct of the local, persistent configuration of this instance.
 type Config struct {
-	ID        string `json:"id" yaml:"id" xml:"id" form:"id"`                             // The identifier of the instance.
-	ClusterID string `json:"clusterId" yaml:"clusterId" xml:"clusterId" form:"clusterId"` // The cluster this instance belongs to.
+	NodeID    string   `json:"nodeId" yaml:"nodeId" xml:"nodeId" form:"nodeId"`             // The identifier of this node, generated when it first registers.
+	ClusterID string   `json:"clusterId" yaml:"clusterId" xml:"clusterId" form:"clusterId"` // The cluster this node belongs to.
+	Instances []string `json:"instances" yaml:"instances" xml:"instances" form:"instances"` // The identifiers of the instances running on this node.
+
+	// The resources held for instances which are being created, by their identifier.
+	Reservations map[string]types.Resources `json:"reservations,omitempty" yaml:"reservations,omitempty" xml:"reservations,omitempty" form:"reservations,omitempty"`
+}
+
+// stateFile is the layout of the config file on disk.
+type stateFile struct {
+	Version int `json:"version"` // The version of the file layout, used to migrate older files.
+	Config
 }
 
+// ErrClusterInUse is returned when the cluster id is changed while the node has instances,
+// whose records are stored beneath the cluster and would be left behind.
+var ErrClusterInUse error = errors.New("cluster id can not be changed while instances exist")
+
+// mu serialises access to the config file within this process.
+// The file is additionally locked to serialise access between processes.
+var mu sync.RWMutex
+
 // Update creates or modifies config properties.
 func Update(newConfig Config) (Config, error) {
-	// Get the file.
-	file, err := getFile()
+	config, err := modify(func(config *Config) error {
+		// Merge the configs, taking precidence from the input config.
+		// WithOverwriteWithEmptyValue allows us to overwrite populated fields even with empty fields.
+		return mergo.Merge(config, newConfig, mergo.WithOverwriteWithEmptyValue)
+	})
+	if err != nil {
+		return Config{}, fmt.Errorf("Update() error modifying config: %v", err)
+	}
+
+	return config, nil
+}
+
+// Read returns the current configuration.
+func Read() (Config, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	// Get the path of the config file.
+	path, err := filePath()
 	if err != nil {
-		return Config{}, fmt.Errorf("Update() error getting file: %v", err)
+		return Config{}, fmt.Errorf("Read() error getting file path: %v", err)
 	}
-	defer file.Close()
 
+	// Hold a shared lock, so no other process writes while we read.
+	unlock, err := lockFile(path, false)
+	if err != nil {
+		return Config{}, fmt.Errorf("Read() error locking file: %v", err)
+	}
+	defer unlock()
+
+	config, err := readFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("Read() error reading file: %v", err)
+	}
+
+	return config, nil
+}
+
+// GetInstances gets the ids of the instances on this node from the config.
+func GetInstances() ([]string, error) {
 	// Read the existing config.
 	config, err := Read()
 	if err != nil {
-		return Config{}, fmt.Errorf("Update() error reading config: %v", err)
+		return nil, fmt.Errorf("GetInstances() error reading config: %v", err)
 	}
 
-	// Merge the configs, taking precidence from the input config.
-	// WithOverwriteWithEmptyValue allows us to overwrite populated fields even with empty fields.
-	if err := mergo.Merge(&config, newConfig, mergo.WithOverwriteWithEmptyValue); err != nil {
-		return Config{}, fmt.Errorf("Update() error merging configs: %v", err)
+	return config.Instances, nil
+}
+
+// HasInstance reports whether an instance, given an id, is on this node.
+func HasInstance(id string) (bool, error) {
+	instances, err := GetInstances()
+	if err != nil {
+		return false, fmt.Errorf("HasInstance() error getting instances: %v", err)
 	}
 
-	// Update the file.
-	as_json, jsonErr := json.MarshalIndent(config, "", "\t")
-	if jsonErr != nil {
-		return Config{}, fmt.Errorf("Update() error converting config to json: %v", err)
+	for _, instance := range instances {
+		if instance == id {
+			return true, nil
+		}
 	}
-	if writeErr := os.WriteFile(file.Name(), as_json, 0666); writeErr != nil {
-		return Config{}, fmt.Errorf("Update() error writing json to file: %v", err)
+	return false, nil
+}
+
+// AddInstance adds an instance id to the config, if it is not already present.
+func AddInstance(id string) (Config, error) {
+	cfg, err := modify(func(config *Config) error {
+		for _, instance := range config.Instances {
+			if instance == id {
+				return nil
+			}
+		}
+		config.Instances = append(config.Instances, id)
+		return nil
+	})
+	if err != nil {
+		return Config{}, fmt.Errorf("AddInstance() error modifying config: %v", err)
 	}
 
-	return config, nil
+	return cfg, nil
 }
 
-// Read returns the current configuration.
-func Read() (Config, error) {
-	// Get the file.
-	file, err := getFile()
+// RemoveInstance removes an instance id from the config.
+func RemoveInstance(id string) (Config, error) {
+	cfg, err := modify(func(config *Config) error {
+		instances := []string{}
+		for _, instance := range config.Instances {
+			if instance != id {
+				instances = append(instances, instance)
+			}
+		}
+		config.Instances = instances
+		return nil
+	})
 	if err != nil {
-		return Config{}, fmt.Errorf("Read() error getting file: %v", err)
+		return Config{}, fmt.Errorf("RemoveInstance() error modifying config: %v", err)
 	}
-	defer file.Close()
 
-	// Load the file; returns []byte.
-	content, err := os.ReadFile(file.Name())
+	return cfg, nil
+}
+
+// ReserveInstance holds resources for an instance which is being created, if check accepts the config
+// with the instances and reservations already on this node. The check is made under the config locks,
+// so concurrent creates can not both claim the same resources.
+func ReserveInstance(id string, resources types.Resources, check func(config Config) error) (Config, error) {
+	cfg, err := modify(func(config *Config) error {
+		if err := check(*config); err != nil {
+			return err
+		}
+		if config.Reservations == nil {
+			config.Reservations = map[string]types.Resources{}
+		}
+		config.Reservations[id] = resources
+		return nil
+	})
 	if err != nil {
-		return Config{}, fmt.Errorf("Read() error reading json from file: %v", err)
+		return Config{}, fmt.Errorf("ReserveInstance() error modifying config: %w", err)
 	}
 
-	// Create an empty Config to be are target of unmarshalling.
-	var config Config
+	return cfg, nil
+}
 
-	// Unmarshal the JSON file into empty Config.
-	if err := json.Unmarshal(content, &config); err != nil {
-		return Config{}, fmt.Errorf("Read() error converting json from file to Config: %v", err)
+// ReleaseInstance releases the resources held for an instance by ReserveInstance.
+func ReleaseInstance(id string) (Config, error) {
+	cfg, err := modify(func(config *Config) error {
+		delete(config.Reservations, id)
+		return nil
+	})
+	if err != nil {
+		return Config{}, fmt.Errorf("ReleaseInstance() error modifying config: %v", err)
 	}
 
-	return config, nil
+	return cfg, nil
 }
 
-// GetId gets the instance id from the config.
-func GetId() (string, error) {
-	// Read the existing config.
-	config, err := Read()
+// ClearReservations releases the resources held for every instance.
+// Jobs creating instances do not survive a restart, so their reservations are cleared when the node starts.
+func ClearReservations() (Config, error) {
+	cfg, err := modify(func(config *Config) error {
+		config.Reservations = nil
+		return nil
+	})
 	if err != nil {
-		return "", fmt.Errorf("GetId() error reading config: %v", err)
+		return Config{}, fmt.Errorf("ClearReservations() error modifying config: %v", err)
 	}
 
-	return config.ID, nil
+	return cfg, nil
 }
 
-// UpdateId updates the instance id in the config, given an id.
-func UpdateId(id string) (Config, error) {
-	// Read the existing config.
-	cfg, err := Read()
+// GetNodeId gets the node id from the config, generating and storing one if it is not set.
+func GetNodeId() (string, error) {
+	cfg, err := modify(func(config *Config) error {
+		if len(config.NodeID) == 0 {
+			config.NodeID = uuid.New().String()
+		}
+		return nil
+	})
 	if err != nil {
-		return Config{}, fmt.Errorf("UpdateId() error reading config: %v", err)
+		return "", fmt.Errorf("GetNodeId() error modifying config: %v", err)
 	}
 
-	// Replace the ID.
-	cfg.ID = id
+	return cfg.NodeID, nil
+}
 
-	// Update the config with changes.
-	cfg, err = Update(cfg)
+// UpdateClusterId updates the cluster id in the config, given an id.
+// It returns ErrClusterInUse if the id changes while the node has instances.
+func UpdateClusterId(id string) (Config, error) {
+	cfg, err := modify(func(config *Config) error {
+		// Records are stored beneath the cluster, so changing it would orphan those of the instances.
+		if id != config.ClusterID && (len(config.Instances) > 0 || len(config.Reservations) > 0) {
+			return ErrClusterInUse
+		}
+
+		// Replace the ID.
+		config.ClusterID = id
+		return nil
+	})
 	if err != nil {
-		return Config{}, fmt.Errorf("UpdateId() error updating config: %v", err)
+		return Config{}, fmt.Errorf("UpdateClusterId() error modifying config: %w", err)
 	}
 
 	return cfg, nil
 }
 
-// getFile returns the config file.
-// Additionally it will create the file if it does not exist.
-func getFile() (*os.File, error) {
-	var file *os.File
+// modify reads the config, applies a change to it and writes it back,
+// holding the locks throughout so concurrent changes are not lost.
+func modify(change func(*Config) error) (Config, error) {
+	mu.Lock()
+	defer mu.Unlock()
 
-	// Get the working directory.
-	wd, wdErr := os.Getwd()
-	if wdErr != nil {
-		return file, fmt.Errorf("getFile() error getting working directory: %v", wdErr)
+	// Get the path of the config file.
+	path, err := filePath()
+	if err != nil {
+		return Config{}, fmt.Errorf("modify() error getting file path: %v", err)
 	}
 
-	// Check path exists.
-	_, pathErr := os.Stat(wd + configPath)
+	// Hold an exclusive lock, so no other process reads or writes until we are done.
+	unlock, err := lockFile(path, true)
+	if err != nil {
+		return Config{}, fmt.Errorf("modify() error locking file: %v", err)
+	}
+	defer unlock()
 
-	// Scope variables correctly.
-	var err error
+	// Read the existing config.
+	config, err := readFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("modify() error reading file: %v", err)
+	}
 
-	if errors.Is(pathErr, os.ErrNotExist) {
-		// Create the file if it doesn't exist.
-		file, err = os.Create(wd + configPath)
-		if err != nil {
-			return &os.File{}, fmt.Errorf("getFile() error creating file: %v", err)
-		}
+	if err := change(&config); err != nil {
+		return Config{}, fmt.Errorf("modify() error changing config: %w", err)
+	}
 
-		// Create an empty json file.
-		as_json, jsonErr := json.MarshalIndent(struct{}{}, "", "\t")
-		if jsonErr != nil {
-			return &os.File{}, fmt.Errorf("getFile() error marshalling struct{}{} to json: %v", jsonErr)
-		}
-		if writeErr := os.WriteFile(file.Name(), as_json, 0666); writeErr != nil {
-			return &os.File{}, fmt.Errorf("getFile() error writing to file: %v", writeErr)
-		}
-	} else if !errors.Is(pathErr, os.ErrNotExist) {
-		// Otherwise return the existing file.
-		file, err = os.Open(wd + configPath)
-		if err != nil {
-			return &os.File{}, fmt.Errorf("getFile() error opening file: %v", err)
-		}
-	} else {
-		return &os.File{}, fmt.Errorf("getFile() error checking path exists: %v", pathErr)
+	// Update the file.
+	if err := writeFile(path, config); err != nil {
+		return Config{}, fmt.Errorf("modify() error writing file: %v", err)
+	}
+
+	return config, nil
+}
+
+// readFile loads the config file, migrating it to the current version if required.
+// A missing file is treated as an empty config.
+func readFile(path string) (Config, error) {
+	// Load the file; returns []byte.
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Config{}, nil
+	} else if err != nil {
+		return Config{}, fmt.Errorf("readFile() error reading json from file: %v", err)
+	}
+
+	// Decode generically first, so older layouts can be migrated.
+	var raw map[string]any
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return Config{}, fmt.Errorf("readFile() error converting json from file: %v", err)
+	}
+	if raw == nil {
+		raw = map[string]any{}
 	}
+	if err := migrate(raw); err != nil {
+		return Config{}, fmt.Errorf("readFile() error migrating config: %v", err)
+	}
+
+	// Convert the migrated file into the current layout.
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return Config{}, fmt.Errorf("readFile() error converting migrated config to json: %v", err)
+	}
+	var file stateFile
+	if err := json.Unmarshal(migrated, &file); err != nil {
+		return Config{}, fmt.Errorf("readFile() error converting json to Config: %v", err)
+	}
+
+	return file.Config, nil
+}
+
+// writeFile replaces the config file atomically.
+// The config is written to a temporary file which is synced and then renamed over the original,
+// so a crash leaves either the old or the new config, never a partial one.
+func writeFile(path string, config Config) error {
+	as_json, err := json.MarshalIndent(stateFile{Version: currentVersion, Config: config}, "", "\t")
+	if err != nil {
+		return fmt.Errorf("writeFile() error converting config to json: %v", err)
+	}
+
+	// The temporary file must be in the same directory for the rename to be atomic.
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("writeFile() error creating directory: %v", err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("writeFile() error creating temporary file: %v", err)
+	}
+	// Clean up the temporary file if anything goes wrong before the rename.
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(as_json); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writeFile() error writing temporary file: %v", err)
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writeFile() error setting permissions: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writeFile() error syncing temporary file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writeFile() error closing temporary file: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writeFile() error replacing file: %v", err)
+	}
+
+	// Sync the directory so the rename itself survives a crash.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
 
-	return file, nil
+// filePath returns the absolute path to the config file, from the current settings.
+// Relative paths are resolved against the working directory.
+func filePath() (string, error) {
+	return filepath.Abs(Current().StatePath)
 }
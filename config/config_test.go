@@ -1,11 +1,16 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"sync"
 	"testing"
 
+	"github.com/RicochetStudios/aurora/types"
+
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -52,13 +57,13 @@ func TestUpdate(t *testing.T) {
 
 	// Test creation.
 	var createWant Config = Config{
-		ID:        "00000001",
 		ClusterID: "myclusterid",
+		Instances: []string{"00000001"},
 	}
 
 	createGot, createErr := Update(Config{
-		ID:        "00000001",
 		ClusterID: "myclusterid",
+		Instances: []string{"00000001"},
 	})
 
 	if createErr != nil {
@@ -76,13 +81,13 @@ func TestUpdate(t *testing.T) {
 
 	// Test updating.
 	var modifyWant Config = Config{
-		ID:        "00000002",
 		ClusterID: "mynewclusterid",
+		Instances: []string{"00000002"},
 	}
 
 	modifyGot, modifyErr := Update(Config{
-		ID:        "00000002",
 		ClusterID: "mynewclusterid",
+		Instances: []string{"00000002"},
 	})
 
 	if modifyErr != nil {
@@ -99,62 +104,338 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
-// TestGetId calls GetId,
-// checking for an id in return.
-func TestGetId(t *testing.T) {
+// TestGetInstances calls GetInstances,
+// checking for the instance ids in return.
+func TestGetInstances(t *testing.T) {
+	// Cleanup at the end of the test.
+	t.Cleanup(func() {
+		if err := cleanup(); err != nil {
+			t.Fatalf("TestGetInstances() error cleaning up:\n%v", err)
+		}
+	})
+
+	var want []string = []string{"00000001", "00000002"}
+
+	// Setup the config file.
+	if _, err := Update(Config{
+		Instances: []string{"00000001", "00000002"},
+	}); err != nil {
+		t.Fatalf("TestGetInstances() error setting up the config: \n%v", err)
+	}
+
+	got, err := GetInstances()
+
+	if err != nil {
+		t.Fatalf("TestGetInstances() error getting the instances from the config: \n%v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("TestGetInstances() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestAddInstance calls AddInstance with a new and an existing id,
+// checking the id is only added once.
+func TestAddInstance(t *testing.T) {
 	// Cleanup at the end of the test.
 	t.Cleanup(func() {
 		if err := cleanup(); err != nil {
-			t.Fatalf("TestGetId() error cleaning up:\n%v", err)
+			t.Fatalf("TestAddInstance() error cleaning up:\n%v", err)
 		}
 	})
 
-	var want string = "00000001"
+	var want []string = []string{"00000001", "00000002"}
 
 	// Setup the config file.
 	if _, err := Update(Config{
-		ID: "00000001",
+		Instances: []string{"00000001"},
 	}); err != nil {
-		t.Fatalf("TestGetId() error setting up the config: \n%v", err)
+		t.Fatalf("TestAddInstance() error setting up the config: \n%v", err)
 	}
 
-	got, err := GetId()
+	// Add the ids.
+	if _, err := AddInstance("00000002"); err != nil {
+		t.Fatalf("TestAddInstance() error adding the id to the config: \n%v", err)
+	}
+	got, err := AddInstance("00000001")
 
 	if err != nil {
-		t.Fatalf("TestGetId() error getting the id from the config: \n%v", err)
+		t.Fatalf("TestAddInstance() error adding the id to the config: \n%v", err)
 	}
-	if got != want {
-		t.Fatalf("TestGetId() = %q, %v, want match for %#q, nil", got, err, want)
+	if diff := cmp.Diff(want, got.Instances); diff != "" {
+		t.Fatalf("TestAddInstance() mismatch (-want +got):\n%s", diff)
+	}
+
+	has, err := HasInstance("00000002")
+	if err != nil || !has {
+		t.Fatalf("HasInstance() = %v, %v, want true, nil", has, err)
 	}
 }
 
-// TestUpdateId calls UpdateId with an id,
-// checking the id was updated.
-func TestUpdateId(t *testing.T) {
+// TestRemoveInstance calls RemoveInstance with an id,
+// checking only that id was removed.
+func TestRemoveInstance(t *testing.T) {
 	// Cleanup at the end of the test.
 	t.Cleanup(func() {
 		if err := cleanup(); err != nil {
-			t.Fatalf("TestUpdateId() error cleaning up:\n%v", err)
+			t.Fatalf("TestRemoveInstance() error cleaning up:\n%v", err)
 		}
 	})
 
-	var want string = "00000002"
+	var want []string = []string{"00000002"}
 
 	// Setup the config file.
 	if _, err := Update(Config{
-		ID: "00000001",
+		Instances: []string{"00000001", "00000002"},
 	}); err != nil {
-		t.Fatalf("TestUpdateId() error setting up the config: \n%v", err)
+		t.Fatalf("TestRemoveInstance() error setting up the config: \n%v", err)
+	}
+
+	// Remove the id.
+	got, err := RemoveInstance("00000001")
+
+	if err != nil {
+		t.Fatalf("TestRemoveInstance() error removing the id from the config: \n%v", err)
+	}
+	if diff := cmp.Diff(want, got.Instances); diff != "" {
+		t.Fatalf("TestRemoveInstance() mismatch (-want +got):\n%s", diff)
+	}
+
+	has, err := HasInstance("00000001")
+	if err != nil || has {
+		t.Fatalf("HasInstance() = %v, %v, want false, nil", has, err)
+	}
+}
+
+// TestReserveInstance calls ReserveInstance with a check which accepts and one which rejects the config,
+// checking only accepted reservations are held, each check sees those already held, and ReleaseInstance releases them.
+func TestReserveInstance(t *testing.T) {
+	// Cleanup at the end of the test.
+	t.Cleanup(func() {
+		if err := cleanup(); err != nil {
+			t.Fatalf("TestReserveInstance() error cleaning up:\n%v", err)
+		}
+	})
+
+	errFull := errors.New("full")
+	first := types.Resources{MilliCPU: 1000, Memory: 2048}
+	if _, err := ReserveInstance("00000001", first, func(config Config) error { return nil }); err != nil {
+		t.Fatalf("ReserveInstance() returned an error: \n%v", err)
+	}
+
+	var seen map[string]types.Resources
+	_, err := ReserveInstance("00000002", first, func(config Config) error {
+		seen = config.Reservations
+		return errFull
+	})
+	if !errors.Is(err, errFull) {
+		t.Fatalf("ReserveInstance() returned %v, want %v", err, errFull)
+	}
+	if diff := cmp.Diff(map[string]types.Resources{"00000001": first}, seen); diff != "" {
+		t.Fatalf("ReserveInstance() check mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err := Read()
+	if err != nil {
+		t.Fatalf("TestReserveInstance() error reading config: \n%v", err)
+	}
+	if diff := cmp.Diff(map[string]types.Resources{"00000001": first}, got.Reservations); diff != "" {
+		t.Fatalf("ReserveInstance() mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = ReleaseInstance("00000001")
+	if err != nil {
+		t.Fatalf("ReleaseInstance() returned an error: \n%v", err)
+	}
+	if len(got.Reservations) != 0 {
+		t.Fatalf("ReleaseInstance() reservations = %v, want none", got.Reservations)
+	}
+}
+
+// TestUpdateClusterId calls UpdateClusterId before and after an instance is added,
+// checking the cluster id can only be changed while the node has no instances.
+func TestUpdateClusterId(t *testing.T) {
+	// Cleanup at the end of the test.
+	t.Cleanup(func() {
+		if err := cleanup(); err != nil {
+			t.Fatalf("TestUpdateClusterId() error cleaning up:\n%v", err)
+		}
+	})
+
+	if _, err := UpdateClusterId("first"); err != nil {
+		t.Fatalf("UpdateClusterId() returned an error: \n%v", err)
+	}
+	if _, err := AddInstance("00000001"); err != nil {
+		t.Fatalf("TestUpdateClusterId() error adding an instance: \n%v", err)
+	}
+
+	// Setting the same id again changes nothing, so is allowed.
+	if _, err := UpdateClusterId("first"); err != nil {
+		t.Fatalf("UpdateClusterId() with the same id returned an error: \n%v", err)
+	}
+	if _, err := UpdateClusterId("second"); !errors.Is(err, ErrClusterInUse) {
+		t.Fatalf("UpdateClusterId() returned %v, want %v", err, ErrClusterInUse)
+	}
+
+	got, err := Read()
+	if err != nil {
+		t.Fatalf("TestUpdateClusterId() error reading config: \n%v", err)
+	}
+	if got.ClusterID != "first" {
+		t.Fatalf("UpdateClusterId() cluster id = %q, want %q", got.ClusterID, "first")
+	}
+}
+
+// TestUpdateConcurrent calls AddInstance and Read from many goroutines at once,
+// checking no update is lost and the file remains valid.
+func TestUpdateConcurrent(t *testing.T) {
+	// Cleanup at the end of the test.
+	t.Cleanup(func() {
+		if err := cleanup(); err != nil {
+			t.Fatalf("TestUpdateConcurrent() error cleaning up:\n%v", err)
+		}
+	})
+
+	// Each goroutine adds a distinct id, so an update lost to another writer leaves its id missing.
+	want := []string{}
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("%08d", i)
+		want = append(want, id)
+		wg.Add(2)
+		go func(id string) {
+			defer wg.Done()
+			if _, err := AddInstance(id); err != nil {
+				errs <- err
+			}
+		}(id)
+		go func() {
+			defer wg.Done()
+			// Read alongside the writers.
+			if _, err := Read(); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("TestUpdateConcurrent() returned an error: \n%v", err)
+	}
+
+	// Reload the file from disk, rather than trusting the result of the last writer.
+	path, err := filePath()
+	if err != nil {
+		t.Fatalf("TestUpdateConcurrent() error getting file path: \n%v", err)
+	}
+	got, err := readFile(path)
+	if err != nil {
+		t.Fatalf("TestUpdateConcurrent() error reading config: \n%v", err)
+	}
+	sort.Strings(got.Instances)
+	if diff := cmp.Diff(want, got.Instances); diff != "" {
+		t.Fatalf("TestUpdateConcurrent() instances mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestUpdatePermissions calls Update,
+// checking the file is only accessible by its owner.
+func TestUpdatePermissions(t *testing.T) {
+	// Cleanup at the end of the test.
+	t.Cleanup(func() {
+		if err := cleanup(); err != nil {
+			t.Fatalf("TestUpdatePermissions() error cleaning up:\n%v", err)
+		}
+	})
+
+	if _, err := AddInstance("00000001"); err != nil {
+		t.Fatalf("TestUpdatePermissions() returned an error: \n%v", err)
+	}
+
+	path, err := filePath()
+	if err != nil {
+		t.Fatalf("TestUpdatePermissions() error getting file path: \n%v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("TestUpdatePermissions() error checking file: \n%v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Fatalf("TestUpdatePermissions() permissions = %v, want %v", perm, os.FileMode(0600))
+	}
+}
+
+// TestReadMigrates calls Read with a config file from before versioning,
+// checking the single instance id is migrated into the list of instances
+// and the file is written back with the current version.
+func TestReadMigrates(t *testing.T) {
+	// Cleanup at the end of the test.
+	t.Cleanup(func() {
+		if err := cleanup(); err != nil {
+			t.Fatalf("TestReadMigrates() error cleaning up:\n%v", err)
+		}
+	})
+
+	path, err := filePath()
+	if err != nil {
+		t.Fatalf("TestReadMigrates() error getting file path: \n%v", err)
+	}
+	if err := os.WriteFile(path, []byte(`{"id": "00000001", "clusterId": "myclusterid"}`), 0600); err != nil {
+		t.Fatalf("TestReadMigrates() error writing legacy file: \n%v", err)
+	}
+
+	var want Config = Config{
+		ClusterID: "myclusterid",
+		Instances: []string{"00000001"},
+	}
+
+	got, err := Read()
+
+	if err != nil {
+		t.Fatalf("TestReadMigrates() returned an error: \n%v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("TestReadMigrates() mismatch (-want +got):\n%s", diff)
 	}
 
-	// Update the id.
-	got, err := UpdateId("00000002")
+	// Writing persists the current version.
+	if _, err := Update(got); err != nil {
+		t.Fatalf("TestReadMigrates() error updating config: \n%v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("TestReadMigrates() error reading file: \n%v", err)
+	}
+	var file stateFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		t.Fatalf("TestReadMigrates() error converting file: \n%v", err)
+	}
+	if file.Version != currentVersion {
+		t.Fatalf("TestReadMigrates() version = %v, want %v", file.Version, currentVersion)
+	}
+}
 
+// TestReadNewerVersion calls Read with a config file from a newer version,
+// checking for an error in return.
+func TestReadNewerVersion(t *testing.T) {
+	// Cleanup at the end of the test.
+	t.Cleanup(func() {
+		if err := cleanup(); err != nil {
+			t.Fatalf("TestReadNewerVersion() error cleaning up:\n%v", err)
+		}
+	})
+
+	path, err := filePath()
 	if err != nil {
-		t.Fatalf("TestUpdateId() error updating the id in the config: \n%v", err)
+		t.Fatalf("TestReadNewerVersion() error getting file path: \n%v", err)
 	}
-	if got.ID != want {
-		t.Fatalf("TestUpdateId() = %q, %v, want match for %#q, nil", got, err, want)
+	content := fmt.Sprintf(`{"version": %v, "id": "00000001"}`, currentVersion+1)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("TestReadNewerVersion() error writing file: \n%v", err)
+	}
+
+	if _, err := Read(); err == nil {
+		t.Fatalf("Read() expected a version error, got %v", err)
 	}
 }
 
@@ -166,10 +447,12 @@ func cleanup() error {
 		return fmt.Errorf("cleanup() error getting working directory: %v", wdErr)
 	}
 
-	// Remove the file if it exists.
-	if _, pathErr := os.Stat(wd + configPath); !errors.Is(pathErr, os.ErrNotExist) {
-		if err := os.Remove(wd + configPath); err != nil {
-			return fmt.Errorf("cleanup() error removing file: %v", err)
+	// Remove the file and its lock if they exist.
+	for _, path := range []string{wd + configPath, wd + configPath + ".lock"} {
+		if _, pathErr := os.Stat(path); !errors.Is(pathErr, os.ErrNotExist) {
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("cleanup() error removing file: %v", err)
+			}
 		}
 	}
 
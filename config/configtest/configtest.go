@@ -0,0 +1,25 @@
+package configtest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/RicochetStudios/aurora/config"
+)
+
+// UseLocalStorage points the settings at local storage and config in a temporary directory,
+// restoring the defaults at the end of the test. It returns the directory documents are stored in.
+func UseLocalStorage(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	settings := config.Defaults()
+	settings.Storage = config.StorageLocal
+	settings.StoragePath = filepath.Join(dir, "data")
+	settings.StatePath = filepath.Join(dir, "aurora-config.json")
+	config.SetCurrent(settings)
+
+	t.Cleanup(func() { config.SetCurrent(config.Defaults()) })
+
+	return settings.StoragePath
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package config
+
+// lockFile is a no-op on platforms without advisory locks,
+// access is still serialised within this process.
+func lockFile(path string, exclusive bool) (func(), error) {
+	return func() {}, nil
+}
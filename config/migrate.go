@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+)
+
+// migrations upgrade the raw config file one version at a time.
+// migrations[n] upgrades a file from version n to version n+1.
+// Add new migrations to the end, never modify existing ones.
+var migrations = []func(raw map[string]any) error{
+	// 0 -> 1: files written before versioning have the same fields, only the version is added.
+	func(raw map[string]any) error { return nil },
+
+	// 1 -> 2: the single instance id becomes a list of instances, so a node can run several.
+	func(raw map[string]any) error {
+		instances := []any{}
+		if id, ok := raw["id"].(string); ok && id != "" {
+			instances = append(instances, id)
+		}
+		delete(raw, "id")
+		raw["instances"] = instances
+		return nil
+	},
+}
+
+// currentVersion is the version of the config file layout written by this build.
+var currentVersion int = len(migrations)
+
+// migrate upgrades a raw config file to the current version in place.
+func migrate(raw map[string]any) error {
+	version := 0
+	if v, ok := raw["version"]; ok {
+		// Numbers are decoded from json as float64.
+		f, ok := v.(float64)
+		if !ok || f != float64(int(f)) || f < 0 {
+			return fmt.Errorf("migrate() invalid version %v", v)
+		}
+		version = int(f)
+	}
+
+	if version > currentVersion {
+		return fmt.Errorf("migrate() config version %v is newer than the supported version %v", version, currentVersion)
+	}
+
+	for ; version < currentVersion; version++ {
+		if err := migrations[version](raw); err != nil {
+			return fmt.Errorf("migrate() error migrating from version %v: %v", version, err)
+		}
+	}
+	raw["version"] = float64(currentVersion)
+
+	return nil
+}
@@ -0,0 +1,354 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// envPrefix is prepended to a setting's name to create its environment variable.
+	envPrefix string = "AURORA_"
+
+	// configFileEnv is the environment variable pointing to the settings file.
+	configFileEnv string = envPrefix + "CONFIG"
+
+	// redacted replaces the value of secret settings when they are displayed.
+	redacted string = "[redacted]"
+
+	// StorageFirestore stores records in the Firestore database.
+	StorageFirestore string = "firestore"
+
+	// StorageLocal stores records as files on this node, useful for development and tests.
+	StorageLocal string = "local"
+
+	// RuntimeDocker runs servers as containers on the local docker engine.
+	RuntimeDocker string = "docker"
+
+	// RuntimeKubernetes runs servers as workloads in a Kubernetes cluster.
+	RuntimeKubernetes string = "kubernetes"
+)
+
+// Settings are the options Aurora is started with.
+// They are resolved from defaults, a settings file, AURORA_* environment variables and flags,
+// with each layer taking precedence over the one before it.
+// The yaml name of a field is also its flag name, and in upper snake case with the
+// AURORA_ prefix, its environment variable e.g. databaseUrl is --databaseUrl and AURORA_DATABASE_URL.
+type Settings struct {
+	Listen            string        `json:"listen" yaml:"listen" usage:"Address the API listens on."`
+	DatabaseURL       string        `json:"databaseUrl" yaml:"databaseUrl" usage:"URL of the Firebase realtime database."`
+	CredentialsPath   string        `json:"credentialsPath" yaml:"credentialsPath" usage:"Path to the Firebase service account key."`
+	CredentialsJSON   string        `json:"credentialsJson" yaml:"credentialsJson" usage:"Firebase service account key, used instead of credentialsPath." secret:"true"`
+	Environment       string        `json:"environment" yaml:"environment" usage:"Deployment environment, used as the Firestore collection."`
+	StatePath         string        `json:"statePath" yaml:"statePath" usage:"Path to the file holding this instance's persistent config."`
+	Storage           string        `json:"storage" yaml:"storage" usage:"Where records are stored, firestore or local."`
+	StoragePath       string        `json:"storagePath" yaml:"storagePath" usage:"Directory records are stored in, with the local storage."`
+	HeartbeatInterval time.Duration `json:"heartbeatInterval" yaml:"heartbeatInterval" usage:"How often this node reports to its cluster."`
+	NodeTimeout       time.Duration `json:"nodeTimeout" yaml:"nodeTimeout" usage:"How long a node may miss heartbeats before it is marked offline."`
+	AdvertiseURL      string        `json:"advertiseUrl" yaml:"advertiseUrl" usage:"URL other nodes reach this node's API at, required for servers to be scheduled onto it."`
+	Labels            string        `json:"labels" yaml:"labels" usage:"Comma separated key=value labels used to place servers e.g. region=eu-west,class=ssd."`
+	InsecureNodes     string        `json:"insecureNodes" yaml:"insecureNodes" usage:"Comma separated http addresses of nodes which may be sent credentials without https, such as nodes on a private network e.g. http://10.0.0.2:6969."`
+	Runtime           string        `json:"runtime" yaml:"runtime" usage:"Where servers run, docker or kubernetes."`
+	Namespace         string        `json:"namespace" yaml:"namespace" usage:"Kubernetes namespace servers run in, with the kubernetes runtime."`
+	Kubeconfig        string        `json:"kubeconfig" yaml:"kubeconfig" usage:"Path to a kubeconfig file, the in cluster config is used when empty."`
+	RegistryServer    string        `json:"registryServer" yaml:"registryServer" usage:"Registry the registry credentials are used with e.g. ghcr.io."`
+	RegistryUsername  string        `json:"registryUsername" yaml:"registryUsername" usage:"Username for pulling images from the registry server."`
+	RegistryPassword  string        `json:"registryPassword" yaml:"registryPassword" usage:"Password or access token for pulling images from the registry server." secret:"true"`
+	ReconcileInterval time.Duration `json:"reconcileInterval" yaml:"reconcileInterval" usage:"How often the servers of this node are checked against the runtime and repaired."`
+	UploadLimit       int           `json:"uploadLimit" yaml:"uploadLimit" usage:"Largest request body the API accepts in megabytes, which limits the size of uploaded files."`
+	WorldLimit        int           `json:"worldLimit" yaml:"worldLimit" usage:"Largest world an uploaded world archive may extract to in megabytes."`
+	FileLimit         int           `json:"fileLimit" yaml:"fileLimit" usage:"Largest file the file manager reads or writes in megabytes."`
+}
+
+// Defaults returns the settings used when nothing else is configured.
+func Defaults() Settings {
+	return Settings{
+		Listen:            ":6969",
+		DatabaseURL:       "https://game-server-e2c56-default-rtdb.europe-west1.firebasedatabase.app",
+		CredentialsPath:   "./firebase-config.json",
+		Environment:       "development",
+		StatePath:         "." + configPath,
+		Storage:           StorageFirestore,
+		StoragePath:       "./aurora-data",
+		HeartbeatInterval: 15 * time.Second,
+		NodeTimeout:       time.Minute,
+		Runtime:           RuntimeDocker,
+		Namespace:         "default",
+		RegistryServer:    "docker.io",
+		ReconcileInterval: 30 * time.Second,
+		UploadLimit:       1024,
+		WorldLimit:        10240,
+		FileLimit:         4,
+	}
+}
+
+// Version is the version of Aurora, set when building with
+// -ldflags "-X github.com/RicochetStudios/aurora/config.Version=<version>".
+var Version string = "dev"
+
+var (
+	// current holds the settings Aurora was started with.
+	current Settings = Defaults()
+
+	// currentMu guards current.
+	currentMu sync.RWMutex
+)
+
+// Current returns the settings Aurora was started with.
+func Current() Settings {
+	currentMu.RLock()
+	defer currentMu.RUnlock()
+	return current
+}
+
+// SetCurrent replaces the settings Aurora is running with.
+func SetCurrent(settings Settings) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = settings
+}
+
+// Load resolves the settings from the layers, given the command line arguments
+// (without the program name). The settings file is given with --config or AURORA_CONFIG.
+// The resolved settings are validated and made current, and any arguments
+// remaining after the flags are returned.
+func Load(args []string) (Settings, []string, error) {
+	settings := Defaults()
+
+	// Parse the flags first, to find the settings file, but apply them last.
+	fs := flag.NewFlagSet("aurora", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv(configFileEnv), "Path to a yaml or json settings file.")
+	flagValues := map[string]string{}
+	for _, f := range settings.fields() {
+		name := f.name
+		fs.Func(name, f.usage+fmt.Sprintf(" (env %v, default %q)", f.env, f.value.Interface()), func(raw string) error {
+			flagValues[name] = raw
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return Settings{}, nil, fmt.Errorf("Load() error parsing flags: %v", err)
+	}
+
+	// Apply the settings file.
+	if *configFile != "" {
+		content, err := os.ReadFile(*configFile)
+		if err != nil {
+			return Settings{}, nil, fmt.Errorf("Load() error reading settings file: %v", err)
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&settings); err != nil && !errors.Is(err, io.EOF) {
+			return Settings{}, nil, fmt.Errorf("Load() error parsing settings file %v: %v", *configFile, err)
+		}
+	}
+
+	// Apply environment variables, then flags.
+	for _, f := range settings.fields() {
+		if raw, ok := os.LookupEnv(f.env); ok {
+			if err := f.set(raw); err != nil {
+				return Settings{}, nil, fmt.Errorf("Load() error in environment variable %v: %v", f.env, err)
+			}
+		}
+		if raw, ok := flagValues[f.name]; ok {
+			if err := f.set(raw); err != nil {
+				return Settings{}, nil, fmt.Errorf("Load() error in flag --%v: %v", f.name, err)
+			}
+		}
+	}
+
+	if err := settings.Validate(); err != nil {
+		return Settings{}, nil, fmt.Errorf("Load() invalid settings:\n%v", err)
+	}
+
+	SetCurrent(settings)
+	return settings, fs.Args(), nil
+}
+
+// Validate checks every setting, returning all of the problems found.
+func (s Settings) Validate() error {
+	var errs []error
+
+	if _, _, err := net.SplitHostPort(s.Listen); err != nil {
+		errs = append(errs, fmt.Errorf("listen %q is not a valid address: %v", s.Listen, err))
+	}
+	if u, err := url.Parse(s.DatabaseURL); err != nil || u.Scheme != "https" || u.Host == "" {
+		errs = append(errs, fmt.Errorf("databaseUrl %q must be an https url", s.DatabaseURL))
+	}
+	if s.CredentialsPath == "" && s.CredentialsJSON == "" {
+		errs = append(errs, errors.New("one of credentialsPath or credentialsJson must be set"))
+	}
+	if s.Environment == "" || strings.Contains(s.Environment, "/") {
+		errs = append(errs, fmt.Errorf("environment %q must be a non-empty name without slashes", s.Environment))
+	}
+	if s.StatePath == "" {
+		errs = append(errs, errors.New("statePath must be set"))
+	}
+	if s.HeartbeatInterval <= 0 {
+		errs = append(errs, fmt.Errorf("heartbeatInterval %v must be positive", s.HeartbeatInterval))
+	}
+	if s.NodeTimeout <= s.HeartbeatInterval {
+		errs = append(errs, fmt.Errorf("nodeTimeout %v must be longer than heartbeatInterval %v", s.NodeTimeout, s.HeartbeatInterval))
+	}
+	if (s.RegistryUsername == "") != (s.RegistryPassword == "") {
+		errs = append(errs, errors.New("registryUsername and registryPassword must be set together"))
+	}
+	if s.RegistryUsername != "" && s.RegistryServer == "" {
+		errs = append(errs, errors.New("registryServer must be set with registry credentials"))
+	}
+	if s.ReconcileInterval <= 0 {
+		errs = append(errs, fmt.Errorf("reconcileInterval %v must be positive", s.ReconcileInterval))
+	}
+	if s.UploadLimit <= 0 {
+		errs = append(errs, fmt.Errorf("uploadLimit %v must be positive", s.UploadLimit))
+	}
+	if s.WorldLimit <= 0 {
+		errs = append(errs, fmt.Errorf("worldLimit %v must be positive", s.WorldLimit))
+	}
+	if s.FileLimit <= 0 {
+		errs = append(errs, fmt.Errorf("fileLimit %v must be positive", s.FileLimit))
+	}
+	if s.AdvertiseURL != "" {
+		if u, err := url.Parse(s.AdvertiseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("advertiseUrl %q must be an http or https url", s.AdvertiseURL))
+		}
+	}
+	if _, err := ParseLabels(s.Labels); err != nil {
+		errs = append(errs, err)
+	}
+	switch s.Storage {
+	case StorageFirestore:
+	case StorageLocal:
+		if s.StoragePath == "" {
+			errs = append(errs, errors.New("storagePath must be set with local storage"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("storage %q must be %v or %v", s.Storage, StorageFirestore, StorageLocal))
+	}
+	switch s.Runtime {
+	case RuntimeDocker:
+	case RuntimeKubernetes:
+		if s.Namespace == "" {
+			errs = append(errs, errors.New("namespace must be set with the kubernetes runtime"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("runtime %q must be %v or %v", s.Runtime, RuntimeDocker, RuntimeKubernetes))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ParseLabels converts comma separated key=value pairs into a map.
+func ParseLabels(labels string) (map[string]string, error) {
+	parsed := map[string]string{}
+	if strings.TrimSpace(labels) == "" {
+		return parsed, nil
+	}
+
+	for _, pair := range strings.Split(labels, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("label %q must be in the form key=value", pair)
+		}
+		parsed[key] = value
+	}
+
+	return parsed, nil
+}
+
+// Redacted returns a copy of the settings with secret values hidden, safe to display.
+func (s Settings) Redacted() Settings {
+	for _, f := range s.fields() {
+		if f.secret && !f.value.IsZero() {
+			f.value.SetString(redacted)
+		}
+	}
+	return s
+}
+
+// settingField is a single configurable field of Settings.
+type settingField struct {
+	name   string        // The yaml and flag name.
+	env    string        // The environment variable name.
+	usage  string        // Help text for the flag.
+	secret bool          // Whether the value must be hidden when displayed.
+	value  reflect.Value // The settable field.
+}
+
+// fields returns the configurable fields of the settings, which can be set in place.
+func (s *Settings) fields() []settingField {
+	var fields []settingField
+	v := reflect.ValueOf(s).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		structField := v.Type().Field(i)
+		name := strings.Split(structField.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		fields = append(fields, settingField{
+			name:   name,
+			env:    envPrefix + toUpperSnake(name),
+			usage:  structField.Tag.Get("usage"),
+			secret: structField.Tag.Get("secret") == "true",
+			value:  v.Field(i),
+		})
+	}
+	return fields
+}
+
+// set parses a raw value into the field, according to its type.
+func (f settingField) set(raw string) error {
+	// Durations are int64 underneath, so check for them first.
+	if f.value.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		f.value.SetInt(int64(d))
+		return nil
+	}
+
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.value.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		f.value.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported setting type %v", f.value.Type())
+	}
+	return nil
+}
+
+// toUpperSnake converts a camel case name to upper snake case e.g. databaseUrl to DATABASE_URL.
+func toUpperSnake(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			b.WriteByte('_')
+		}
+		b.WriteRune(r)
+	}
+	return strings.ToUpper(b.String())
+}
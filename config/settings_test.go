@@ -0,0 +1,136 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestLoadDefaults calls Load with no arguments,
+// checking the defaults are returned and made current.
+func TestLoadDefaults(t *testing.T) {
+	t.Cleanup(func() { SetCurrent(Defaults()) })
+
+	var want Settings = Defaults()
+
+	got, args, err := Load([]string{})
+
+	if err != nil {
+		t.Fatalf("Load() returned an error: \n%v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Load() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(want, Current()); diff != "" {
+		t.Fatalf("Current() mismatch (-want +got):\n%s", diff)
+	}
+	if len(args) != 0 {
+		t.Fatalf("Load() returned unexpected arguments: %v", args)
+	}
+}
+
+// TestLoadLayers calls Load with a settings file, environment variables and flags,
+// checking each layer takes precedence over the one before it.
+func TestLoadLayers(t *testing.T) {
+	t.Cleanup(func() { SetCurrent(Defaults()) })
+
+	// Create the settings file.
+	file := filepath.Join(t.TempDir(), "aurora.yaml")
+	content := "listen: \":7000\"\nenvironment: staging\nstatePath: /tmp/file-state.json\nnodeTimeout: 2m\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatalf("TestLoadLayers() error writing settings file: \n%v", err)
+	}
+
+	// The environment overrides the file, and flags override the environment.
+	t.Setenv("AURORA_CONFIG", file)
+	t.Setenv("AURORA_ENVIRONMENT", "production")
+	t.Setenv("AURORA_STATE_PATH", "/tmp/env-state.json")
+
+	var want Settings = Defaults()
+	want.Listen = ":7000"
+	want.Environment = "production"
+	want.StatePath = "/tmp/flag-state.json"
+	want.NodeTimeout = 2 * time.Minute
+	want.HeartbeatInterval = 30 * time.Second
+
+	got, args, err := Load([]string{"--statePath", "/tmp/flag-state.json", "--heartbeatInterval", "30s", "serve"})
+
+	if err != nil {
+		t.Fatalf("Load() returned an error: \n%v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Load() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"serve"}, args); diff != "" {
+		t.Fatalf("Load() arguments mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestLoadInvalid calls Load with invalid settings,
+// checking for an error in return and the current settings are unchanged.
+func TestLoadInvalid(t *testing.T) {
+	t.Cleanup(func() { SetCurrent(Defaults()) })
+
+	if _, _, err := Load([]string{"--listen", "nowhere", "--databaseUrl", "http://insecure"}); err == nil {
+		t.Fatalf("Load() expected an invalid settings error, got %v", err)
+	}
+	if diff := cmp.Diff(Defaults(), Current()); diff != "" {
+		t.Fatalf("Current() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestLoadUnknownKey calls Load with a settings file containing an unknown key,
+// checking for an error in return.
+func TestLoadUnknownKey(t *testing.T) {
+	t.Cleanup(func() { SetCurrent(Defaults()) })
+
+	file := filepath.Join(t.TempDir(), "aurora.yaml")
+	if err := os.WriteFile(file, []byte("lisen: \":7000\"\n"), 0600); err != nil {
+		t.Fatalf("TestLoadUnknownKey() error writing settings file: \n%v", err)
+	}
+
+	if _, _, err := Load([]string{"--config", file}); err == nil {
+		t.Fatalf("Load() expected an unknown key error, got %v", err)
+	}
+}
+
+// TestRedacted calls Redacted on settings with a secret,
+// checking the secret is hidden without modifying the original.
+func TestRedacted(t *testing.T) {
+	settings := Defaults()
+	settings.CredentialsJSON = `{"private_key": "secret"}`
+
+	got := settings.Redacted()
+
+	if got.CredentialsJSON != redacted {
+		t.Fatalf("Redacted() CredentialsJSON = %q, want %q", got.CredentialsJSON, redacted)
+	}
+	if got.CredentialsPath != settings.CredentialsPath {
+		t.Fatalf("Redacted() CredentialsPath = %q, want %q", got.CredentialsPath, settings.CredentialsPath)
+	}
+	if settings.CredentialsJSON == redacted {
+		t.Fatalf("Redacted() modified the original settings")
+	}
+}
+
+// TestParseLabels calls ParseLabels with valid and invalid labels,
+// checking for a map of labels or an error in return.
+func TestParseLabels(t *testing.T) {
+	var want map[string]string = map[string]string{"region": "eu-west", "class": "ssd"}
+
+	got, err := ParseLabels("region=eu-west, class=ssd")
+
+	if err != nil {
+		t.Fatalf("ParseLabels() returned an error: \n%v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("ParseLabels() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := ParseLabels("region"); err == nil {
+		t.Fatalf("ParseLabels() expected an invalid label error, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RicochetStudios/aurora/audit"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/uuid"
+)
+
+// AddAuditEvent stores an audit event, generating an ID if one is not set.
+func AddAuditEvent(ctx context.Context, event types.AuditEvent) (types.AuditEvent, error) {
+	if len(event.ID) == 0 {
+		event.ID = uuid.New().String()
+	}
+
+	path, err := documentPath(auditCollection, event.ID)
+	if err != nil {
+		return types.AuditEvent{}, fmt.Errorf("error getting audit event document path:\n%v", err)
+	}
+
+	// Events are never modified once written, so create fails if the ID is reused.
+	if err := currentStore().Create(ctx, path, event); err != nil {
+		return types.AuditEvent{}, fmt.Errorf("error writing audit event:\n%v", err)
+	}
+
+	return event, nil
+}
+
+// GetAuditEvents returns the audit events matching a filter, newest first.
+// The filter is applied by the store, so only the events returned are read.
+func GetAuditEvents(ctx context.Context, filter audit.Filter) ([]types.AuditEvent, error) {
+	query := Query{OrderBy: "Timestamp", Descending: true, Limit: filter.Limit}
+	if filter.Actor != "" {
+		query.Where = append(query.Where, Where{Field: "Actor", Op: "==", Value: filter.Actor})
+	}
+	if filter.Action != "" {
+		query.Where = append(query.Where, Where{Field: "Action", Op: "==", Value: filter.Action})
+	}
+	if !filter.Since.IsZero() {
+		query.Where = append(query.Where, Where{Field: "Timestamp", Op: ">=", Value: filter.Since})
+	}
+	if !filter.Until.IsZero() {
+		query.Where = append(query.Where, Where{Field: "Timestamp", Op: "<=", Value: filter.Until})
+	}
+
+	events, err := queryAs[types.AuditEvent](ctx, auditCollection, query)
+	if err != nil {
+		return nil, fmt.Errorf("error listing audit events:\n%v", err)
+	}
+
+	return events, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/RicochetStudios/aurora/config"
 	"github.com/RicochetStudios/aurora/types"
 
 	"cloud.google.com/go/firestore"
@@ -14,26 +15,25 @@ import (
 	"google.golang.org/api/option"
 )
 
-const (
-	// instancePath is the path to the instance documents.
-	instancePath string = "default/instances/"
+// firebaseOptions returns the Firebase config and credentials from the current settings.
+func firebaseOptions() (*firebase.Config, option.ClientOption) {
+	settings := config.Current()
 
-	// dbUrl is the url for the Firebase database instance.
-	dbUrl string = "https://game-server-e2c56-default-rtdb.europe-west1.firebasedatabase.app"
-
-	// dbAuth is the authentication config path, used to access firebase.
-	dbAuth string = "./firebase-config.json"
-)
-
-// Call this function to get/initialise the firebase app
-func Firebase(ctx context.Context) (*firebase.App, error) {
 	// configure database URL
 	conf := &firebase.Config{
-		DatabaseURL: dbUrl,
+		DatabaseURL: settings.DatabaseURL,
 	}
 
-	// fetch service account key
-	opt := option.WithCredentialsFile(dbAuth)
+	// fetch service account key, preferring an inline key
+	if settings.CredentialsJSON != "" {
+		return conf, option.WithCredentialsJSON([]byte(settings.CredentialsJSON))
+	}
+	return conf, option.WithCredentialsFile(settings.CredentialsPath)
+}
+
+// Call this function to get/initialise the firebase app
+func Firebase(ctx context.Context) (*firebase.App, error) {
+	conf, opt := firebaseOptions()
 
 	app, err := firebase.NewApp(ctx, conf, opt)
 	if err != nil {
@@ -45,13 +45,7 @@ func Firebase(ctx context.Context) (*firebase.App, error) {
 
 // Call this function to get/initialise the firebase auth
 func FirebaseAuth(ctx context.Context) (*auth.Client, error) {
-	// configure database URL
-	conf := &firebase.Config{
-		DatabaseURL: dbUrl,
-	}
-
-	// fetch service account key
-	opt := option.WithCredentialsFile(dbAuth)
+	conf, opt := firebaseOptions()
 
 	// initialize app
 	app, err := firebase.NewApp(ctx, conf, opt)
@@ -103,42 +97,55 @@ func Firestore(ctx context.Context) (*firestore.Client, error) {
 
 // GetServer reads and returns a server document, given an ID.
 func GetServer(ctx context.Context, id string) (types.Server, error) {
-	// Create the firestore client.
-	client, err := Firestore(ctx)
+	path, err := documentPath(instancesCollection, id)
 	if err != nil {
-		return types.Server{}, fmt.Errorf("error creating Firestore client:\n%v", err)
+		return types.Server{}, fmt.Errorf("error getting server document path:\n%v", err)
 	}
-	defer client.Close()
 
 	// Read the full document from the database.
-	// Temporarily hardcoding the collection, this needs to be changed to reflect the cluster it belongs to later.
-	document, err := client.Collection("development").Doc(instancePath + id).Get(ctx)
+	var server types.Server
+	if err := currentStore().Get(ctx, path, &server); err != nil {
+		return types.Server{}, fmt.Errorf("error reading server document:\n%w", err)
+	}
+
+	return server, nil
+}
+
+// ListServers reads and returns every server instance in the cluster.
+func ListServers(ctx context.Context) ([]types.Instance, error) {
+	path, err := collectionPath(instancesCollection)
 	if err != nil {
-		return types.Server{}, fmt.Errorf("error reading document from Firestore database:\n%v", err)
+		return nil, fmt.Errorf("error getting server collection path:\n%v", err)
 	}
 
-	// Convert the document into the server struct.
-	var server types.Server
-	if err := document.DataTo(&server); err != nil {
-		return types.Server{}, fmt.Errorf("error converting Firestore document to types.Server struct:\n%v", err)
+	documents, err := currentStore().List(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("error listing server documents:\n%v", err)
 	}
 
-	return server, nil
+	// Convert the documents into instances, the document id is the instance id.
+	instances := []types.Instance{}
+	for _, document := range documents {
+		var server types.Server
+		if err := document.DataTo(&server); err != nil {
+			return nil, fmt.Errorf("error converting document to types.Server struct:\n%v", err)
+		}
+		instances = append(instances, types.Instance{ID: document.ID, Server: server})
+	}
+
+	return instances, nil
 }
 
 // SetServer creates and overwrites fields in the server document, given a Server.
 func SetServer(ctx context.Context, id string, server types.Server) (types.Server, error) {
-	// Create the firestore client.
-	client, err := Firestore(ctx)
+	path, err := documentPath(instancesCollection, id)
 	if err != nil {
-		return types.Server{}, fmt.Errorf("error creating Firestore client:\n%v", err)
+		return types.Server{}, fmt.Errorf("error getting server document path:\n%v", err)
 	}
-	defer client.Close()
 
 	// Write to the database, overwriting existing fields and creating new ones.
-	// Temporarily hardcoding the collection, this needs to be changed to reflect the cluster it belongs to later.
-	if _, err := client.Collection("development").Doc(instancePath+id).Set(ctx, server); err != nil {
-		return types.Server{}, fmt.Errorf("error writing to document in Firestore database:\n%v", err)
+	if err := currentStore().Set(ctx, path, server); err != nil {
+		return types.Server{}, fmt.Errorf("error writing server document:\n%v", err)
 	}
 
 	return server, nil
@@ -146,17 +153,14 @@ func SetServer(ctx context.Context, id string, server types.Server) (types.Serve
 
 // RemoveServer removes an instance document from the database, given an ID.
 func RemoveServer(ctx context.Context, id string) error {
-	// Create the firestore client.
-	client, err := Firestore(ctx)
+	path, err := documentPath(instancesCollection, id)
 	if err != nil {
-		return fmt.Errorf("error creating Firestore client:\n%v", err)
+		return fmt.Errorf("error getting server document path:\n%v", err)
 	}
-	defer client.Close()
 
 	// Removing the server instance from the database by deleting the corresponding document.
-	// Temporarily hardcoding the collection, this needs to be changed to reflect the cluster it belongs to later.
-	if _, err := client.Collection("development").Doc(instancePath + id).Delete(ctx); err != nil {
-		return fmt.Errorf("error deleting document from Firestore database:\n%v", err)
+	if err := currentStore().Delete(ctx, path); err != nil {
+		return fmt.Errorf("error deleting server document:\n%v", err)
 	}
 
 	return nil
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RicochetStudios/aurora/audit"
+	"github.com/RicochetStudios/aurora/config"
+	"github.com/RicochetStudios/aurora/config/configtest"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestServerLifecycle calls SetServer, GetServer, ListServers and RemoveServer,
+// checking the server is stored, read and removed.
+func TestServerLifecycle(t *testing.T) {
+	configtest.UseLocalStorage(t)
+	ctx := context.Background()
+
+	var want types.Server = types.Server{
+		Name: "mytest",
+		Size: "xs",
+		Game: types.Game{Name: "minecraft_java", Modloader: "vanilla"},
+	}
+
+	if _, err := SetServer(ctx, "00000001", want); err != nil {
+		t.Fatalf("SetServer() returned an error: \n%v", err)
+	}
+
+	got, err := GetServer(ctx, "00000001")
+	if err != nil {
+		t.Fatalf("GetServer() returned an error: \n%v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("GetServer() mismatch (-want +got):\n%s", diff)
+	}
+
+	list, err := ListServers(ctx)
+	if err != nil {
+		t.Fatalf("ListServers() returned an error: \n%v", err)
+	}
+	if diff := cmp.Diff([]types.Instance{{ID: "00000001", Server: want}}, list); diff != "" {
+		t.Fatalf("ListServers() mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := RemoveServer(ctx, "00000001"); err != nil {
+		t.Fatalf("RemoveServer() returned an error: \n%v", err)
+	}
+	if _, err := GetServer(ctx, "00000001"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetServer() after removal = %v, want ErrNotFound", err)
+	}
+}
+
+// TestClusterScope calls SetServer before and after a cluster id is set up,
+// checking records are stored beneath the environment and cluster.
+func TestClusterScope(t *testing.T) {
+	root := configtest.UseLocalStorage(t)
+	ctx := context.Background()
+
+	if _, err := SetServer(ctx, "00000001", types.Server{Name: "before"}); err != nil {
+		t.Fatalf("SetServer() returned an error: \n%v", err)
+	}
+	if _, err := config.UpdateClusterId("mycluster"); err != nil {
+		t.Fatalf("UpdateClusterId() returned an error: \n%v", err)
+	}
+	if _, err := SetServer(ctx, "00000002", types.Server{Name: "after"}); err != nil {
+		t.Fatalf("SetServer() returned an error: \n%v", err)
+	}
+
+	for _, path := range []string{
+		"development/default/instances/00000001.json",
+		"development/mycluster/instances/00000002.json",
+	} {
+		if _, err := os.Stat(filepath.Join(root, path)); err != nil {
+			t.Fatalf("TestClusterScope() document %v was not created: \n%v", path, err)
+		}
+	}
+
+	// Only the instances of the current cluster are listed.
+	list, err := ListServers(ctx)
+	if err != nil {
+		t.Fatalf("ListServers() returned an error: \n%v", err)
+	}
+	if len(list) != 1 || list[0].ID != "00000002" {
+		t.Fatalf("ListServers() = %v, want only instance 00000002", list)
+	}
+}
+
+// TestGetAuditEvents calls AddAuditEvent and GetAuditEvents with a filter,
+// checking the matching events are returned newest first.
+func TestGetAuditEvents(t *testing.T) {
+	configtest.UseLocalStorage(t)
+	ctx := context.Background()
+
+	now := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	events := []types.AuditEvent{
+		{ID: "1", Actor: "user-1", Action: audit.ActionServerCreate, Timestamp: now.Add(-2 * time.Hour)},
+		{ID: "2", Actor: "user-2", Action: audit.ActionServerCreate, Timestamp: now.Add(-time.Hour)},
+		{ID: "3", Actor: "user-1", Action: audit.ActionServerRemove, Timestamp: now},
+		{ID: "4", Actor: "user-1", Action: audit.ActionServerCreate, Timestamp: now.Add(time.Hour)},
+	}
+	for _, event := range events {
+		if _, err := AddAuditEvent(ctx, event); err != nil {
+			t.Fatalf("AddAuditEvent() returned an error: \n%v", err)
+		}
+	}
+
+	// Events can not be overwritten.
+	if _, err := AddAuditEvent(ctx, events[0]); err == nil {
+		t.Fatalf("AddAuditEvent() expected an error adding a duplicate event, got %v", err)
+	}
+
+	got, err := GetAuditEvents(ctx, audit.Filter{
+		Actor:  "user-1",
+		Action: audit.ActionServerCreate,
+		Until:  now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("GetAuditEvents() returned an error: \n%v", err)
+	}
+	if diff := cmp.Diff([]types.AuditEvent{events[3], events[0]}, got); diff != "" {
+		t.Fatalf("GetAuditEvents() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The newest events are kept when the events are limited.
+	got, err = GetAuditEvents(ctx, audit.Filter{Since: now.Add(-time.Hour), Limit: 2})
+	if err != nil {
+		t.Fatalf("GetAuditEvents() returned an error: \n%v", err)
+	}
+	if diff := cmp.Diff([]types.AuditEvent{events[3], events[2]}, got); diff != "" {
+		t.Fatalf("GetAuditEvents() with a limit mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// firestoreStore stores documents in the Firestore database.
+// A client is created for each operation, as with the rest of the Firebase helpers.
+type firestoreStore struct{}
+
+// Get reads a document into dst, returning ErrNotFound if it does not exist.
+func (firestoreStore) Get(ctx context.Context, path string, dst any) error {
+	// Create the firestore client.
+	client, err := Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client:\n%v", err)
+	}
+	defer client.Close()
+
+	document, err := client.Doc(path).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return ErrNotFound
+	} else if err != nil {
+		return fmt.Errorf("error reading document from Firestore database:\n%v", err)
+	}
+
+	return document.DataTo(dst)
+}
+
+// Set creates or overwrites a document.
+func (firestoreStore) Set(ctx context.Context, path string, src any) error {
+	// Create the firestore client.
+	client, err := Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client:\n%v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.Doc(path).Set(ctx, src); err != nil {
+		return fmt.Errorf("error writing to document in Firestore database:\n%v", err)
+	}
+
+	return nil
+}
+
+// Create creates a document, failing if it already exists.
+func (firestoreStore) Create(ctx context.Context, path string, src any) error {
+	// Create the firestore client.
+	client, err := Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client:\n%v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.Doc(path).Create(ctx, src); err != nil {
+		return fmt.Errorf("error creating document in Firestore database:\n%v", err)
+	}
+
+	return nil
+}
+
+// Delete removes a document, succeeding if it does not exist.
+func (firestoreStore) Delete(ctx context.Context, path string) error {
+	// Create the firestore client.
+	client, err := Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client:\n%v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.Doc(path).Delete(ctx); err != nil {
+		return fmt.Errorf("error deleting document from Firestore database:\n%v", err)
+	}
+
+	return nil
+}
+
+// List returns every document in a collection.
+func (firestoreStore) List(ctx context.Context, collection string) ([]Document, error) {
+	// Create the firestore client.
+	client, err := Firestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Firestore client:\n%v", err)
+	}
+	defer client.Close()
+
+	documents := []Document{}
+	iter := client.Collection(collection).Documents(ctx)
+	defer iter.Stop()
+	for {
+		snapshot, err := iter.Next()
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("error listing documents from Firestore database:\n%v", err)
+		}
+		documents = append(documents, Document{ID: snapshot.Ref.ID, decode: snapshot.DataTo})
+	}
+
+	return documents, nil
+}
+
+// Query returns the documents in a collection matching every condition of a query, in its order and up to its limit.
+func (firestoreStore) Query(ctx context.Context, collection string, query Query) ([]Document, error) {
+	// Create the firestore client.
+	client, err := Firestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Firestore client:\n%v", err)
+	}
+	defer client.Close()
+
+	q := client.Collection(collection).Query
+	for _, where := range query.Where {
+		q = q.Where(where.Field, where.Op, where.Value)
+	}
+	if query.OrderBy != "" {
+		direction := firestore.Asc
+		if query.Descending {
+			direction = firestore.Desc
+		}
+		q = q.OrderBy(query.OrderBy, direction)
+	}
+	if query.Limit > 0 {
+		q = q.Limit(query.Limit)
+	}
+
+	documents := []Document{}
+	iter := q.Documents(ctx)
+	defer iter.Stop()
+	for {
+		snapshot, err := iter.Next()
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("error querying documents from Firestore database:\n%v", err)
+		}
+		documents = append(documents, Document{ID: snapshot.Ref.ID, decode: snapshot.DataTo})
+	}
+
+	return documents, nil
+}
@@ -0,0 +1,292 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// localMu serialises access to the local store, which may be shared by several goroutines.
+var localMu sync.RWMutex
+
+// localStore stores each document as a json file beneath a root directory.
+// Collections are directories, and documents are files named after their id.
+type localStore struct {
+	root string // The directory documents are stored in.
+}
+
+// file returns the file path of a document.
+func (s *localStore) file(path string) (string, error) {
+	// Reject paths that would escape the root directory.
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", fmt.Errorf("invalid document path %q", path)
+		}
+	}
+	return filepath.Join(s.root, filepath.FromSlash(path)) + ".json", nil
+}
+
+// Get reads a document into dst, returning ErrNotFound if it does not exist.
+func (s *localStore) Get(ctx context.Context, path string, dst any) error {
+	localMu.RLock()
+	defer localMu.RUnlock()
+
+	file, err := s.file(path)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	} else if err != nil {
+		return fmt.Errorf("error reading document from local storage:\n%v", err)
+	}
+
+	return json.Unmarshal(content, dst)
+}
+
+// Set creates or overwrites a document.
+func (s *localStore) Set(ctx context.Context, path string, src any) error {
+	localMu.Lock()
+	defer localMu.Unlock()
+
+	return s.write(path, src)
+}
+
+// Create creates a document, failing if it already exists.
+func (s *localStore) Create(ctx context.Context, path string, src any) error {
+	localMu.Lock()
+	defer localMu.Unlock()
+
+	file, err := s.file(path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(file); err == nil {
+		return fmt.Errorf("error creating document in local storage: %v already exists", path)
+	}
+
+	return s.write(path, src)
+}
+
+// Delete removes a document, succeeding if it does not exist.
+func (s *localStore) Delete(ctx context.Context, path string) error {
+	localMu.Lock()
+	defer localMu.Unlock()
+
+	file, err := s.file(path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error deleting document from local storage:\n%v", err)
+	}
+
+	return nil
+}
+
+// List returns every document in a collection, ordered by id.
+func (s *localStore) List(ctx context.Context, collection string) ([]Document, error) {
+	localMu.RLock()
+	defer localMu.RUnlock()
+
+	dir, err := s.file(collection)
+	if err != nil {
+		return nil, err
+	}
+	dir = strings.TrimSuffix(dir, ".json")
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Document{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error listing documents from local storage:\n%v", err)
+	}
+
+	documents := []Document{}
+	for _, entry := range entries {
+		id, found := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !found {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("error reading document from local storage:\n%v", err)
+		}
+		documents = append(documents, Document{
+			ID:     id,
+			decode: func(dst any) error { return json.Unmarshal(content, dst) },
+		})
+	}
+	sort.Slice(documents, func(i, j int) bool { return documents[i].ID < documents[j].ID })
+
+	return documents, nil
+}
+
+// Query returns the documents in a collection matching every condition of a query, in its order and up to its limit.
+// Documents are stored as json, so their fields are matched to the Go names of the query regardless of case.
+func (s *localStore) Query(ctx context.Context, collection string, query Query) ([]Document, error) {
+	documents, err := s.List(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := []Document{}
+	fields := map[string]map[string]any{}
+	for _, document := range documents {
+		var data map[string]any
+		if err := document.DataTo(&data); err != nil {
+			return nil, fmt.Errorf("error converting document %v:\n%v", document.ID, err)
+		}
+		match := true
+		for _, where := range query.Where {
+			order, ok := compare(field(data, where.Field), where.Value)
+			switch {
+			case !ok:
+				match = false
+			case where.Op == "==":
+				match = match && order == 0
+			case where.Op == ">=":
+				match = match && order >= 0
+			case where.Op == "<=":
+				match = match && order <= 0
+			default:
+				return nil, fmt.Errorf("unsupported query operator %q", where.Op)
+			}
+		}
+		if match {
+			matched = append(matched, document)
+			fields[document.ID] = data
+		}
+	}
+
+	if query.OrderBy != "" {
+		// Documents are listed by id, so documents with equal values stay in order of their id.
+		sort.SliceStable(matched, func(i, j int) bool {
+			a, b := field(fields[matched[i].ID], query.OrderBy), field(fields[matched[j].ID], query.OrderBy)
+			order, _ := compare(a, b)
+			if query.Descending {
+				return order > 0
+			}
+			return order < 0
+		})
+	}
+	if query.Limit > 0 && len(matched) > query.Limit {
+		matched = matched[:query.Limit]
+	}
+
+	return matched, nil
+}
+
+// field returns the value of a field of a json document, named regardless of case.
+func field(data map[string]any, name string) any {
+	if value, ok := data[name]; ok {
+		return value
+	}
+	for key, value := range data {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return nil
+}
+
+// compare compares a value of a json document with a value of a query, returning -1, 0 or 1
+// as the document value is less than, equal to or greater than it. It returns false if they can not be compared.
+func compare(value any, other any) (int, bool) {
+	switch other := other.(type) {
+	case time.Time:
+		text, ok := value.(string)
+		if !ok {
+			return 0, false
+		}
+		t, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return 0, false
+		}
+		return t.Compare(other), true
+	case string:
+		text, ok := value.(string)
+		if !ok {
+			return 0, false
+		}
+		// Times of another document are compared as times, so differing precisions order correctly.
+		if t, err := time.Parse(time.RFC3339Nano, text); err == nil {
+			if u, err := time.Parse(time.RFC3339Nano, other); err == nil {
+				return t.Compare(u), true
+			}
+		}
+		return strings.Compare(text, other), true
+	case bool:
+		// Bools are only equal or not, so unequal values compare as greater.
+		b, ok := value.(bool)
+		if !ok {
+			return 0, false
+		}
+		if b != other {
+			return 1, true
+		}
+		return 0, true
+	}
+
+	number, ok := value.(float64)
+	if !ok {
+		return 0, false
+	}
+	var o float64
+	switch other := other.(type) {
+	case int:
+		o = float64(other)
+	case int64:
+		o = float64(other)
+	case float64:
+		o = other
+	default:
+		return 0, false
+	}
+	switch {
+	case number < o:
+		return -1, true
+	case number > o:
+		return 1, true
+	}
+	return 0, true
+}
+
+// write replaces a document atomically, the caller must hold localMu.
+func (s *localStore) write(path string, src any) error {
+	file, err := s.file(path)
+	if err != nil {
+		return err
+	}
+
+	as_json, err := json.MarshalIndent(src, "", "\t")
+	if err != nil {
+		return fmt.Errorf("error converting document to json:\n%v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return fmt.Errorf("error creating collection in local storage:\n%v", err)
+	}
+
+	// Write beside the document and rename, so readers never see a partial document.
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, as_json, 0600); err != nil {
+		return fmt.Errorf("error writing document to local storage:\n%v", err)
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error writing document to local storage:\n%v", err)
+	}
+
+	return nil
+}
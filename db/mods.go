@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/RicochetStudios/aurora/types"
+)
+
+// GetMods reads the mod manifest of an instance, which is empty if no mods have been added.
+func GetMods(ctx context.Context, id string) (types.ModManifest, error) {
+	path, err := documentPath(modsCollection, id)
+	if err != nil {
+		return types.ModManifest{}, fmt.Errorf("error getting mods document path:\n%v", err)
+	}
+
+	var manifest types.ModManifest
+	if err := currentStore().Get(ctx, path, &manifest); errors.Is(err, ErrNotFound) {
+		return types.ModManifest{InstanceID: id, Mods: []types.Mod{}}, nil
+	} else if err != nil {
+		return types.ModManifest{}, fmt.Errorf("error reading mods document:\n%w", err)
+	}
+
+	return manifest, nil
+}
+
+// SetMods creates or overwrites the mod manifest of an instance.
+func SetMods(ctx context.Context, manifest types.ModManifest) (types.ModManifest, error) {
+	path, err := documentPath(modsCollection, manifest.InstanceID)
+	if err != nil {
+		return types.ModManifest{}, fmt.Errorf("error getting mods document path:\n%v", err)
+	}
+
+	if err := currentStore().Set(ctx, path, manifest); err != nil {
+		return types.ModManifest{}, fmt.Errorf("error writing mods document:\n%v", err)
+	}
+
+	return manifest, nil
+}
+
+// RemoveMods removes the mod manifest of an instance, succeeding if it has none.
+func RemoveMods(ctx context.Context, id string) error {
+	path, err := documentPath(modsCollection, id)
+	if err != nil {
+		return fmt.Errorf("error getting mods document path:\n%v", err)
+	}
+
+	if err := currentStore().Delete(ctx, path); err != nil {
+		return fmt.Errorf("error deleting mods document:\n%v", err)
+	}
+
+	return nil
+}
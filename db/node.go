@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RicochetStudios/aurora/types"
+)
+
+// GetNode reads and returns a node document, given an ID.
+func GetNode(ctx context.Context, id string) (types.Node, error) {
+	path, err := documentPath(nodesCollection, id)
+	if err != nil {
+		return types.Node{}, fmt.Errorf("error getting node document path:\n%v", err)
+	}
+
+	var node types.Node
+	if err := currentStore().Get(ctx, path, &node); err != nil {
+		return types.Node{}, fmt.Errorf("error reading node document:\n%w", err)
+	}
+
+	return node, nil
+}
+
+// ListNodes reads and returns every node registered to the cluster.
+func ListNodes(ctx context.Context) ([]types.Node, error) {
+	nodes, err := listAs[types.Node](ctx, nodesCollection)
+	if err != nil {
+		return nil, fmt.Errorf("error listing node documents:\n%v", err)
+	}
+
+	return nodes, nil
+}
+
+// SetNode creates or overwrites a node document.
+func SetNode(ctx context.Context, node types.Node) (types.Node, error) {
+	path, err := documentPath(nodesCollection, node.ID)
+	if err != nil {
+		return types.Node{}, fmt.Errorf("error getting node document path:\n%v", err)
+	}
+
+	if err := currentStore().Set(ctx, path, node); err != nil {
+		return types.Node{}, fmt.Errorf("error writing node document:\n%v", err)
+	}
+
+	return node, nil
+}
@@ -0,0 +1,158 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/RicochetStudios/aurora/config"
+)
+
+const (
+	// defaultCluster is the cluster records are stored beneath until a cluster id is set up.
+	defaultCluster string = "default"
+
+	// instancesCollection holds a document for each server instance.
+	instancesCollection string = "instances"
+
+	// auditCollection holds a document for each audit event.
+	auditCollection string = "audit"
+
+	// nodesCollection holds a document for each node registered to the cluster.
+	nodesCollection string = "nodes"
+
+	// modsCollection holds the mod manifest of each instance, by instance id.
+	modsCollection string = "mods"
+)
+
+// ErrNotFound is returned when a document does not exist.
+var ErrNotFound = errors.New("document not found")
+
+// Store is a document database holding Aurora's records.
+// Paths are slash separated, alternating collections and documents,
+// so a document path has an even number of segments and a collection path an odd number.
+type Store interface {
+	// Get reads a document into dst, returning ErrNotFound if it does not exist.
+	Get(ctx context.Context, path string, dst any) error
+
+	// Set creates or overwrites a document.
+	Set(ctx context.Context, path string, src any) error
+
+	// Create creates a document, failing if it already exists.
+	Create(ctx context.Context, path string, src any) error
+
+	// Delete removes a document, succeeding if it does not exist.
+	Delete(ctx context.Context, path string) error
+
+	// List returns every document in a collection.
+	List(ctx context.Context, collection string) ([]Document, error)
+
+	// Query returns the documents in a collection matching every condition of a query, in its order and up to its limit.
+	Query(ctx context.Context, collection string, query Query) ([]Document, error)
+}
+
+// Query narrows down, orders and limits the documents read from a collection.
+// Fields are named as they are in Go, such as Timestamp.
+type Query struct {
+	Where      []Where // Conditions every document must match.
+	OrderBy    string  // The field to order documents by, or empty for the order of the store.
+	Descending bool    // Whether documents are ordered from the largest value down.
+	Limit      int     // The maximum number of documents to return, 0 is unlimited.
+}
+
+// Where is a condition on a field of a document.
+type Where struct {
+	Field string // The field to compare e.g. Actor.
+	Op    string // How the field is compared with the value, one of "==", ">=" or "<=".
+	Value any    // The value the field is compared with, a string, number, bool or time.Time.
+}
+
+// Document is a single document read from a collection.
+type Document struct {
+	ID     string              // The last segment of the document path.
+	decode func(dst any) error // Converts the document data into a struct.
+}
+
+// DataTo converts the document data into dst.
+func (d Document) DataTo(dst any) error {
+	return d.decode(dst)
+}
+
+// currentStore returns the store selected by the current settings.
+func currentStore() Store {
+	settings := config.Current()
+	if settings.Storage == config.StorageLocal {
+		return &localStore{root: settings.StoragePath}
+	}
+	return firestoreStore{}
+}
+
+// collectionPath returns the path of a collection of records.
+// Records are namespaced by the environment and then by the cluster from the config,
+// so several clusters can share a database without seeing each other's records.
+func collectionPath(collection string) (string, error) {
+	cfg, err := config.Read()
+	if err != nil {
+		return "", fmt.Errorf("error reading config:\n%v", err)
+	}
+
+	cluster := cfg.ClusterID
+	if len(cluster) == 0 {
+		cluster = defaultCluster
+	}
+
+	return config.Current().Environment + "/" + cluster + "/" + collection, nil
+}
+
+// documentPath returns the path of a single record in a collection.
+func documentPath(collection string, id string) (string, error) {
+	path, err := collectionPath(collection)
+	if err != nil {
+		return "", err
+	}
+	return path + "/" + id, nil
+}
+
+// listAs reads every document in a collection of records into a slice of T.
+func listAs[T any](ctx context.Context, collection string) ([]T, error) {
+	path, err := collectionPath(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := currentStore().List(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeAs[T](documents)
+}
+
+// queryAs reads the documents in a collection of records matching a query into a slice of T.
+func queryAs[T any](ctx context.Context, collection string, query Query) ([]T, error) {
+	path, err := collectionPath(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := currentStore().Query(ctx, path, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeAs[T](documents)
+}
+
+// decodeAs converts documents into a slice of T.
+func decodeAs[T any](documents []Document) ([]T, error) {
+	items := []T{}
+	for _, document := range documents {
+		var item T
+		if err := document.DataTo(&item); err != nil {
+			return nil, fmt.Errorf("error converting document %v:\n%v", document.ID, err)
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
@@ -4,22 +4,42 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
-	"os"
 	"regexp"
+	"strings"
 
+	"github.com/RicochetStudios/aurora/jobs"
+	"github.com/RicochetStudios/aurora/registry"
 	"github.com/RicochetStudios/aurora/schema"
 	"github.com/RicochetStudios/aurora/types"
 
 	dockerTypes "github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
 	"github.com/docker/go-connections/nat"
 )
 
-// templateRegex is a regular expression to validate templates.
-const templateRegex string = `^{{ (?P<tpl>(\.\w+)*) }}$`
+const (
+	// namePrefix is prepended to the names of containers and volumes created for instances.
+	namePrefix string = "aurora-"
+
+	// InstanceLabel is the label identifying the instance a container or volume belongs to.
+	InstanceLabel string = "aurora.instance"
+
+	// RevisionLabel is the label recording the revision of the configuration a container was created with.
+	RevisionLabel string = "aurora.revision"
+)
+
+// ContainerName returns the name of the container for an instance.
+func ContainerName(id string) string {
+	return namePrefix + id
+}
+
+// VolumeName returns the name of a schema volume for an instance.
+func VolumeName(id string, volume string) string {
+	return namePrefix + id + "-" + volume
+}
 
 // NewContainerEnvVar creates a new instance of ContainerEnvVar given a name and value.
 func NewContainerEnvVar(name, value string) (string, error) {
@@ -42,34 +62,13 @@ type ContainerConfig struct {
 	ExposedPorts nat.PortSet
 	Binds        []string
 	Env          []string
+	Labels       map[string]string
 }
 
-// templateValue takes a value and resolves its template if it is a template.
-func templateValue(v string, g schema.Schema, s types.Server) string {
-	// Template the env var if needed.
-	re := regexp.MustCompile(templateRegex)
-	if re.MatchString(v) {
-		// Get the template to target.
-		matches := re.FindStringSubmatch(v)
-		tplIndex := re.SubexpIndex("tpl")
-		tpl := matches[tplIndex]
-
-		// Resolve the templates.
-		switch tpl {
-		case ".name":
-			return s.Name
-		case ".modloader":
-			return s.Game.Modloader
-		case ".players":
-			return fmt.Sprint(g.Sizes[s.Size].Players)
-		}
-	}
-	// If it is not a template, return the original value.
-	return v
-}
-
-// NewContainerConfig creates a new ContainerConfig from a name, game schema and a server.
-func NewContainerConfig(name string, gameSchema schema.Schema, server types.Server) (ContainerConfig, error) {
+// NewContainerConfig creates a new ContainerConfig from an instance id, game schema and a server.
+// The container and its volumes are named and labelled after the instance,
+// so several instances can run side by side.
+func NewContainerConfig(id string, gameSchema schema.Schema, server types.Server) (ContainerConfig, error) {
 	// Create container environment ports.
 	var portSet nat.PortSet = nat.PortSet{}
 	for _, network := range gameSchema.Network {
@@ -83,25 +82,19 @@ func NewContainerConfig(name string, gameSchema schema.Schema, server types.Serv
 		portSet[port] = struct{}{}
 	}
 
-	// Create container bindings.
+	// Create container bindings to a named volume for each schema volume.
 	var bindList []string = []string{}
 	for _, volume := range gameSchema.Volumes {
-		bindList = append(bindList, (volume.Path + ":" + volume.Path))
+		bindList = append(bindList, (VolumeName(id, volume.Name) + ":" + volume.Path))
 	}
 
 	// Create container environment variables.
 	var envList []string = []string{}
-	for _, setting := range gameSchema.Settings {
-		// Template environment variables if required.
-		var sList [2]string = [2]string{setting.Name, setting.Value}
-		for i, item := range sList {
-			sList[i] = templateValue(item, gameSchema, server)
-		}
-
+	for _, setting := range schema.Environment(gameSchema, server) {
 		// Construct env vars.
 		env, err := NewContainerEnvVar(
-			sList[0],
-			sList[1],
+			setting.Name,
+			setting.Value,
 		)
 		if err != nil {
 			return ContainerConfig{}, err
@@ -109,13 +102,19 @@ func NewContainerConfig(name string, gameSchema schema.Schema, server types.Serv
 		envList = append(envList, env)
 	}
 
+	revision, err := schema.Revision(gameSchema, server)
+	if err != nil {
+		return ContainerConfig{}, err
+	}
+
 	// Create container config.
 	return ContainerConfig{
-		Name:         name,
-		Image:        gameSchema.Image,
+		Name:         ContainerName(id),
+		Image:        schema.Image(gameSchema, server),
 		ExposedPorts: portSet,
 		Binds:        bindList,
 		Env:          envList,
+		Labels:       map[string]string{InstanceLabel: id, RevisionLabel: revision},
 	}, nil
 }
 
@@ -128,19 +127,41 @@ func RunServer(ctx context.Context, config ContainerConfig) (container.CreateRes
 	}
 	defer cli.Close()
 
-	// Pull the image.
-	out, err := cli.ImagePull(ctx, config.Image, dockerTypes.ImagePullOptions{})
-	if err != nil {
-		return container.CreateResponse{}, err
+	// Pull the image, unless it is pinned to a digest which has already been pulled.
+	pulled := false
+	if registry.IsPinned(config.Image) {
+		if pulled, err = imageExists(ctx, cli, config.Image); err != nil {
+			return container.CreateResponse{}, err
+		}
+	}
+	if !pulled {
+		if err := pullImage(ctx, cli, config.Image); err != nil {
+			return container.CreateResponse{}, err
+		}
+	}
+
+	// Create the named volumes, labelled so they can be found and removed with the container.
+	for _, bind := range config.Binds {
+		source := strings.SplitN(bind, ":", 2)[0]
+		if strings.HasPrefix(source, "/") {
+			// Host paths are not volumes.
+			continue
+		}
+		if _, err := cli.VolumeCreate(ctx, volume.CreateOptions{
+			Name:   source,
+			Labels: map[string]string{InstanceLabel: config.Labels[InstanceLabel]},
+		}); err != nil {
+			return container.CreateResponse{}, err
+		}
 	}
-	defer out.Close()
-	// Output the download status.
-	io.Copy(os.Stdout, out)
 
 	// Create the container.
+	jobs.Report(ctx, "creating", 0)
 	resp, err := cli.ContainerCreate(ctx, &container.Config{
 		Image:        config.Image,
 		ExposedPorts: config.ExposedPorts,
+		Env:          config.Env,
+		Labels:       config.Labels,
 	}, &container.HostConfig{
 		// Binds work the way that mounts would normally.
 		Binds: config.Binds,
@@ -152,6 +173,7 @@ func RunServer(ctx context.Context, config ContainerConfig) (container.CreateRes
 	}
 
 	// Start the container.
+	jobs.Report(ctx, "starting", 0)
 	if err := cli.ContainerStart(ctx, resp.ID, dockerTypes.ContainerStartOptions{}); err != nil {
 		return resp, err
 	}
@@ -159,8 +181,8 @@ func RunServer(ctx context.Context, config ContainerConfig) (container.CreateRes
 	return resp, nil
 }
 
-// RemoveServer stops and removes a server container.
-func RemoveServer(ctx context.Context) error {
+// RemoveServer stops and removes the container and volumes of an instance.
+func RemoveServer(ctx context.Context, id string) error {
 	// Constructs the client object.
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
@@ -168,16 +190,19 @@ func RemoveServer(ctx context.Context) error {
 	}
 	defer cli.Close()
 
-	// Get the latest container (the only one).
+	// Only touch resources labelled with this instance.
+	instanceFilter := filters.NewArgs(filters.Arg("label", InstanceLabel+"="+id))
+
+	// Get the containers of the instance.
 	containers, err := cli.ContainerList(ctx, dockerTypes.ContainerListOptions{
-		Latest: true,
-		All:    true,
+		All:     true,
+		Filters: instanceFilter,
 	})
 	if err != nil {
 		return err
 	}
 
-	// Stop and delete the container and volumes.
+	// Stop and delete the containers and anonymous volumes.
 	for _, cont := range containers {
 		if err := cli.ContainerRemove(ctx, cont.ID, dockerTypes.ContainerRemoveOptions{
 			RemoveVolumes: true,
@@ -188,14 +213,40 @@ func RemoveServer(ctx context.Context) error {
 		}
 	}
 
-	// Remove unused data.
-	if _, err := cli.ContainersPrune(ctx, filters.Args{}); err != nil {
+	// Delete the named volumes.
+	volumes, err := cli.VolumeList(ctx, volume.ListOptions{Filters: instanceFilter})
+	if err != nil {
 		return err
 	}
+	for _, vol := range volumes.Volumes {
+		if err := cli.VolumeRemove(ctx, vol.Name, true); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
+// HostResources returns the total CPU and memory of the host running the docker engine.
+func HostResources(ctx context.Context) (types.Resources, error) {
+	// Constructs the client object.
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return types.Resources{}, err
+	}
+	defer cli.Close()
+
+	info, err := cli.Info(ctx)
+	if err != nil {
+		return types.Resources{}, err
+	}
+
+	return types.Resources{
+		MilliCPU: int64(info.NCPU) * 1000,
+		Memory:   info.MemTotal,
+	}, nil
+}
+
 // // GetServer gets details about the currently configured game server instance.
 // func GetServer(ctx context.Context, containerID string) (ContainerConfig, error) {
 // 	// Constructs the client object.
@@ -11,6 +11,7 @@ import (
 	dockerTypes "github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
 	"github.com/docker/go-connections/nat"
 	"github.com/google/go-cmp/cmp"
@@ -62,6 +63,17 @@ func cleaupAllContainers(ctx context.Context, cli *client.Client) error {
 		return fmt.Errorf("cleaupAllContainers() error pruning containers: %v", err)
 	}
 
+	// Remove named volumes created for instances.
+	volumes, err := cli.VolumeList(ctx, volume.ListOptions{Filters: filters.NewArgs(filters.Arg("label", InstanceLabel))})
+	if err != nil {
+		return fmt.Errorf("cleaupAllContainers() error getting list of volumes: %v", err)
+	}
+	for _, vol := range volumes.Volumes {
+		if err := cli.VolumeRemove(ctx, vol.Name, true); err != nil {
+			return fmt.Errorf("cleaupAllContainers() error removing volume %v:\n%v", vol.Name, err)
+		}
+	}
+
 	return nil
 }
 
@@ -85,11 +97,12 @@ func TestRunServer(t *testing.T) {
 
 	// Run a test container.
 	got, err := RunServer(ctx, ContainerConfig{
-		"my-unique-id",
-		"nginx",
-		nat.PortSet{"8080/tcp": struct{}{}},
-		[]string{"/data:/data"},
-		[]string{"name=value"},
+		Name:         ContainerName("my-unique-id"),
+		Image:        "nginx",
+		ExposedPorts: nat.PortSet{"8080/tcp": struct{}{}},
+		Binds:        []string{VolumeName("my-unique-id", "data") + ":/data"},
+		Env:          []string{"name=value"},
+		Labels:       map[string]string{InstanceLabel: "my-unique-id"},
 	})
 
 	if err != nil {
@@ -124,17 +137,18 @@ func TestRemoveServer(t *testing.T) {
 
 	// Run a test container.
 	if _, err := RunServer(ctx, ContainerConfig{
-		"my-unique-id",
-		"nginx",
-		nat.PortSet{"8080/tcp": struct{}{}},
-		[]string{"/data:/data"},
-		[]string{"name=value"},
+		Name:         ContainerName("my-unique-id"),
+		Image:        "nginx",
+		ExposedPorts: nat.PortSet{"8080/tcp": struct{}{}},
+		Binds:        []string{VolumeName("my-unique-id", "data") + ":/data"},
+		Env:          []string{"name=value"},
+		Labels:       map[string]string{InstanceLabel: "my-unique-id"},
 	}); err != nil {
 		t.Fatalf("RunServer() returned an error: \n%v", err)
 	}
 
 	// Stop the container.
-	if err := RemoveServer(ctx); err != nil {
+	if err := RemoveServer(ctx, "my-unique-id"); err != nil {
 		t.Fatalf("RemoveServer() returned an error: \n%v", err)
 	}
 }
@@ -223,16 +237,17 @@ func TestNewContainerConfig(t *testing.T) {
 	}
 
 	var want ContainerConfig = ContainerConfig{
-		"my-unique-id",
-		"itzg/minecraft-server:latest",
-		nat.PortSet{"25565/tcp": struct{}{}},
-		[]string{"/data:/data"},
-		[]string{
+		Name:         "aurora-my-unique-id",
+		Image:        "itzg/minecraft-server:latest",
+		ExposedPorts: nat.PortSet{"25565/tcp": struct{}{}},
+		Binds:        []string{"aurora-my-unique-id-data:/data"},
+		Env: []string{
 			"EULA=TRUE",
 			"TYPE=vanilla",
 			"MAX_PLAYERS=8",
 			"MOTD=mytest",
 		},
+		Labels: map[string]string{"aurora.instance": "my-unique-id"},
 	}
 
 	got, err := NewContainerConfig("my-unique-id", schema, server)
@@ -240,8 +255,36 @@ func TestNewContainerConfig(t *testing.T) {
 	if err != nil {
 		t.Fatalf("NewContainerConfigFromSchema() returned an error: \n%v", err)
 	}
+	// The revision itself is tested by the schema package.
+	if len(got.Labels[RevisionLabel]) == 0 {
+		t.Fatalf("NewContainerConfigFromSchema() did not label the revision")
+	}
+	delete(got.Labels, RevisionLabel)
 	// Use cmp for more complex types.
 	if diff := cmp.Diff(want, got); diff != "" {
 		t.Fatalf("NewContainerConfigFromSchema() mismatch (-want +got):\n%s", diff)
 	}
 }
+
+// TestStatsFrom calls StatsFrom with two samples from the docker engine,
+// checking the CPU and memory usage in return.
+func TestStatsFrom(t *testing.T) {
+	var stats dockerTypes.StatsJSON
+	stats.PreCPUStats.CPUUsage.TotalUsage = 1_000
+	stats.PreCPUStats.SystemUsage = 10_000
+	stats.CPUStats.CPUUsage.TotalUsage = 2_000
+	stats.CPUStats.SystemUsage = 20_000
+	stats.CPUStats.OnlineCPUs = 4
+	stats.MemoryStats.Usage = 3_000
+	stats.MemoryStats.Limit = 8_000
+	stats.MemoryStats.Stats = map[string]uint64{"inactive_file": 1_000}
+
+	// A tenth of the system time across 4 CPUs is 400 millicores.
+	var want types.Stats = types.Stats{MilliCPU: 400, Memory: 2_000, MemoryLimit: 8_000}
+
+	got := StatsFrom(stats)
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("StatsFrom() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,70 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RicochetStudios/aurora/registry"
+
+	"github.com/docker/distribution/reference"
+	dockerTypes "github.com/docker/docker/api/types"
+	registryTypes "github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/client"
+)
+
+// pullImage pulls an image, with the configured registry credentials if they are for its registry,
+// reporting its progress to the job of the context.
+func pullImage(ctx context.Context, cli *client.Client, image string) error {
+	options := dockerTypes.ImagePullOptions{}
+	if credentials, ok := registry.Lookup(image); ok {
+		auth, err := registryTypes.EncodeAuthConfig(registryTypes.AuthConfig{
+			Username:      credentials.Username,
+			Password:      credentials.Password,
+			ServerAddress: credentials.Server,
+		})
+		if err != nil {
+			return fmt.Errorf("pullImage() error encoding registry credentials: \n%v", err)
+		}
+		options.RegistryAuth = auth
+	}
+
+	out, err := cli.ImagePull(ctx, image, options)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	return reportPull(ctx, out)
+}
+
+// imageExists returns whether an image has already been pulled.
+func imageExists(ctx context.Context, cli *client.Client, image string) (bool, error) {
+	_, _, err := cli.ImageInspectWithRaw(ctx, image)
+	if client.IsErrNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// pinnedFrom returns an image pinned to the digest its registry gave it,
+// chosen from the repository digests of the pulled image.
+func pinnedFrom(image string, repoDigests []string) (string, bool) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", false
+	}
+
+	// An image pushed to several repositories has a digest for each of them.
+	for _, repoDigest := range repoDigests {
+		pinned, err := reference.ParseNormalizedNamed(repoDigest)
+		if err != nil {
+			continue
+		}
+		if _, ok := pinned.(reference.Digested); ok && pinned.Name() == named.Name() {
+			return reference.FamiliarString(pinned), true
+		}
+	}
+	return "", false
+}
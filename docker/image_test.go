@@ -0,0 +1,21 @@
+package docker
+
+import "testing"
+
+// TestPinnedFrom calls pinnedFrom with the repository digests of an image pushed to two repositories,
+// checking the digest of the repository the image was pulled from is chosen.
+func TestPinnedFrom(t *testing.T) {
+	repoDigests := []string{
+		"ghcr.io/itzg/minecraft-server@sha256:1111111111111111111111111111111111111111111111111111111111111111",
+		"itzg/minecraft-server@sha256:2222222222222222222222222222222222222222222222222222222222222222",
+	}
+
+	got, ok := pinnedFrom("itzg/minecraft-server:latest", repoDigests)
+	if want := repoDigests[1]; !ok || got != want {
+		t.Fatalf("pinnedFrom() = %v, %v, want %v", got, ok, want)
+	}
+
+	if _, ok := pinnedFrom("itzg/bedrock-server:latest", repoDigests); ok {
+		t.Fatalf("pinnedFrom() found a digest for an image of another repository")
+	}
+}
@@ -0,0 +1,74 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/RicochetStudios/aurora/jobs"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// pullProgress tracks the download of each layer of an image being pulled.
+type pullProgress struct {
+	layers map[string]float64 // Fraction of each layer downloaded, by layer id.
+}
+
+// newPullProgress creates a pullProgress with no known layers.
+func newPullProgress() *pullProgress {
+	return &pullProgress{layers: map[string]float64{}}
+}
+
+// Update records a message from the pull output, returning the percentage of the image pulled.
+func (p *pullProgress) Update(message jsonmessage.JSONMessage) int {
+	if message.ID != "" {
+		switch message.Status {
+		case "Pulling fs layer", "Waiting":
+			p.layers[message.ID] = 0
+		case "Downloading":
+			if message.Progress != nil && message.Progress.Total > 0 {
+				p.layers[message.ID] = float64(message.Progress.Current) / float64(message.Progress.Total)
+			}
+		case "Download complete", "Verifying Checksum", "Extracting", "Pull complete", "Already exists":
+			p.layers[message.ID] = 1
+		}
+	}
+
+	return p.Percent()
+}
+
+// Percent returns the percentage of the image pulled, across every known layer.
+func (p *pullProgress) Percent() int {
+	if len(p.layers) == 0 {
+		return 0
+	}
+
+	var total float64
+	for _, fraction := range p.layers {
+		total += fraction
+	}
+	return int(total / float64(len(p.layers)) * 100)
+}
+
+// reportPull reads the output of an image pull, reporting its progress to the job of the context.
+// An error is returned if the pull failed part way through.
+func reportPull(ctx context.Context, out io.Reader) error {
+	progress := newPullProgress()
+	decoder := json.NewDecoder(out)
+	for {
+		var message jsonmessage.JSONMessage
+		if err := decoder.Decode(&message); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("reportPull() error reading pull output: \n%v", err)
+		}
+
+		if message.Error != nil {
+			return message.Error
+		}
+		jobs.Report(ctx, "pulling", progress.Update(message))
+	}
+}
@@ -0,0 +1,44 @@
+package docker
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// TestPullProgress calls Update with the output of pulling two layers,
+// checking the percentage of the image pulled after each message.
+func TestPullProgress(t *testing.T) {
+	progress := newPullProgress()
+
+	tests := []struct {
+		message jsonmessage.JSONMessage
+		want    int
+	}{
+		{jsonmessage.JSONMessage{Status: "Pulling from itzg/minecraft-server", ID: "latest"}, 0},
+		{jsonmessage.JSONMessage{Status: "Pulling fs layer", ID: "a"}, 0},
+		{jsonmessage.JSONMessage{Status: "Already exists", ID: "b"}, 50},
+		{jsonmessage.JSONMessage{Status: "Downloading", ID: "a", Progress: &jsonmessage.JSONProgress{Current: 43, Total: 100}}, 71},
+		{jsonmessage.JSONMessage{Status: "Pull complete", ID: "a"}, 100},
+	}
+
+	for _, test := range tests {
+		if got := progress.Update(test.message); got != test.want {
+			t.Fatalf("Update(%v) = %v, want %v", test.message.Status, got, test.want)
+		}
+	}
+}
+
+// TestReportPullError calls reportPull with output ending in an error,
+// checking the error is returned.
+func TestReportPullError(t *testing.T) {
+	out := `{"status":"Pulling fs layer","id":"a"}
+{"errorDetail":{"message":"manifest unknown"},"error":"manifest unknown"}
+`
+
+	if err := reportPull(context.Background(), strings.NewReader(out)); err == nil {
+		t.Fatalf("reportPull() expected a pull error, got %v", err)
+	}
+}
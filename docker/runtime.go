@@ -0,0 +1,402 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/RicochetStudios/aurora/registry"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+
+	dockerTypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// Runtime runs server instances as containers on the local docker engine.
+type Runtime struct{}
+
+// newClient constructs a client for the docker engine given by the environment.
+func newClient() (*client.Client, error) {
+	return client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+}
+
+// Deploy creates the container and volumes of an instance and starts it.
+func (Runtime) Deploy(ctx context.Context, id string, gameSchema schema.Schema, server types.Server) error {
+	containerConfig, err := NewContainerConfig(id, gameSchema, server)
+	if err != nil {
+		return fmt.Errorf("Deploy() error creating container config: \n%v", err)
+	}
+
+	if _, err := RunServer(ctx, containerConfig); err != nil {
+		return fmt.Errorf("Deploy() error running container: \n%v", err)
+	}
+	return nil
+}
+
+// Update recreates the container of an instance with a new configuration, keeping its volumes.
+func (Runtime) Update(ctx context.Context, id string, gameSchema schema.Schema, server types.Server) error {
+	containerConfig, err := NewContainerConfig(id, gameSchema, server)
+	if err != nil {
+		return fmt.Errorf("Update() error creating container config: \n%v", err)
+	}
+
+	cli, err := newClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	// Remove the old container, the named volumes are reused by the new one.
+	containers, err := cli.ContainerList(ctx, dockerTypes.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", InstanceLabel+"="+id)),
+	})
+	if err != nil {
+		return fmt.Errorf("Update() error listing containers: \n%v", err)
+	}
+	for _, cont := range containers {
+		if err := cli.ContainerRemove(ctx, cont.ID, dockerTypes.ContainerRemoveOptions{Force: true}); err != nil {
+			return fmt.Errorf("Update() error removing container %v: \n%v", cont.ID, err)
+		}
+	}
+
+	if _, err := RunServer(ctx, containerConfig); err != nil {
+		return fmt.Errorf("Update() error running container: \n%v", err)
+	}
+	return nil
+}
+
+// Resolve pulls an image, returning it pinned to the digest its registry gave it.
+func (Runtime) Resolve(ctx context.Context, image string) (string, error) {
+	if registry.IsPinned(image) {
+		return image, nil
+	}
+
+	cli, err := newClient()
+	if err != nil {
+		return "", err
+	}
+	defer cli.Close()
+
+	if err := pullImage(ctx, cli, image); err != nil {
+		return "", fmt.Errorf("Resolve() error pulling image: \n%v", err)
+	}
+	inspect, _, err := cli.ImageInspectWithRaw(ctx, image)
+	if err != nil {
+		return "", fmt.Errorf("Resolve() error inspecting image: \n%v", err)
+	}
+
+	pinned, ok := pinnedFrom(image, inspect.RepoDigests)
+	if !ok {
+		return "", fmt.Errorf("Resolve() image %v has no digest from its registry", image)
+	}
+	return pinned, nil
+}
+
+// Start starts the stopped container of an instance.
+func (Runtime) Start(ctx context.Context, id string) error {
+	cli, err := newClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	return cli.ContainerStart(ctx, ContainerName(id), dockerTypes.ContainerStartOptions{})
+}
+
+// Stop stops the container of an instance, without removing it.
+func (Runtime) Stop(ctx context.Context, id string) error {
+	cli, err := newClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	return cli.ContainerStop(ctx, ContainerName(id), container.StopOptions{})
+}
+
+// Remove stops and removes the container and volumes of an instance.
+func (Runtime) Remove(ctx context.Context, id string) error {
+	return RemoveServer(ctx, id)
+}
+
+// Status returns the state of the container of an instance.
+func (Runtime) Status(ctx context.Context, id string) (types.RuntimeStatus, error) {
+	cli, err := newClient()
+	if err != nil {
+		return types.RuntimeStatus{}, err
+	}
+	defer cli.Close()
+
+	inspect, err := cli.ContainerInspect(ctx, ContainerName(id))
+	if client.IsErrNotFound(err) {
+		return types.RuntimeStatus{State: types.StateMissing}, nil
+	} else if err != nil {
+		return types.RuntimeStatus{}, err
+	}
+
+	state := inspect.State
+	status := types.RuntimeStatus{State: types.StateStopped, Message: state.Status}
+	if inspect.Config != nil {
+		status.Revision = inspect.Config.Labels[RevisionLabel]
+	}
+	status.StartedAt, _ = time.Parse(time.RFC3339Nano, state.StartedAt)
+	switch {
+	case state.Running && state.Health != nil && state.Health.Status == dockerTypes.Starting:
+		status.State = types.StateStarting
+	case state.Running:
+		status.State = types.StateRunning
+	case state.Error != "":
+		status.Message = state.Error
+	case state.ExitCode != 0:
+		status.Message = fmt.Sprintf("exited with code %v", state.ExitCode)
+	}
+	return status, nil
+}
+
+// Logs returns the last lines of output of the container of an instance, or all of it if tail is not positive.
+func (Runtime) Logs(ctx context.Context, id string, tail int) (io.ReadCloser, error) {
+	cli, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	inspect, err := cli.ContainerInspect(ctx, ContainerName(id))
+	if err != nil {
+		return nil, err
+	}
+
+	lines := "all"
+	if tail > 0 {
+		lines = strconv.Itoa(tail)
+	}
+	out, err := cli.ContainerLogs(ctx, ContainerName(id), dockerTypes.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       lines,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Containers without a terminal multiplex stdout and stderr, which needs separating.
+	if inspect.Config != nil && inspect.Config.Tty {
+		return out, nil
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(writer, writer, out)
+		out.Close()
+		writer.CloseWithError(err)
+	}()
+	return reader, nil
+}
+
+// Exec runs a command inside the container of an instance and waits for it to finish.
+func (Runtime) Exec(ctx context.Context, id string, command []string) (types.ExecResult, error) {
+	cli, err := newClient()
+	if err != nil {
+		return types.ExecResult{}, err
+	}
+	defer cli.Close()
+
+	exec, err := cli.ContainerExecCreate(ctx, ContainerName(id), dockerTypes.ExecConfig{
+		Cmd:          command,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return types.ExecResult{}, fmt.Errorf("Exec() error creating exec: \n%v", err)
+	}
+
+	attach, err := cli.ContainerExecAttach(ctx, exec.ID, dockerTypes.ExecStartCheck{})
+	if err != nil {
+		return types.ExecResult{}, fmt.Errorf("Exec() error attaching to exec: \n%v", err)
+	}
+	defer attach.Close()
+
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, attach.Reader); err != nil {
+		return types.ExecResult{}, fmt.Errorf("Exec() error reading output: \n%v", err)
+	}
+
+	inspect, err := cli.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return types.ExecResult{}, fmt.Errorf("Exec() error inspecting exec: \n%v", err)
+	}
+
+	return types.ExecResult{ExitCode: inspect.ExitCode, Output: output.String()}, nil
+}
+
+// CopyTo extracts a tar archive into a directory of the container of an instance,
+// which works whether or not the container is running.
+func (Runtime) CopyTo(ctx context.Context, id string, dir string, archive io.Reader) error {
+	cli, err := newClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	return cli.CopyToContainer(ctx, ContainerName(id), dir, archive, dockerTypes.CopyToContainerOptions{})
+}
+
+// CopyFrom returns a tar archive of a file or directory of the container of an instance,
+// which works whether or not the container is running.
+func (Runtime) CopyFrom(ctx context.Context, id string, path string) (io.ReadCloser, error) {
+	cli, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	archive, _, err := cli.CopyFromContainer(ctx, ContainerName(id), path)
+	if err != nil {
+		cli.Close()
+		return nil, err
+	}
+	return &closeBoth{ReadCloser: archive, client: cli}, nil
+}
+
+// Replace swaps a directory of the stopped container of an instance for the contents of a tar archive.
+// The archive is extracted beside the directory, then moved over it by a helper container sharing its volumes,
+// as a stopped container can not run commands itself.
+func (Runtime) Replace(ctx context.Context, id string, dir string, archive io.Reader) error {
+	cli, err := newClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	inspect, err := cli.ContainerInspect(ctx, ContainerName(id))
+	if err != nil {
+		return err
+	}
+	if inspect.State != nil && inspect.State.Running {
+		return fmt.Errorf("Replace() container %v must be stopped", ContainerName(id))
+	}
+
+	upload := dir + ".upload"
+	if err := runHelper(ctx, cli, id, inspect.Config.Image, `rm -rf "$1" && mkdir -p "$1"`, upload); err != nil {
+		return fmt.Errorf("Replace() error preparing %v: \n%v", upload, err)
+	}
+	if err := cli.CopyToContainer(ctx, ContainerName(id), upload, archive, dockerTypes.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("Replace() error extracting archive: \n%v", err)
+	}
+	if err := runHelper(ctx, cli, id, inspect.Config.Image, `rm -rf "$2" && mv "$1" "$2"`, upload, dir); err != nil {
+		return fmt.Errorf("Replace() error replacing %v: \n%v", dir, err)
+	}
+	return nil
+}
+
+// runHelper runs a shell script with arguments in a short lived container of the image of an instance,
+// sharing the volumes of its container, and removes it once it exits.
+// Helpers are labelled with the instance, so any left behind are removed with it.
+func runHelper(ctx context.Context, cli *client.Client, id string, image string, script string, args ...string) error {
+	created, err := cli.ContainerCreate(ctx, &container.Config{
+		Image:      image,
+		User:       "root",
+		Entrypoint: []string{"sh", "-c", script, "sh"},
+		Cmd:        args,
+		Labels:     map[string]string{InstanceLabel: id},
+	}, &container.HostConfig{
+		VolumesFrom: []string{ContainerName(id)},
+	}, nil, nil, "")
+	if err != nil {
+		return err
+	}
+	defer cli.ContainerRemove(context.Background(), created.ID, dockerTypes.ContainerRemoveOptions{Force: true})
+
+	// Wait from before the start, so a quick exit is not missed.
+	statusCh, errCh := cli.ContainerWait(ctx, created.ID, container.WaitConditionNextExit)
+	if err := cli.ContainerStart(ctx, created.ID, dockerTypes.ContainerStartOptions{}); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-errCh:
+		return err
+	case status := <-statusCh:
+		if status.StatusCode == 0 {
+			return nil
+		}
+		var output bytes.Buffer
+		if out, err := cli.ContainerLogs(ctx, created.ID, dockerTypes.ContainerLogsOptions{ShowStdout: true, ShowStderr: true}); err == nil {
+			stdcopy.StdCopy(&output, &output, out)
+			out.Close()
+		}
+		return fmt.Errorf("helper exited with code %v: %v", status.StatusCode, output.String())
+	}
+}
+
+// closeBoth is a response body which closes its client once it is read.
+type closeBoth struct {
+	io.ReadCloser
+	client *client.Client
+}
+
+// Close closes the body, then the client.
+func (c *closeBoth) Close() error {
+	err := c.ReadCloser.Close()
+	c.client.Close()
+	return err
+}
+
+// Stats returns the resource usage of the container of an instance.
+func (Runtime) Stats(ctx context.Context, id string) (types.Stats, error) {
+	cli, err := newClient()
+	if err != nil {
+		return types.Stats{}, err
+	}
+	defer cli.Close()
+
+	// A single sample, which includes the previous sample to measure CPU over.
+	resp, err := cli.ContainerStats(ctx, ContainerName(id), false)
+	if err != nil {
+		return types.Stats{}, err
+	}
+	defer resp.Body.Close()
+
+	var stats dockerTypes.StatsJSON
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return types.Stats{}, fmt.Errorf("Stats() error decoding stats: \n%v", err)
+	}
+
+	return StatsFrom(stats), nil
+}
+
+// StatsFrom converts the stats reported by the docker engine into resource usage.
+func StatsFrom(stats dockerTypes.StatsJSON) types.Stats {
+	var usage types.Stats
+
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	cpus := float64(stats.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		usage.MilliCPU = int64(cpuDelta / systemDelta * cpus * 1000)
+	}
+
+	// Page cache can be reclaimed, so is not counted as used.
+	usage.Memory = int64(stats.MemoryStats.Usage)
+	if cache, ok := stats.MemoryStats.Stats["inactive_file"]; ok && cache < stats.MemoryStats.Usage {
+		usage.Memory -= int64(cache)
+	}
+	usage.MemoryLimit = int64(stats.MemoryStats.Limit)
+
+	return usage
+}
+
+// Resources returns the total CPU and memory of the host running the docker engine.
+func (Runtime) Resources(ctx context.Context) (types.Resources, error) {
+	return HostResources(ctx)
+}
@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/RicochetStudios/aurora/config"
+	"github.com/RicochetStudios/aurora/docker"
+	"github.com/RicochetStudios/aurora/kubernetes"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+)
+
+// Runtime runs the workloads of server instances, such as containers or pods.
+// Each instance is identified by its id, which the runtime names and labels its objects after.
+type Runtime interface {
+	// Resolve returns an image pinned to the digest its tag currently points to.
+	Resolve(ctx context.Context, image string) (string, error)
+	// Deploy creates the workload of a new instance and starts it.
+	Deploy(ctx context.Context, id string, gameSchema schema.Schema, server types.Server) error
+	// Update replaces the workload of an instance with a new configuration, keeping its data.
+	Update(ctx context.Context, id string, gameSchema schema.Schema, server types.Server) error
+	// Start starts the stopped workload of an instance.
+	Start(ctx context.Context, id string) error
+	// Stop stops the workload of an instance, keeping its data.
+	Stop(ctx context.Context, id string) error
+	// Remove deletes the workload and data of an instance.
+	Remove(ctx context.Context, id string) error
+	// Status returns the state of the workload of an instance.
+	Status(ctx context.Context, id string) (types.RuntimeStatus, error)
+	// Logs returns the last lines of output of an instance, or all of it if tail is not positive.
+	Logs(ctx context.Context, id string, tail int) (io.ReadCloser, error)
+	// Exec runs a command inside the workload of an instance.
+	Exec(ctx context.Context, id string, command []string) (types.ExecResult, error)
+	// CopyTo extracts a tar archive into a directory of the workload of an instance.
+	CopyTo(ctx context.Context, id string, dir string, archive io.Reader) error
+	// CopyFrom returns a tar archive of a file or directory of the workload of an instance,
+	// with its entries named from the base name of the path.
+	CopyFrom(ctx context.Context, id string, path string) (io.ReadCloser, error)
+	// Replace swaps a directory of the stopped workload of an instance for the contents of a tar archive.
+	// The directory is only replaced once the archive has been extracted beside it.
+	Replace(ctx context.Context, id string, dir string, archive io.Reader) error
+	// Stats returns the resource usage of an instance.
+	Stats(ctx context.Context, id string) (types.Stats, error)
+	// Resources returns the total compute available to the runtime.
+	Resources(ctx context.Context) (types.Resources, error)
+}
+
+// New creates the runtime chosen by the settings.
+func New(settings config.Settings) (Runtime, error) {
+	switch settings.Runtime {
+	case config.RuntimeDocker:
+		return docker.Runtime{}, nil
+	case config.RuntimeKubernetes:
+		return kubernetes.NewRuntime(settings.Kubeconfig, settings.Namespace)
+	default:
+		return nil, fmt.Errorf("New() unknown runtime %q", settings.Runtime)
+	}
+}
+
+// Current creates the runtime chosen by the current settings.
+func Current() (Runtime, error) {
+	return New(config.Current())
+}
@@ -0,0 +1,36 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/RicochetStudios/aurora/config"
+	"github.com/RicochetStudios/aurora/docker"
+	"github.com/RicochetStudios/aurora/engine/enginetest"
+	"github.com/RicochetStudios/aurora/kubernetes"
+)
+
+// The runtimes must implement Runtime.
+var (
+	_ Runtime = docker.Runtime{}
+	_ Runtime = &kubernetes.Runtime{}
+	_ Runtime = enginetest.NewFake()
+)
+
+// TestNew calls New with each runtime setting,
+// checking for the matching runtime or an error in return.
+func TestNew(t *testing.T) {
+	settings := config.Defaults()
+
+	got, err := New(settings)
+	if err != nil {
+		t.Fatalf("New() returned an error: \n%v", err)
+	}
+	if _, ok := got.(docker.Runtime); !ok {
+		t.Fatalf("New() = %T, want docker.Runtime", got)
+	}
+
+	settings.Runtime = "podman"
+	if _, err := New(settings); err == nil {
+		t.Fatalf("New() expected an unknown runtime error, got %v", err)
+	}
+}
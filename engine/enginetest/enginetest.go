@@ -0,0 +1,403 @@
+package enginetest
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+)
+
+// Fake is a runtime keeping workloads in memory, for testing code which uses a runtime.
+// Errors can be injected for any method, by name.
+type Fake struct {
+	mu        sync.Mutex
+	workloads map[string]types.Server // Deployed servers, by instance id.
+	running   map[string]bool         // Whether each workload is running, by instance id.
+	revisions map[string]string       // Revision each workload was deployed with, by instance id.
+	digests   map[string]string       // Digests images resolve to, by image.
+	files     map[string][]byte       // Content of the files of workloads, by instance id and path joined by a colon.
+	calls     []string                // Methods called which change workloads, as "Method id".
+	errors    map[string]error        // Errors returned by methods, by method name.
+}
+
+// NewFake creates a Fake without workloads.
+func NewFake() *Fake {
+	return &Fake{
+		workloads: map[string]types.Server{},
+		running:   map[string]bool{},
+		revisions: map[string]string{},
+		digests:   map[string]string{},
+		files:     map[string][]byte{},
+		errors:    map[string]error{},
+	}
+}
+
+// Fail makes a method return an error, or succeed again if err is nil.
+func (f *Fake) Fail(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.errors, method)
+		return
+	}
+	f.errors[method] = err
+}
+
+// Publish makes an image resolve to a new digest, as if a new version was pushed to its tag.
+func (f *Fake) Publish(image string, digest string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.digests[image] = digest
+}
+
+// Workload returns the server an instance was deployed with, whether it is running, and whether it exists.
+func (f *Fake) Workload(id string) (types.Server, bool, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	server, ok := f.workloads[id]
+	return server, f.running[id], ok
+}
+
+// File returns the content of a file of the workload of an instance, and whether it exists.
+func (f *Fake) File(id string, file string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	content, ok := f.files[id+":"+file]
+	return content, ok
+}
+
+// WriteFile creates a file in the workload of an instance, as if the server wrote it.
+func (f *Fake) WriteFile(id string, file string, content []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.files[id+":"+file] = content
+}
+
+// Crash stops the workload of an instance, as if it exited.
+func (f *Fake) Crash(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running[id] = false
+}
+
+// Delete removes the workload of an instance, as if it was deleted by hand.
+func (f *Fake) Delete(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.workloads, id)
+	delete(f.running, id)
+	delete(f.revisions, id)
+}
+
+// Calls returns the methods called which change workloads, as "Method id", and forgets them.
+func (f *Fake) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := f.calls
+	f.calls = nil
+	return calls
+}
+
+// call records a call to a method, returning its injected error if any.
+// It must be called with the lock held.
+func (f *Fake) call(method string, id string) error {
+	if err, ok := f.errors[method]; ok {
+		return err
+	}
+	f.calls = append(f.calls, method+" "+id)
+	return nil
+}
+
+// Resolve returns the image pinned to its published digest, or to a digest of its name
+// if none was published.
+func (f *Fake) Resolve(ctx context.Context, image string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Resolve", image); err != nil {
+		return "", err
+	}
+	digest, ok := f.digests[image]
+	if !ok {
+		sum := sha256.Sum256([]byte(image))
+		digest = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	name, _, _ := strings.Cut(image, ":")
+	return name + "@" + digest, nil
+}
+
+// Deploy records a running workload of the server, with its revision.
+func (f *Fake) Deploy(ctx context.Context, id string, gameSchema schema.Schema, server types.Server) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Deploy", id); err != nil {
+		return err
+	}
+	f.workloads[id] = server
+	f.running[id] = true
+	revision, err := schema.Revision(gameSchema, server)
+	if err != nil {
+		return err
+	}
+	f.revisions[id] = revision
+	return nil
+}
+
+// Update replaces the server of an existing workload, with its revision.
+func (f *Fake) Update(ctx context.Context, id string, gameSchema schema.Schema, server types.Server) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Update", id); err != nil {
+		return err
+	}
+	if _, ok := f.workloads[id]; !ok {
+		return fmt.Errorf("workload %v does not exist", id)
+	}
+	f.workloads[id] = server
+	revision, err := schema.Revision(gameSchema, server)
+	if err != nil {
+		return err
+	}
+	f.revisions[id] = revision
+	return nil
+}
+
+// Start marks an existing workload as running.
+func (f *Fake) Start(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Start", id); err != nil {
+		return err
+	}
+	if _, ok := f.workloads[id]; !ok {
+		return fmt.Errorf("workload %v does not exist", id)
+	}
+	f.running[id] = true
+	return nil
+}
+
+// Stop marks an existing workload as stopped.
+func (f *Fake) Stop(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Stop", id); err != nil {
+		return err
+	}
+	if _, ok := f.workloads[id]; !ok {
+		return fmt.Errorf("workload %v does not exist", id)
+	}
+	f.running[id] = false
+	return nil
+}
+
+// Remove deletes the workload of an instance, if it exists.
+func (f *Fake) Remove(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Remove", id); err != nil {
+		return err
+	}
+	delete(f.workloads, id)
+	delete(f.running, id)
+	delete(f.revisions, id)
+	for key := range f.files {
+		if strings.HasPrefix(key, id+":") {
+			delete(f.files, key)
+		}
+	}
+	return nil
+}
+
+// Status returns whether the workload of an instance is running, stopped or missing, with its revision.
+func (f *Fake) Status(ctx context.Context, id string) (types.RuntimeStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err, ok := f.errors["Status"]; ok {
+		return types.RuntimeStatus{}, err
+	}
+	if _, ok := f.workloads[id]; !ok {
+		return types.RuntimeStatus{State: types.StateMissing}, nil
+	}
+	if !f.running[id] {
+		return types.RuntimeStatus{State: types.StateStopped, Revision: f.revisions[id]}, nil
+	}
+	return types.RuntimeStatus{State: types.StateRunning, Revision: f.revisions[id]}, nil
+}
+
+// Logs returns no output.
+func (f *Fake) Logs(ctx context.Context, id string, tail int) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+// Exec records the call, acting out rm -f, rm -rf and mv on the files of the workload.
+// Any other command succeeds without output.
+func (f *Fake) Exec(ctx context.Context, id string, command []string) (types.ExecResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Exec", id); err != nil {
+		return types.ExecResult{}, err
+	}
+
+	switch {
+	case len(command) >= 2 && command[0] == "rm" && command[1] == "-f":
+		for _, file := range command[2:] {
+			delete(f.files, id+":"+file)
+		}
+	case len(command) >= 2 && command[0] == "rm" && command[1] == "-rf":
+		for _, file := range command[2:] {
+			for key := range f.files {
+				if key == id+":"+file || strings.HasPrefix(key, id+":"+file+"/") {
+					delete(f.files, key)
+				}
+			}
+		}
+	case len(command) == 3 && command[0] == "mv":
+		content, ok := f.files[id+":"+command[1]]
+		if !ok {
+			return types.ExecResult{ExitCode: 1, Output: "mv: cannot stat '" + command[1] + "': No such file or directory"}, nil
+		}
+		delete(f.files, id+":"+command[1])
+		f.files[id+":"+command[2]] = content
+	}
+	return types.ExecResult{}, nil
+}
+
+// CopyTo extracts the regular files of a tar archive into a directory of the workload of an instance.
+func (f *Fake) CopyTo(ctx context.Context, id string, dir string, archive io.Reader) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("CopyTo", id); err != nil {
+		return err
+	}
+	if _, ok := f.workloads[id]; !ok {
+		return fmt.Errorf("workload %v does not exist", id)
+	}
+
+	reader := tar.NewReader(archive)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		f.files[id+":"+path.Join(dir, header.Name)] = content
+	}
+}
+
+// CopyFrom returns a tar archive of a file or directory of the workload of an instance.
+func (f *Fake) CopyFrom(ctx context.Context, id string, file string) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err, ok := f.errors["CopyFrom"]; ok {
+		return nil, err
+	}
+
+	// Entries are named from the base name of the path, in a stable order.
+	var names []string
+	for key := range f.files {
+		name, found := strings.CutPrefix(key, id+":")
+		if found && (name == file || strings.HasPrefix(name, file+"/")) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%v does not exist in workload %v", file, id)
+	}
+	sort.Strings(names)
+
+	var archive bytes.Buffer
+	writer := tar.NewWriter(&archive)
+	for _, name := range names {
+		content := f.files[id+":"+name]
+		header := &tar.Header{
+			Name:     path.Join(path.Base(file), strings.TrimPrefix(name, file)),
+			Mode:     0644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}
+		if err := writer.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := writer.Write(content); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(&archive), nil
+}
+
+// Replace swaps the files under a directory of the stopped workload of an instance
+// for the regular files of a tar archive.
+func (f *Fake) Replace(ctx context.Context, id string, dir string, archive io.Reader) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Replace", id); err != nil {
+		return err
+	}
+	if _, ok := f.workloads[id]; !ok {
+		return fmt.Errorf("workload %v does not exist", id)
+	}
+	if f.running[id] {
+		return fmt.Errorf("workload %v must be stopped", id)
+	}
+
+	// Read the whole archive first, so a broken archive leaves the directory as it was.
+	files := map[string][]byte{}
+	reader := tar.NewReader(archive)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		files[id+":"+path.Join(dir, header.Name)] = content
+	}
+
+	for key := range f.files {
+		if name, found := strings.CutPrefix(key, id+":"); found && strings.HasPrefix(name, dir+"/") {
+			delete(f.files, key)
+		}
+	}
+	for key, content := range files {
+		f.files[key] = content
+	}
+	return nil
+}
+
+// Stats returns no usage.
+func (f *Fake) Stats(ctx context.Context, id string) (types.Stats, error) {
+	return types.Stats{}, nil
+}
+
+// Resources returns no resources.
+func (f *Fake) Resources(ctx context.Context) (types.Resources, error) {
+	return types.Resources{}, nil
+}
@@ -0,0 +1,165 @@
+package files
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RicochetStudios/aurora/engine"
+	"github.com/RicochetStudios/aurora/types"
+)
+
+var (
+	// ErrTooLarge is returned for files larger than the limit they are read or written with.
+	ErrTooLarge error = errors.New("file is larger than the limit")
+
+	// ErrFailed is returned when a command changing files fails, such as for a path which does not exist.
+	ErrFailed error = errors.New("file operation failed")
+)
+
+// listScript prints the type, size, modification time and name of every entry of a directory, one per line.
+// The fields are separated by slashes, which names can not contain.
+const listScript string = `cd "$1" || exit 1
+for f in * .*; do
+	{ [ "$f" = . ] || [ "$f" = .. ]; } && continue
+	{ [ -e "$f" ] || [ -L "$f" ]; } || continue
+	stat -c '%F/%s/%Y/%n' "$f"
+done`
+
+// renameScript moves a file or directory, unless something already has its new name,
+// as mv would otherwise move it inside a directory of that name.
+const renameScript string = `if [ -e "$2" ] || [ -L "$2" ]; then echo "$2 already exists" >&2; exit 1; fi
+mv "$1" "$2"`
+
+// List returns the files of a directory of the workload of an instance, in order of their names.
+// Commands are run in the workload, so it must be running.
+func List(ctx context.Context, runtime engine.Runtime, id string, dir string) ([]types.File, error) {
+	output, err := run(ctx, runtime, id, "sh", "-c", listScript, "sh", dir)
+	if err != nil {
+		return nil, err
+	}
+	return parseList(dir, output), nil
+}
+
+// parseList reads the files of a directory from the output of listScript, skipping lines it can not read.
+func parseList(dir string, output string) []types.File {
+	files := []types.File{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "/", 4)
+		if len(fields) != 4 || fields[3] == "" {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		modified, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		files = append(files, types.File{
+			Name:       fields[3],
+			Path:       path.Join(dir, fields[3]),
+			Dir:        fields[0] == "directory",
+			Size:       size,
+			ModifiedAt: time.Unix(modified, 0).UTC(),
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files
+}
+
+// Read returns the content of a file of the workload of an instance,
+// or an error if it is a directory or larger than limit bytes.
+func Read(ctx context.Context, runtime engine.Runtime, id string, file string, limit int64) ([]byte, error) {
+	archive, err := runtime.CopyFrom(ctx, id, file)
+	if err != nil {
+		return nil, fmt.Errorf("Read() error copying %v: \n%v", file, err)
+	}
+	defer archive.Close()
+
+	reader := tar.NewReader(archive)
+	header, err := reader.Next()
+	if err != nil {
+		return nil, fmt.Errorf("Read() error reading archive of %v: %v", file, err)
+	}
+	if header.Typeflag != tar.TypeReg {
+		return nil, fmt.Errorf("Read() %w: %v is not a regular file", ErrFailed, file)
+	}
+	if header.Size > limit {
+		return nil, fmt.Errorf("Read() %w of %v bytes: %v is %v bytes", ErrTooLarge, limit, file, header.Size)
+	}
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("Read() error reading %v: %v", file, err)
+	}
+	return content, nil
+}
+
+// Write creates or replaces a file of the workload of an instance, in a directory which already exists.
+func Write(ctx context.Context, runtime engine.Runtime, id string, file string, content []byte) error {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	header := &tar.Header{
+		Name:     path.Base(file),
+		Mode:     0644,
+		Size:     int64(len(content)),
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("Write() error writing archive header: %v", err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		return fmt.Errorf("Write() error writing archive: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("Write() error closing archive: %v", err)
+	}
+
+	if err := runtime.CopyTo(ctx, id, path.Dir(file), &archive); err != nil {
+		return fmt.Errorf("Write() error copying %v: \n%v", file, err)
+	}
+	return nil
+}
+
+// Rename moves a file or directory of the workload of an instance, unless its new path already exists.
+func Rename(ctx context.Context, runtime engine.Runtime, id string, from string, to string) error {
+	_, err := run(ctx, runtime, id, "sh", "-c", renameScript, "sh", from, to)
+	return err
+}
+
+// Remove deletes a file, or a directory and everything inside it, from the workload of an instance.
+func Remove(ctx context.Context, runtime engine.Runtime, id string, file string) error {
+	_, err := run(ctx, runtime, id, "rm", "-rf", file)
+	return err
+}
+
+// Mkdir creates a directory of the workload of an instance, with any parents it is missing.
+func Mkdir(ctx context.Context, runtime engine.Runtime, id string, dir string) error {
+	_, err := run(ctx, runtime, id, "mkdir", "-p", dir)
+	return err
+}
+
+// run executes a command in the workload of an instance, returning its output,
+// or the output as an ErrFailed error if it fails.
+func run(ctx context.Context, runtime engine.Runtime, id string, command ...string) (string, error) {
+	result, err := runtime.Exec(ctx, id, command)
+	if err != nil {
+		return "", err
+	}
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("%w: %v exited with code %v: %v", ErrFailed, command[0], result.ExitCode, strings.TrimSpace(result.Output))
+	}
+	return result.Output, nil
+}
@@ -0,0 +1,99 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RicochetStudios/aurora/engine/enginetest"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// setup returns a runtime with a deployed workload.
+func setup(t *testing.T) *enginetest.Fake {
+	runtime := enginetest.NewFake()
+	if err := runtime.Deploy(context.Background(), "00000001", schema.Schema{}, types.Server{Name: "myserver"}); err != nil {
+		t.Fatalf("setup() error deploying workload: \n%v", err)
+	}
+	return runtime
+}
+
+// TestParseList calls parseList with the output of listing a directory,
+// checking files and directories are read in order of their names and unreadable lines are skipped.
+func TestParseList(t *testing.T) {
+	output := "regular file/42/1700000000/server.properties\n" +
+		"directory/4096/1700000100/world\n" +
+		"stat: cannot stat 'gone': No such file or directory\n" +
+		"regular empty file/0/1700000200/.hidden\n"
+
+	want := []types.File{
+		{Name: ".hidden", Path: "/data/.hidden", ModifiedAt: time.Unix(1700000200, 0).UTC()},
+		{Name: "server.properties", Path: "/data/server.properties", Size: 42, ModifiedAt: time.Unix(1700000000, 0).UTC()},
+		{Name: "world", Path: "/data/world", Dir: true, Size: 4096, ModifiedAt: time.Unix(1700000100, 0).UTC()},
+	}
+	if diff := cmp.Diff(want, parseList("/data", output)); diff != "" {
+		t.Fatalf("parseList() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestWriteRead calls Write and then Read on a file,
+// checking the content is kept and reading it with a smaller limit fails.
+func TestWriteRead(t *testing.T) {
+	runtime := setup(t)
+	ctx := context.Background()
+
+	if err := Write(ctx, runtime, "00000001", "/data/server.properties", []byte("motd=hi")); err != nil {
+		t.Fatalf("Write() returned an error: \n%v", err)
+	}
+	content, err := Read(ctx, runtime, "00000001", "/data/server.properties", 1024)
+	if err != nil {
+		t.Fatalf("Read() returned an error: \n%v", err)
+	}
+	if string(content) != "motd=hi" {
+		t.Fatalf("Read() = %q, want the written content", content)
+	}
+
+	if _, err := Read(ctx, runtime, "00000001", "/data/server.properties", 3); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("Read() with a small limit returned %v, want %v", err, ErrTooLarge)
+	}
+}
+
+// TestRemove calls Remove on a directory,
+// checking everything inside it is deleted and its neighbours are kept.
+func TestRemove(t *testing.T) {
+	runtime := setup(t)
+	runtime.WriteFile("00000001", "/data/config/a.toml", []byte("a"))
+	runtime.WriteFile("00000001", "/data/config/sub/b.toml", []byte("b"))
+	runtime.WriteFile("00000001", "/data/config.bak", []byte("c"))
+
+	if err := Remove(context.Background(), runtime, "00000001", "/data/config"); err != nil {
+		t.Fatalf("Remove() returned an error: \n%v", err)
+	}
+	for _, file := range []string{"/data/config/a.toml", "/data/config/sub/b.toml"} {
+		if _, ok := runtime.File("00000001", file); ok {
+			t.Fatalf("Remove() left %v behind", file)
+		}
+	}
+	if _, ok := runtime.File("00000001", "/data/config.bak"); !ok {
+		t.Fatalf("Remove() deleted a file outside the directory")
+	}
+}
+
+// TestSetProperties calls SetProperties on a properties file,
+// checking keys are changed in place, removed or added in order, keeping comments.
+func TestSetProperties(t *testing.T) {
+	content := "#Minecraft server properties\nmotd=A Minecraft Server\npvp: true\nspawn-protection=16\n"
+	set := map[string]string{"spawn-protection": "0", "view-distance": "12", "difficulty": "hard"}
+
+	want := "#Minecraft server properties\nmotd=A Minecraft Server\nspawn-protection=0\ndifficulty=hard\nview-distance=12\n"
+	if diff := cmp.Diff(want, string(SetProperties([]byte(content), set, []string{"pvp"}))); diff != "" {
+		t.Fatalf("SetProperties() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("motd=hi\n", string(SetProperties(nil, map[string]string{"motd": "hi"}, nil))); diff != "" {
+		t.Fatalf("SetProperties() of an empty file mismatch (-want +got):\n%s", diff)
+	}
+}
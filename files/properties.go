@@ -0,0 +1,82 @@
+package files
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/RicochetStudios/aurora/engine"
+)
+
+// WriteProperties sets keys of a properties file of the workload of an instance, and removes others,
+// so the game uses its default for them. The file must already exist, as games write it when they first start.
+// It returns the previous content of the file, to restore it with Write.
+func WriteProperties(ctx context.Context, runtime engine.Runtime, id string, file string, set map[string]string, remove []string, limit int64) ([]byte, error) {
+	previous, err := Read(ctx, runtime, id, file, limit)
+	if err != nil {
+		return nil, fmt.Errorf("WriteProperties() error reading %v: \n%v", file, err)
+	}
+	if err := Write(ctx, runtime, id, file, SetProperties(previous, set, remove)); err != nil {
+		return nil, fmt.Errorf("WriteProperties() error writing %v: \n%v", file, err)
+	}
+	return previous, nil
+}
+
+// SetProperties returns the content of a properties file with the values of keys set and other keys removed.
+// Keys already in the file are changed where they are, keeping comments and the order of the file,
+// and keys it does not have are added to the end in order of their names.
+func SetProperties(content []byte, set map[string]string, remove []string) []byte {
+	removed := map[string]bool{}
+	for _, key := range remove {
+		removed[key] = true
+	}
+	written := map[string]bool{}
+
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(content) == 0 {
+		lines = nil
+	}
+	kept := make([]string, 0, len(lines)+len(set))
+	for _, line := range lines {
+		key, ok := propertyKey(line)
+		if !ok {
+			kept = append(kept, line)
+			continue
+		}
+		if value, found := set[key]; found {
+			kept = append(kept, key+"="+value)
+			written[key] = true
+			continue
+		}
+		if !removed[key] {
+			kept = append(kept, line)
+		}
+	}
+
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		if !written[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		kept = append(kept, key+"="+set[key])
+	}
+
+	return []byte(strings.Join(kept, "\n") + "\n")
+}
+
+// propertyKey returns the key of a line of a properties file, and false for comments and blank lines.
+func propertyKey(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "!") {
+		return "", false
+	}
+	end := strings.IndexAny(trimmed, "=: \t")
+	if end == -1 {
+		return trimmed, true
+	}
+	return trimmed[:end], true
+}
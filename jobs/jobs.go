@@ -0,0 +1,265 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// StatusPending is a job which has been submitted but not started.
+	StatusPending string = "pending"
+
+	// StatusRunning is a job which is being executed.
+	StatusRunning string = "running"
+
+	// StatusSucceeded is a job which finished without error.
+	StatusSucceeded string = "succeeded"
+
+	// StatusFailed is a job which finished with an error.
+	StatusFailed string = "failed"
+
+	// retention is how long finished jobs are kept for.
+	retention time.Duration = time.Hour
+)
+
+// ErrActive is returned when a job is submitted for an instance which another job is still acting on.
+var ErrActive error = errors.New("another job is in progress for the instance")
+
+// Func is the work of a job. Progress is reported with Report on the given context.
+type Func func(ctx context.Context) error
+
+// Manager executes jobs in the background and keeps their progress.
+type Manager struct {
+	mu          sync.Mutex
+	jobs        map[string]*types.Job
+	subscribers map[string][]chan types.Job
+	wg          sync.WaitGroup
+	now         func() time.Time
+}
+
+// NewManager creates an empty Manager.
+func NewManager() *Manager {
+	return &Manager{
+		jobs:        map[string]*types.Job{},
+		subscribers: map[string][]chan types.Job{},
+		now:         time.Now,
+	}
+}
+
+// defaultManager holds the jobs of this node.
+var defaultManager *Manager = NewManager()
+
+// Submit starts a job on this node. See Manager.Submit.
+func Submit(action string, instanceID string, run Func) (types.Job, error) {
+	return defaultManager.Submit(action, instanceID, run)
+}
+
+// Get returns a job of this node. See Manager.Get.
+func Get(id string) (types.Job, bool) {
+	return defaultManager.Get(id)
+}
+
+// Subscribe follows a job of this node. See Manager.Subscribe.
+func Subscribe(id string) (<-chan types.Job, func(), bool) {
+	return defaultManager.Subscribe(id)
+}
+
+// Active returns whether a job of this node is acting on an instance. See Manager.Active.
+func Active(instanceID string) bool {
+	return defaultManager.Active(instanceID)
+}
+
+// Submit records a new job and executes it in the background, returning it as pending.
+// The job is not tied to any request, so it keeps running if the client goes away.
+// Only one job acts on an instance at a time, so ErrActive is returned if another has not finished,
+// as the steps of both, and their undoing, would overwrite each other.
+func (m *Manager) Submit(action string, instanceID string, run Func) (types.Job, error) {
+	m.mu.Lock()
+	m.prune()
+	if m.active(instanceID) {
+		m.mu.Unlock()
+		return types.Job{}, ErrActive
+	}
+	now := m.now()
+	job := &types.Job{
+		ID:         uuid.New().String(),
+		Action:     action,
+		InstanceID: instanceID,
+		Status:     StatusPending,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	m.jobs[job.ID] = job
+	submitted := *job
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go m.execute(job.ID, run)
+
+	return submitted, nil
+}
+
+// execute runs a job, recording its progress and result.
+func (m *Manager) execute(id string, run Func) {
+	defer m.wg.Done()
+
+	m.update(id, func(job *types.Job) { job.Status = StatusRunning })
+
+	ctx := context.WithValue(context.Background(), reporterKey{}, reporter(func(step string, progress int) {
+		m.update(id, func(job *types.Job) {
+			job.Step = step
+			job.Progress = progress
+		})
+	}))
+	err := run(ctx)
+
+	m.update(id, func(job *types.Job) {
+		if err != nil {
+			job.Status = StatusFailed
+			job.Error = err.Error()
+			return
+		}
+		job.Status = StatusSucceeded
+		job.Progress = 100
+	})
+}
+
+// update modifies a job and notifies its subscribers.
+// Subscribers are closed once the job has finished.
+func (m *Manager) update(id string, change func(job *types.Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return
+	}
+	change(job)
+	job.UpdatedAt = m.now()
+
+	finished := Finished(*job)
+	for _, subscriber := range m.subscribers[id] {
+		send(subscriber, *job)
+		if finished {
+			close(subscriber)
+		}
+	}
+	if finished {
+		delete(m.subscribers, id)
+	}
+}
+
+// send delivers the latest state of a job, replacing an undelivered older state
+// so a slow subscriber never blocks the job.
+func send(subscriber chan types.Job, job types.Job) {
+	select {
+	case <-subscriber:
+	default:
+	}
+	subscriber <- job
+}
+
+// prune removes jobs which finished longer ago than the retention period.
+// It must be called with the lock held.
+func (m *Manager) prune() {
+	for id, job := range m.jobs {
+		if Finished(*job) && m.now().Sub(job.UpdatedAt) > retention {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// Get returns the current state of a job, and whether it exists.
+func (m *Manager) Get(id string) (types.Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return types.Job{}, false
+	}
+	return *job, true
+}
+
+// Subscribe returns a channel receiving the state of a job each time it changes,
+// starting with its current state. The channel is closed when the job finishes,
+// or when the returned cancel function is called. False is returned if the job does not exist.
+func (m *Manager) Subscribe(id string) (<-chan types.Job, func(), bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, func() {}, false
+	}
+
+	subscriber := make(chan types.Job, 1)
+	subscriber <- *job
+	if Finished(*job) {
+		close(subscriber)
+		return subscriber, func() {}, true
+	}
+	m.subscribers[id] = append(m.subscribers[id], subscriber)
+
+	cancel := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for i, s := range m.subscribers[id] {
+			if s == subscriber {
+				m.subscribers[id] = append(m.subscribers[id][:i], m.subscribers[id][i+1:]...)
+				close(subscriber)
+				return
+			}
+		}
+	}
+	return subscriber, cancel, true
+}
+
+// Active returns whether a job which has not finished is acting on an instance.
+func (m *Manager) Active(instanceID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.active(instanceID)
+}
+
+// active returns whether a job which has not finished is acting on an instance.
+// It must be called with the lock held.
+func (m *Manager) active(instanceID string) bool {
+	for _, job := range m.jobs {
+		if job.InstanceID == instanceID && !Finished(*job) {
+			return true
+		}
+	}
+	return false
+}
+
+// Wait blocks until every submitted job has finished.
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
+
+// Finished returns whether a job has succeeded or failed.
+func Finished(job types.Job) bool {
+	return job.Status == StatusSucceeded || job.Status == StatusFailed
+}
+
+// reporterKey is the context key of the reporter of a job.
+type reporterKey struct{}
+
+// reporter records the progress of a job.
+type reporter func(step string, progress int)
+
+// Report records the step a job is executing and its percentage complete.
+// It does nothing if the context does not belong to a job, so may be called from anywhere.
+func Report(ctx context.Context, step string, progress int) {
+	if report, ok := ctx.Value(reporterKey{}).(reporter); ok {
+		report(step, progress)
+	}
+}
@@ -0,0 +1,147 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RicochetStudios/aurora/types"
+)
+
+// TestSubmit calls Submit with a job reporting progress,
+// checking the job is returned pending and finishes as succeeded.
+func TestSubmit(t *testing.T) {
+	manager := NewManager()
+
+	submitted, _ := manager.Submit("server.create", "my-unique-id", func(ctx context.Context) error {
+		Report(ctx, "pulling", 43)
+		return nil
+	})
+
+	if submitted.Status != StatusPending || submitted.ID == "" {
+		t.Fatalf("Submit() = %v, want a pending job with an id", submitted)
+	}
+
+	manager.Wait()
+	got, ok := manager.Get(submitted.ID)
+	if !ok {
+		t.Fatalf("Get(%v) did not find the job", submitted.ID)
+	}
+	if got.Status != StatusSucceeded || got.Step != "pulling" || got.Progress != 100 || got.InstanceID != "my-unique-id" {
+		t.Fatalf("Get(%v) = %v, want a succeeded job", submitted.ID, got)
+	}
+}
+
+// TestSubmitFailure calls Submit with a job returning an error,
+// checking the job finishes as failed with the error.
+func TestSubmitFailure(t *testing.T) {
+	manager := NewManager()
+
+	submitted, _ := manager.Submit("server.create", "my-unique-id", func(ctx context.Context) error {
+		return errors.New("image not found")
+	})
+	manager.Wait()
+
+	got, _ := manager.Get(submitted.ID)
+	if got.Status != StatusFailed || got.Error != "image not found" {
+		t.Fatalf("Get(%v) = %v, want a failed job with its error", submitted.ID, got)
+	}
+}
+
+// TestSubscribe calls Subscribe on a running job,
+// checking every state is received in order and the channel is closed when it finishes.
+func TestSubscribe(t *testing.T) {
+	manager := NewManager()
+	proceed := make(chan struct{})
+
+	submitted, _ := manager.Submit("server.create", "my-unique-id", func(ctx context.Context) error {
+		<-proceed
+		Report(ctx, "creating", 0)
+		return nil
+	})
+
+	updates, cancel, ok := manager.Subscribe(submitted.ID)
+	if !ok {
+		t.Fatalf("Subscribe(%v) did not find the job", submitted.ID)
+	}
+	defer cancel()
+	close(proceed)
+
+	var last types.Job
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case job, open := <-updates:
+			if !open {
+				if last.Status != StatusSucceeded {
+					t.Fatalf("Subscribe() last state = %v, want a succeeded job", last)
+				}
+				return
+			}
+			last = job
+		case <-timeout:
+			t.Fatalf("Subscribe() channel was not closed after the job finished")
+		}
+	}
+}
+
+// TestSubscribeUnknown calls Subscribe and Get with an id which does not exist,
+// checking the job is not found.
+func TestSubscribeUnknown(t *testing.T) {
+	manager := NewManager()
+
+	if _, _, ok := manager.Subscribe("missing"); ok {
+		t.Fatalf("Subscribe() found a job which does not exist")
+	}
+	if _, ok := manager.Get("missing"); ok {
+		t.Fatalf("Get() found a job which does not exist")
+	}
+}
+
+// TestPrune calls Submit after a job finished longer ago than the retention period,
+// checking the old job is removed.
+func TestPrune(t *testing.T) {
+	manager := NewManager()
+	now := time.Now()
+	manager.now = func() time.Time { return now }
+
+	old, _ := manager.Submit("server.create", "my-unique-id", func(ctx context.Context) error { return nil })
+	manager.Wait()
+
+	now = now.Add(2 * retention)
+	manager.Submit("server.remove", "my-unique-id", func(ctx context.Context) error { return nil })
+	manager.Wait()
+
+	if _, ok := manager.Get(old.ID); ok {
+		t.Fatalf("Get(%v) found a job older than the retention period", old.ID)
+	}
+}
+
+// TestActive calls Active and Submit while a job is running and after it finishes,
+// checking the instance is only active, and rejects other jobs, while the job runs.
+func TestActive(t *testing.T) {
+	manager := NewManager()
+	proceed := make(chan struct{})
+
+	manager.Submit("server.update", "my-unique-id", func(ctx context.Context) error {
+		<-proceed
+		return nil
+	})
+
+	if !manager.Active("my-unique-id") {
+		t.Fatalf("Active() = false while a job is running")
+	}
+	if _, err := manager.Submit("server.remove", "my-unique-id", func(ctx context.Context) error { return nil }); !errors.Is(err, ErrActive) {
+		t.Fatalf("Submit() while a job is running returned %v, want %v", err, ErrActive)
+	}
+	if manager.Active("another-id") {
+		t.Fatalf("Active() = true for an instance without jobs")
+	}
+
+	close(proceed)
+	manager.Wait()
+	if manager.Active("my-unique-id") {
+		t.Fatalf("Active() = true after the job finished")
+	}
+}
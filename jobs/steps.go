@@ -0,0 +1,46 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Step is a single action of a job, with the action which reverses it.
+type Step struct {
+	Name string                          // Name reported as the job's step e.g. "deploying".
+	Do   func(ctx context.Context) error // Carries out the step.
+	Undo func(ctx context.Context) error // Reverses the step, or nil if there is nothing to reverse.
+}
+
+// RunSteps carries out the steps in order, reporting each one as the job's step.
+// If a step fails, it and every step before it are undone in reverse order,
+// returning the state to how it was before the first step.
+// Undo is also called on the step which failed, so it must cope with the step being partly done.
+// The returned error contains the failure and any errors while undoing.
+func RunSteps(ctx context.Context, steps []Step) error {
+	for i, step := range steps {
+		Report(ctx, step.Name, 0)
+		if err := step.Do(ctx); err != nil {
+			failure := fmt.Errorf("error %v: \n%w", step.Name, err)
+			return errors.Join(failure, undo(ctx, steps[:i+1]))
+		}
+	}
+	return nil
+}
+
+// undo reverses the steps in reverse order, carrying on past errors
+// so as much as possible is reversed.
+func undo(ctx context.Context, steps []Step) error {
+	var errs []error
+	for i := len(steps) - 1; i >= 0; i-- {
+		if steps[i].Undo == nil {
+			continue
+		}
+		Report(ctx, "undoing "+steps[i].Name, 0)
+		if err := steps[i].Undo(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("error undoing %v: \n%w", steps[i].Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
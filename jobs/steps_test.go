@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// recordingSteps returns steps named a, b and c which record what is done and undone,
+// with the step named fail returning an error.
+func recordingSteps(fail string, record *[]string) []Step {
+	var steps []Step
+	for _, name := range []string{"a", "b", "c"} {
+		name := name
+		steps = append(steps, Step{
+			Name: name,
+			Do: func(ctx context.Context) error {
+				if name == fail {
+					return errors.New("injected failure")
+				}
+				*record = append(*record, "do "+name)
+				return nil
+			},
+			Undo: func(ctx context.Context) error {
+				*record = append(*record, "undo "+name)
+				return nil
+			},
+		})
+	}
+	return steps
+}
+
+// TestRunSteps calls RunSteps with a failure injected at each step,
+// checking the failed step and every step before it are undone in reverse order.
+func TestRunSteps(t *testing.T) {
+	tests := []struct {
+		fail string
+		want []string
+	}{
+		{"", []string{"do a", "do b", "do c"}},
+		{"a", []string{"undo a"}},
+		{"b", []string{"do a", "undo b", "undo a"}},
+		{"c", []string{"do a", "do b", "undo c", "undo b", "undo a"}},
+	}
+
+	for _, test := range tests {
+		var got []string
+		err := RunSteps(context.Background(), recordingSteps(test.fail, &got))
+
+		if (err != nil) != (test.fail != "") {
+			t.Fatalf("RunSteps() failing at %q returned error %v", test.fail, err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Fatalf("RunSteps() failing at %q mismatch (-want +got):\n%s", test.fail, diff)
+		}
+	}
+}
+
+// TestRunStepsUndoError calls RunSteps with a step which fails to be undone,
+// checking the remaining steps are still undone and both errors are returned.
+func TestRunStepsUndoError(t *testing.T) {
+	var got []string
+	steps := recordingSteps("c", &got)
+	steps[1].Undo = func(ctx context.Context) error { return errors.New("undo failure") }
+
+	err := RunSteps(context.Background(), steps)
+
+	if err == nil || !cmp.Equal([]string{"do a", "do b", "undo c", "undo a"}, got) {
+		t.Fatalf("RunSteps() = %v, recorded %v, want both errors and the other steps undone", err, got)
+	}
+}
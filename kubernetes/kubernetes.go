@@ -0,0 +1,298 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// namePrefix is prepended to the names of objects created for instances.
+	namePrefix string = "aurora-"
+
+	// InstanceLabel is the label identifying the instance an object belongs to.
+	InstanceLabel string = "aurora.instance"
+
+	// managedByLabel is the well known label identifying the tool managing an object.
+	managedByLabel string = "app.kubernetes.io/managed-by"
+
+	// RevisionAnnotation records the revision of the configuration a workload was rendered from.
+	// It is also set on the pod template, so pods are replaced when the ConfigMap changes.
+	RevisionAnnotation string = "aurora.revision"
+
+	// PullSecretName is the name of the secret holding the registry credentials servers are pulled with.
+	PullSecretName string = namePrefix + "registry"
+
+	// containerName is the name of the game server container in the pod.
+	containerName string = "server"
+)
+
+// Manifests are the Kubernetes objects which run a server instance.
+type Manifests struct {
+	ConfigMap              *corev1.ConfigMap
+	PersistentVolumeClaims []*corev1.PersistentVolumeClaim
+	Service                *corev1.Service
+	StatefulSet            *appsv1.StatefulSet
+}
+
+// Name returns the name of the objects for an instance.
+func Name(id string) string {
+	return namePrefix + id
+}
+
+// VolumeName returns the name of the claim for a schema volume of an instance.
+func VolumeName(id string, volume string) string {
+	return namePrefix + id + "-" + volume
+}
+
+// Render creates the Kubernetes objects for an instance from its game schema and server.
+// Environment settings are held in a ConfigMap, each schema volume is a PersistentVolumeClaim,
+// the schema network is exposed by a Service, and the server runs in a single replica StatefulSet.
+func Render(id string, gameSchema schema.Schema, server types.Server) (Manifests, error) {
+	name := Name(id)
+	labels := map[string]string{
+		managedByLabel: "aurora",
+		InstanceLabel:  id,
+	}
+	meta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Labels: labels}
+	}
+
+	size, ok := gameSchema.Sizes[server.Size]
+	if !ok {
+		return Manifests{}, fmt.Errorf("Render() size %q is not supported by %v", server.Size, gameSchema.Name)
+	}
+	cpu, err := resource.ParseQuantity(size.Resources.CPU)
+	if err != nil {
+		return Manifests{}, fmt.Errorf("Render() invalid cpu %q: %v", size.Resources.CPU, err)
+	}
+	memory, err := resource.ParseQuantity(size.Resources.Memory)
+	if err != nil {
+		return Manifests{}, fmt.Errorf("Render() invalid memory %q: %v", size.Resources.Memory, err)
+	}
+
+	// Hold the templated settings in a ConfigMap, loaded as environment variables.
+	configMap := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: meta(name),
+		Data:       map[string]string{},
+	}
+	for _, setting := range schema.Environment(gameSchema, server) {
+		configMap.Data[setting.Name] = setting.Value
+	}
+
+	// Claim storage for each volume.
+	var claims []*corev1.PersistentVolumeClaim
+	var volumes []corev1.Volume
+	var mounts []corev1.VolumeMount
+	for _, volume := range gameSchema.Volumes {
+		storage, err := resource.ParseQuantity(volume.Size)
+		if err != nil {
+			return Manifests{}, fmt.Errorf("Render() invalid size %q of volume %v: %v", volume.Size, volume.Name, err)
+		}
+		class := volume.Class
+		claimName := VolumeName(id, volume.Name)
+
+		claims = append(claims, &corev1.PersistentVolumeClaim{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolumeClaim"},
+			ObjectMeta: meta(claimName),
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				StorageClassName: &class,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: storage},
+				},
+			},
+		})
+		volumes = append(volumes, corev1.Volume{
+			Name: volume.Name,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+			},
+		})
+		mounts = append(mounts, corev1.VolumeMount{Name: volume.Name, MountPath: volume.Path})
+	}
+
+	// Expose the network ports, publicly if requested.
+	serviceType := corev1.ServiceTypeClusterIP
+	if server.Network.Type == "public" {
+		serviceType = corev1.ServiceTypeLoadBalancer
+	}
+	var servicePorts []corev1.ServicePort
+	var containerPorts []corev1.ContainerPort
+	for _, network := range gameSchema.Network {
+		protocol := corev1.Protocol(strings.ToUpper(network.Protocol))
+		servicePorts = append(servicePorts, corev1.ServicePort{
+			Name:       network.Name,
+			Port:       int32(network.Port),
+			Protocol:   protocol,
+			TargetPort: intstr.FromString(network.Name),
+		})
+		containerPorts = append(containerPorts, corev1.ContainerPort{
+			Name:          network.Name,
+			ContainerPort: int32(network.Port),
+			Protocol:      protocol,
+		})
+	}
+	service := &corev1.Service{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: meta(name),
+		Spec: corev1.ServiceSpec{
+			Type:     serviceType,
+			Selector: labels,
+			Ports:    servicePorts,
+		},
+	}
+
+	// Run the server.
+	replicas := int32(1)
+	revision, err := schema.Revision(gameSchema, server)
+	if err != nil {
+		return Manifests{}, fmt.Errorf("Render() %v", err)
+	}
+	annotations := map[string]string{RevisionAnnotation: revision}
+	statefulSetMeta := meta(name)
+	statefulSetMeta.Annotations = annotations
+	statefulSet := &appsv1.StatefulSet{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "StatefulSet"},
+		ObjectMeta: statefulSetMeta,
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    &replicas,
+			ServiceName: name,
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				Type: appsv1.RollingUpdateStatefulSetStrategyType,
+			},
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels, Annotations: annotations},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  containerName,
+						Image: schema.Image(gameSchema, server),
+						Ports: containerPorts,
+						EnvFrom: []corev1.EnvFromSource{{
+							ConfigMapRef: &corev1.ConfigMapEnvSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: name},
+							},
+						}},
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{corev1.ResourceCPU: cpu, corev1.ResourceMemory: memory},
+							Limits:   corev1.ResourceList{corev1.ResourceCPU: cpu, corev1.ResourceMemory: memory},
+						},
+						VolumeMounts:   mounts,
+						StartupProbe:   probe(gameSchema.Probes.Command, gameSchema.Probes.StartupProbe, false),
+						ReadinessProbe: probe(gameSchema.Probes.Command, gameSchema.Probes.ReadynessProbe, true),
+						LivenessProbe:  probe(gameSchema.Probes.Command, gameSchema.Probes.LivenessProbe, false),
+					}},
+					Volumes: volumes,
+				},
+			},
+		},
+	}
+
+	return Manifests{
+		ConfigMap:              configMap,
+		PersistentVolumeClaims: claims,
+		Service:                service,
+		StatefulSet:            statefulSet,
+	}, nil
+}
+
+// probe converts a schema probe into a Kubernetes exec probe, or nil if the schema has no command.
+// Kubernetes requires startup and liveness probes to succeed exactly once,
+// so the success threshold is only used for readiness probes.
+func probe(command []string, p schema.Probe, readiness bool) *corev1.Probe {
+	if len(command) == 0 {
+		return nil
+	}
+
+	out := &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			Exec: &corev1.ExecAction{Command: command},
+		},
+		InitialDelaySeconds: int32(p.InitialDelaySeconds),
+		PeriodSeconds:       int32(p.PeriodSeconds),
+		FailureThreshold:    int32(p.FailureThreshold),
+		TimeoutSeconds:      int32(p.TimeoutSeconds),
+	}
+	if readiness {
+		out.SuccessThreshold = int32(p.SuccessThreshold)
+	}
+	return out
+}
+
+// Objects returns the objects in the order they should be applied,
+// so configuration and storage exist before the workload which uses them.
+func (m Manifests) Objects() []runtime.Object {
+	objects := []runtime.Object{m.ConfigMap}
+	for _, claim := range m.PersistentVolumeClaims {
+		objects = append(objects, claim)
+	}
+	return append(objects, m.Service, m.StatefulSet)
+}
+
+// YAML returns the objects as a multi document yaml stream, suitable for kubectl apply.
+// Server populated fields such as status are left out.
+func (m Manifests) YAML() ([]byte, error) {
+	var out bytes.Buffer
+	for i, object := range m.Objects() {
+		// Round trip through a map, to drop the fields only the cluster sets.
+		as_json, err := json.Marshal(object)
+		if err != nil {
+			return nil, fmt.Errorf("YAML() error converting object to json: %v", err)
+		}
+		var fields map[string]any
+		if err := json.Unmarshal(as_json, &fields); err != nil {
+			return nil, fmt.Errorf("YAML() error converting json to map: %v", err)
+		}
+		delete(fields, "status")
+		if metadata, ok := fields["metadata"].(map[string]any); ok {
+			delete(metadata, "creationTimestamp")
+		}
+		clean(fields)
+
+		as_yaml, err := yaml.Marshal(fields)
+		if err != nil {
+			return nil, fmt.Errorf("YAML() error converting object to yaml: %v", err)
+		}
+		if i > 0 {
+			out.WriteString("---\n")
+		}
+		out.Write(as_yaml)
+	}
+	return out.Bytes(), nil
+}
+
+// clean removes empty creationTimestamp and resources fields left by nested object metadata.
+func clean(v any) {
+	switch value := v.(type) {
+	case map[string]any:
+		for key, child := range value {
+			if child == nil && (key == "creationTimestamp") {
+				delete(value, key)
+				continue
+			}
+			if m, ok := child.(map[string]any); ok && len(m) == 0 && key == "resources" {
+				delete(value, key)
+				continue
+			}
+			clean(child)
+		}
+	case []any:
+		for _, child := range value {
+			clean(child)
+		}
+	}
+}
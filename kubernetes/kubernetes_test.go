@@ -0,0 +1,133 @@
+package kubernetes
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// update rewrites the golden files with the rendered output, run with `go test ./kubernetes -update`.
+var update = flag.Bool("update", false, "update the golden files")
+
+// testSchema is the minecraft_java schema.
+var testSchema schema.Schema = schema.Schema{
+	Name:  "minecraft_java",
+	Image: "itzg/minecraft-server:latest",
+	URL:   "https://github.com/itzg/docker-minecraft-server",
+	Ratio: "1-2",
+	Sizes: map[string]schema.Size{
+		"xs": {
+			Resources: schema.Resources{CPU: "1000m", Memory: "2000Mi"},
+			Players:   8,
+		},
+		"s": {
+			Resources: schema.Resources{CPU: "1500m", Memory: "4000Mi"},
+			Players:   16,
+		},
+	},
+	Network: []schema.Network{
+		{Name: "game", Port: 25565, Protocol: "tcp"},
+	},
+	Settings: []schema.Setting{
+		{Name: "EULA", Value: "TRUE"},
+		{Name: "TYPE", Value: "{{ .modloader }}"},
+		{Name: "MAX_PLAYERS", Value: "{{ .players }}"},
+		{Name: "MOTD", Value: "{{ .name }}"},
+	},
+	Volumes: []schema.Volume{
+		{
+			Name:  "data",
+			Path:  "/data",
+			Class: "classic",
+			Size:  "10Gi",
+		},
+	},
+	Probes: schema.Probes{
+		Command: []string{"mc-health"},
+		StartupProbe: schema.Probe{
+			FailureThreshold: 30,
+			PeriodSeconds:    10,
+		},
+		ReadynessProbe: schema.Probe{
+			InitialDelaySeconds: 30,
+			PeriodSeconds:       5,
+			FailureThreshold:    20,
+			SuccessThreshold:    3,
+			TimeoutSeconds:      1,
+		},
+		LivenessProbe: schema.Probe{
+			InitialDelaySeconds: 30,
+			PeriodSeconds:       5,
+			FailureThreshold:    20,
+			SuccessThreshold:    3,
+			TimeoutSeconds:      1,
+		},
+	},
+}
+
+// TestRenderGolden calls Render with private and public servers,
+// checking the yaml matches the golden files in testdata.
+func TestRenderGolden(t *testing.T) {
+	tests := []struct {
+		golden string
+		server types.Server
+	}{
+		{
+			golden: "private.yaml",
+			server: types.Server{
+				Name:    "mytest",
+				Size:    "xs",
+				Game:    types.Game{Name: "minecraft_java", Modloader: "vanilla"},
+				Network: types.Network{Type: "private"},
+			},
+		},
+		{
+			golden: "public.yaml",
+			server: types.Server{
+				Name:    "mypublictest",
+				Size:    "s",
+				Game:    types.Game{Name: "minecraft_java", Modloader: "forge"},
+				Network: types.Network{Type: "public"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		manifests, err := Render("my-unique-id", testSchema, test.server)
+		if err != nil {
+			t.Fatalf("Render() (%v) returned an error: \n%v", test.golden, err)
+		}
+		got, err := manifests.YAML()
+		if err != nil {
+			t.Fatalf("YAML() (%v) returned an error: \n%v", test.golden, err)
+		}
+
+		path := filepath.Join("testdata", test.golden)
+		if *update {
+			if err := os.WriteFile(path, got, 0644); err != nil {
+				t.Fatalf("TestRenderGolden() error updating golden file %v: \n%v", path, err)
+			}
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("TestRenderGolden() error reading golden file %v: \n%v", path, err)
+		}
+		if diff := cmp.Diff(string(want), string(got)); diff != "" {
+			t.Fatalf("YAML() (%v) mismatch (-want +got):\n%s", test.golden, diff)
+		}
+	}
+}
+
+// TestRenderInvalidSize calls Render with a size the schema does not support,
+// checking for an error in return.
+func TestRenderInvalidSize(t *testing.T) {
+	if _, err := Render("my-unique-id", testSchema, types.Server{Size: "xxl"}); err == nil {
+		t.Fatalf("Render() expected an unsupported size error, got %v", err)
+	}
+}
@@ -0,0 +1,533 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/RicochetStudios/aurora/jobs"
+	"github.com/RicochetStudios/aurora/registry"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/exec"
+	metrics "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// Runtime runs server instances as workloads in a Kubernetes cluster,
+// applying the manifests rendered from their game schema.
+type Runtime struct {
+	Client    clientset.Interface // Client for the core and apps APIs.
+	Metrics   metrics.Interface   // Client for the metrics API, required for Stats.
+	Config    *rest.Config        // Config the clients were created from, required for Exec.
+	Namespace string              // Namespace the workloads are created in.
+}
+
+// NewRuntime creates a Runtime for the cluster in a kubeconfig file,
+// or the cluster Aurora is running in if the path is empty.
+func NewRuntime(kubeconfig string, namespace string) (*Runtime, error) {
+	var config *rest.Config
+	var err error
+	if kubeconfig == "" {
+		config, err = rest.InClusterConfig()
+	} else {
+		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("NewRuntime() error loading cluster config: \n%v", err)
+	}
+
+	client, err := clientset.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("NewRuntime() error creating client: \n%v", err)
+	}
+	metricsClient, err := metrics.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("NewRuntime() error creating metrics client: \n%v", err)
+	}
+
+	return &Runtime{Client: client, Metrics: metricsClient, Config: config, Namespace: namespace}, nil
+}
+
+// podName returns the name of the single pod of an instance's StatefulSet.
+func podName(id string) string {
+	return Name(id) + "-0"
+}
+
+// Deploy applies the manifests of an instance, creating or updating each object.
+func (r *Runtime) Deploy(ctx context.Context, id string, gameSchema schema.Schema, server types.Server) error {
+	manifests, err := Render(id, gameSchema, server)
+	if err != nil {
+		return fmt.Errorf("Deploy() error rendering manifests: \n%v", err)
+	}
+	jobs.Report(ctx, "applying", 0)
+
+	// Images from the configured registry are pulled with its credentials.
+	if credentials, ok := registry.Lookup(schema.Image(gameSchema, server)); ok {
+		if err := r.applyPullSecret(ctx, credentials); err != nil {
+			return fmt.Errorf("Deploy() error applying image pull secret: \n%v", err)
+		}
+		manifests.StatefulSet.Spec.Template.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: PullSecretName}}
+	}
+
+	if err := r.applyConfigMap(ctx, manifests.ConfigMap); err != nil {
+		return fmt.Errorf("Deploy() error applying config map: \n%v", err)
+	}
+	for _, claim := range manifests.PersistentVolumeClaims {
+		if err := r.applyPersistentVolumeClaim(ctx, claim); err != nil {
+			return fmt.Errorf("Deploy() error applying persistent volume claim: \n%v", err)
+		}
+	}
+	if err := r.applyService(ctx, manifests.Service); err != nil {
+		return fmt.Errorf("Deploy() error applying service: \n%v", err)
+	}
+	if err := r.applyStatefulSet(ctx, manifests.StatefulSet); err != nil {
+		return fmt.Errorf("Deploy() error applying stateful set: \n%v", err)
+	}
+	return nil
+}
+
+// Update applies the new manifests of an instance. The pod is replaced by a rolling update,
+// keeping its volumes, and a stopped instance stays stopped.
+func (r *Runtime) Update(ctx context.Context, id string, gameSchema schema.Schema, server types.Server) error {
+	return r.Deploy(ctx, id, gameSchema, server)
+}
+
+// Resolve asks the registry of an image for the digest its tag points to, returning the image pinned to it.
+// The cluster pulls the image itself when the pod is scheduled.
+func (r *Runtime) Resolve(ctx context.Context, image string) (string, error) {
+	return registry.Resolve(ctx, image)
+}
+
+// applyPullSecret creates the secret holding the registry credentials, or replaces those of an existing one.
+// The secret is shared by every instance in the namespace.
+func (r *Runtime) applyPullSecret(ctx context.Context, credentials registry.Credentials) error {
+	client := r.Client.CoreV1().Secrets(r.Namespace)
+
+	// Docker Hub credentials are keyed by its legacy index address.
+	server := credentials.Server
+	if server == "docker.io" {
+		server = "https://index.docker.io/v1/"
+	}
+	dockerConfig, err := json.Marshal(map[string]any{
+		"auths": map[string]any{
+			server: map[string]string{
+				"username": credentials.Username,
+				"password": credentials.Password,
+				"auth":     base64.StdEncoding.EncodeToString([]byte(credentials.Username + ":" + credentials.Password)),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: PullSecretName, Labels: map[string]string{managedByLabel: "aurora"}},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: dockerConfig},
+	}
+
+	existing, err := client.Get(ctx, secret.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = client.Create(ctx, secret, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	existing.Data = secret.Data
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// applyConfigMap creates the config map, or replaces the data of an existing one.
+func (r *Runtime) applyConfigMap(ctx context.Context, configMap *corev1.ConfigMap) error {
+	client := r.Client.CoreV1().ConfigMaps(r.Namespace)
+
+	existing, err := client.Get(ctx, configMap.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = client.Create(ctx, configMap, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	existing.Labels = configMap.Labels
+	existing.Data = configMap.Data
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// applyPersistentVolumeClaim creates the claim if it does not exist.
+// The spec of a bound claim can not be changed, so existing claims are left alone.
+func (r *Runtime) applyPersistentVolumeClaim(ctx context.Context, claim *corev1.PersistentVolumeClaim) error {
+	client := r.Client.CoreV1().PersistentVolumeClaims(r.Namespace)
+
+	_, err := client.Create(ctx, claim, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// applyService creates the service, or replaces the ports and type of an existing one.
+func (r *Runtime) applyService(ctx context.Context, service *corev1.Service) error {
+	client := r.Client.CoreV1().Services(r.Namespace)
+
+	existing, err := client.Get(ctx, service.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = client.Create(ctx, service, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	// The cluster assigned fields, such as the cluster IP, are kept.
+	existing.Labels = service.Labels
+	existing.Spec.Type = service.Spec.Type
+	existing.Spec.Selector = service.Spec.Selector
+	existing.Spec.Ports = service.Spec.Ports
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// applyStatefulSet creates the stateful set, or replaces the pod template of an existing one.
+func (r *Runtime) applyStatefulSet(ctx context.Context, statefulSet *appsv1.StatefulSet) error {
+	client := r.Client.AppsV1().StatefulSets(r.Namespace)
+
+	existing, err := client.Get(ctx, statefulSet.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = client.Create(ctx, statefulSet, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	// The selector can not be changed, and the replicas are kept so stopped servers stay stopped.
+	existing.Labels = statefulSet.Labels
+	existing.Annotations = statefulSet.Annotations
+	existing.Spec.Template = statefulSet.Spec.Template
+	existing.Spec.UpdateStrategy = statefulSet.Spec.UpdateStrategy
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// Start scales the stateful set of an instance up to its single replica.
+func (r *Runtime) Start(ctx context.Context, id string) error {
+	return r.scale(ctx, id, 1)
+}
+
+// Stop scales the stateful set of an instance down to zero, keeping its volumes.
+func (r *Runtime) Stop(ctx context.Context, id string) error {
+	return r.scale(ctx, id, 0)
+}
+
+// scale sets the number of replicas of the stateful set of an instance.
+func (r *Runtime) scale(ctx context.Context, id string, replicas int32) error {
+	client := r.Client.AppsV1().StatefulSets(r.Namespace)
+
+	statefulSet, err := client.Get(ctx, Name(id), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	statefulSet.Spec.Replicas = &replicas
+	_, err = client.Update(ctx, statefulSet, metav1.UpdateOptions{})
+	return err
+}
+
+// Remove deletes every object of an instance, including its volumes.
+func (r *Runtime) Remove(ctx context.Context, id string) error {
+	name := Name(id)
+	ignoreNotFound := func(err error) error {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	// Remove the workload first, so nothing is using the volumes.
+	if err := ignoreNotFound(r.Client.AppsV1().StatefulSets(r.Namespace).Delete(ctx, name, metav1.DeleteOptions{})); err != nil {
+		return fmt.Errorf("Remove() error deleting stateful set: \n%v", err)
+	}
+	if err := ignoreNotFound(r.Client.CoreV1().Services(r.Namespace).Delete(ctx, name, metav1.DeleteOptions{})); err != nil {
+		return fmt.Errorf("Remove() error deleting service: \n%v", err)
+	}
+	if err := ignoreNotFound(r.Client.CoreV1().ConfigMaps(r.Namespace).Delete(ctx, name, metav1.DeleteOptions{})); err != nil {
+		return fmt.Errorf("Remove() error deleting config map: \n%v", err)
+	}
+
+	claims, err := r.Client.CoreV1().PersistentVolumeClaims(r.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: InstanceLabel + "=" + id,
+	})
+	if err != nil {
+		return fmt.Errorf("Remove() error listing persistent volume claims: \n%v", err)
+	}
+	for _, claim := range claims.Items {
+		if err := ignoreNotFound(r.Client.CoreV1().PersistentVolumeClaims(r.Namespace).Delete(ctx, claim.Name, metav1.DeleteOptions{})); err != nil {
+			return fmt.Errorf("Remove() error deleting persistent volume claim %v: \n%v", claim.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Status returns the state of the stateful set and pod of an instance.
+func (r *Runtime) Status(ctx context.Context, id string) (types.RuntimeStatus, error) {
+	statefulSet, err := r.Client.AppsV1().StatefulSets(r.Namespace).Get(ctx, Name(id), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return types.RuntimeStatus{State: types.StateMissing}, nil
+	} else if err != nil {
+		return types.RuntimeStatus{}, err
+	}
+
+	if statefulSet.Spec.Replicas != nil && *statefulSet.Spec.Replicas == 0 {
+		return types.RuntimeStatus{State: types.StateStopped, Revision: statefulSet.Annotations[RevisionAnnotation]}, nil
+	}
+
+	status := types.RuntimeStatus{State: types.StateStarting, Revision: statefulSet.Annotations[RevisionAnnotation]}
+	if statefulSet.Status.ReadyReplicas > 0 {
+		status.State = types.StateRunning
+	}
+
+	// The pod explains why the server is not ready yet.
+	pod, err := r.Client.CoreV1().Pods(r.Namespace).Get(ctx, podName(id), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return status, nil
+	} else if err != nil {
+		return types.RuntimeStatus{}, err
+	}
+	if pod.Status.StartTime != nil {
+		status.StartedAt = pod.Status.StartTime.Time
+	}
+	status.Message = string(pod.Status.Phase)
+	for _, container := range pod.Status.ContainerStatuses {
+		if container.State.Waiting != nil {
+			status.Message = container.State.Waiting.Reason
+		}
+	}
+
+	return status, nil
+}
+
+// Logs returns the last lines of output of the server container of an instance, or all of it if tail is not positive.
+func (r *Runtime) Logs(ctx context.Context, id string, tail int) (io.ReadCloser, error) {
+	options := &corev1.PodLogOptions{Container: containerName}
+	if tail > 0 {
+		lines := int64(tail)
+		options.TailLines = &lines
+	}
+
+	return r.Client.CoreV1().Pods(r.Namespace).GetLogs(podName(id), options).Stream(ctx)
+}
+
+// Exec runs a command inside the server container of an instance and waits for it to finish.
+func (r *Runtime) Exec(ctx context.Context, id string, command []string) (types.ExecResult, error) {
+	var output bytes.Buffer
+	err := r.stream(ctx, podName(id), containerName, command, nil, &output, &output)
+	var exitErr exec.ExitError
+	if errors.As(err, &exitErr) {
+		return types.ExecResult{ExitCode: exitErr.ExitStatus(), Output: output.String()}, nil
+	} else if err != nil {
+		return types.ExecResult{}, fmt.Errorf("Exec() error running command: \n%v", err)
+	}
+
+	return types.ExecResult{Output: output.String()}, nil
+}
+
+// stream runs a command inside a container of a pod, connecting its input and output.
+// Stdin may be nil, for commands which read no input.
+func (r *Runtime) stream(ctx context.Context, pod string, container string, command []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	if r.Config == nil {
+		return errors.New("stream() requires the cluster config")
+	}
+
+	request := r.Client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(r.Namespace).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdin:     stdin != nil,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(r.Config, "POST", request.URL())
+	if err != nil {
+		return fmt.Errorf("stream() error creating executor: \n%v", err)
+	}
+
+	return executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdin: stdin, Stdout: stdout, Stderr: stderr})
+}
+
+// CopyTo extracts a tar archive into a directory of the server container of an instance,
+// with tar inside the container, so the pod must be running.
+func (r *Runtime) CopyTo(ctx context.Context, id string, dir string, archive io.Reader) error {
+	var output bytes.Buffer
+	if err := r.stream(ctx, podName(id), containerName, []string{"tar", "-xmf", "-", "-C", dir}, archive, &output, &output); err != nil {
+		return fmt.Errorf("CopyTo() error extracting archive: %v\n%v", err, output.String())
+	}
+	return nil
+}
+
+// CopyFrom returns a tar archive of a file or directory of the server container of an instance,
+// with tar inside the container, so the pod must be running.
+func (r *Runtime) CopyFrom(ctx context.Context, id string, file string) (io.ReadCloser, error) {
+	reader, writer := io.Pipe()
+	go func() {
+		var output bytes.Buffer
+		err := r.stream(ctx, podName(id), containerName, []string{"tar", "-cf", "-", "-C", path.Dir(file), path.Base(file)}, nil, writer, &output)
+		if err != nil {
+			err = fmt.Errorf("CopyFrom() error creating archive: %v\n%v", err, output.String())
+		}
+		writer.CloseWithError(err)
+	}()
+	return reader, nil
+}
+
+// Replace swaps a directory of the stopped stateful set of an instance for the contents of a tar archive.
+// The volumes of a stopped instance are not mounted anywhere, so a helper pod mounts them
+// and extracts the archive beside the directory with tar, before moving it over the directory.
+func (r *Runtime) Replace(ctx context.Context, id string, dir string, archive io.Reader) error {
+	pods := r.Client.CoreV1().Pods(r.Namespace)
+	if _, err := pods.Get(ctx, podName(id), metav1.GetOptions{}); err == nil {
+		return fmt.Errorf("Replace() pod %v must be stopped", podName(id))
+	} else if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	statefulSet, err := r.Client.AppsV1().StatefulSets(r.Namespace).Get(ctx, Name(id), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	template := statefulSet.Spec.Template.Spec
+	if len(template.Containers) == 0 {
+		return fmt.Errorf("Replace() stateful set %v has no containers", Name(id))
+	}
+
+	// The helper runs the image of the server, so tar behaves as it does for CopyTo.
+	helper := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   helperName(id),
+			Labels: map[string]string{InstanceLabel: id, managedByLabel: "aurora"},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy:    corev1.RestartPolicyNever,
+			ImagePullSecrets: template.ImagePullSecrets,
+			Volumes:          template.Volumes,
+			Containers: []corev1.Container{{
+				Name:         containerName,
+				Image:        template.Containers[0].Image,
+				Command:      []string{"sleep", "3600"},
+				VolumeMounts: template.Containers[0].VolumeMounts,
+			}},
+		},
+	}
+	if _, err := pods.Create(ctx, helper, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("Replace() error creating helper pod: \n%v", err)
+	}
+	defer pods.Delete(context.Background(), helper.Name, metav1.DeleteOptions{})
+
+	if err := r.waitRunning(ctx, helper.Name); err != nil {
+		return fmt.Errorf("Replace() error starting helper pod: \n%v", err)
+	}
+
+	var output bytes.Buffer
+	script := `rm -rf "$1.upload" && mkdir -p "$1.upload" && tar -xmf - -C "$1.upload" && rm -rf "$1" && mv "$1.upload" "$1"`
+	if err := r.stream(ctx, helper.Name, containerName, []string{"sh", "-c", script, "sh", dir}, archive, &output, &output); err != nil {
+		return fmt.Errorf("Replace() error replacing %v: %v\n%v", dir, err, output.String())
+	}
+	return nil
+}
+
+// helperName returns the name of the helper pod which mounts the volumes of a stopped instance.
+func helperName(id string) string {
+	return Name(id) + "-helper"
+}
+
+// waitRunning waits until a pod is running, returning an error if it stops first.
+func (r *Runtime) waitRunning(ctx context.Context, name string) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		pod, err := r.Client.CoreV1().Pods(r.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		switch pod.Status.Phase {
+		case corev1.PodRunning:
+			return nil
+		case corev1.PodSucceeded, corev1.PodFailed:
+			return fmt.Errorf("pod %v stopped: %v", name, pod.Status.Message)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stats returns the resource usage of the pod of an instance, from the metrics API.
+func (r *Runtime) Stats(ctx context.Context, id string) (types.Stats, error) {
+	if r.Metrics == nil {
+		return types.Stats{}, errors.New("Stats() requires the metrics client")
+	}
+
+	podMetrics, err := r.Metrics.MetricsV1beta1().PodMetricses(r.Namespace).Get(ctx, podName(id), metav1.GetOptions{})
+	if err != nil {
+		return types.Stats{}, fmt.Errorf("Stats() error getting pod metrics: \n%v", err)
+	}
+
+	var stats types.Stats
+	for _, container := range podMetrics.Containers {
+		stats.MilliCPU += container.Usage.Cpu().MilliValue()
+		stats.Memory += container.Usage.Memory().Value()
+	}
+
+	// The limit is the one set on the workload.
+	statefulSet, err := r.Client.AppsV1().StatefulSets(r.Namespace).Get(ctx, Name(id), metav1.GetOptions{})
+	if err != nil {
+		return types.Stats{}, fmt.Errorf("Stats() error getting stateful set: \n%v", err)
+	}
+	for _, container := range statefulSet.Spec.Template.Spec.Containers {
+		stats.MemoryLimit += container.Resources.Limits.Memory().Value()
+	}
+
+	return stats, nil
+}
+
+// Resources returns the total allocatable CPU and memory of the nodes in the cluster.
+func (r *Runtime) Resources(ctx context.Context) (types.Resources, error) {
+	nodes, err := r.Client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return types.Resources{}, fmt.Errorf("Resources() error listing nodes: \n%v", err)
+	}
+
+	var total types.Resources
+	for _, node := range nodes.Items {
+		total.MilliCPU += node.Status.Allocatable.Cpu().MilliValue()
+		total.Memory += node.Status.Allocatable.Memory().Value()
+	}
+	return total, nil
+}
@@ -0,0 +1,305 @@
+package kubernetes
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RicochetStudios/aurora/config"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+// testServer is a server using testSchema.
+var testServer types.Server = types.Server{
+	Name:    "mytest",
+	Size:    "xs",
+	Game:    types.Game{Name: "minecraft_java", Modloader: "vanilla"},
+	Network: types.Network{Type: "private"},
+}
+
+// newTestRuntime creates a Runtime backed by empty fake clients.
+func newTestRuntime() *Runtime {
+	return &Runtime{
+		Client:    fake.NewSimpleClientset(),
+		Metrics:   metricsfake.NewSimpleClientset(),
+		Namespace: "games",
+	}
+}
+
+// TestDeploy calls Deploy twice with a server,
+// checking every object is created and then updated in place.
+func TestDeploy(t *testing.T) {
+	ctx := context.Background()
+	runtime := newTestRuntime()
+
+	if err := runtime.Deploy(ctx, "my-unique-id", testSchema, testServer); err != nil {
+		t.Fatalf("Deploy() returned an error: \n%v", err)
+	}
+
+	// Apply a change, as an update would.
+	updated := testServer
+	updated.Name = "renamed"
+	if err := runtime.Deploy(ctx, "my-unique-id", testSchema, updated); err != nil {
+		t.Fatalf("Deploy() (again) returned an error: \n%v", err)
+	}
+
+	name := Name("my-unique-id")
+	configMap, err := runtime.Client.CoreV1().ConfigMaps("games").Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Deploy() did not create the config map: \n%v", err)
+	}
+	if got := configMap.Data["MOTD"]; got != "renamed" {
+		t.Fatalf("Deploy() config map MOTD = %q, want %q", got, "renamed")
+	}
+	if _, err := runtime.Client.CoreV1().Services("games").Get(ctx, name, metav1.GetOptions{}); err != nil {
+		t.Fatalf("Deploy() did not create the service: \n%v", err)
+	}
+	if _, err := runtime.Client.CoreV1().PersistentVolumeClaims("games").Get(ctx, VolumeName("my-unique-id", "data"), metav1.GetOptions{}); err != nil {
+		t.Fatalf("Deploy() did not create the persistent volume claim: \n%v", err)
+	}
+	if _, err := runtime.Client.AppsV1().StatefulSets("games").Get(ctx, name, metav1.GetOptions{}); err != nil {
+		t.Fatalf("Deploy() did not create the stateful set: \n%v", err)
+	}
+}
+
+// TestDeployPullSecret calls Deploy with registry credentials for the image of the schema,
+// checking the credentials are stored in a pull secret used by the pod.
+func TestDeployPullSecret(t *testing.T) {
+	ctx := context.Background()
+	runtime := newTestRuntime()
+
+	settings := config.Defaults()
+	settings.RegistryUsername = "aurora"
+	settings.RegistryPassword = "hunter2"
+	config.SetCurrent(settings)
+	t.Cleanup(func() { config.SetCurrent(config.Defaults()) })
+
+	if err := runtime.Deploy(ctx, "my-unique-id", testSchema, testServer); err != nil {
+		t.Fatalf("Deploy() returned an error: \n%v", err)
+	}
+
+	secret, err := runtime.Client.CoreV1().Secrets("games").Get(ctx, PullSecretName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Deploy() did not create the pull secret: \n%v", err)
+	}
+	if got := string(secret.Data[corev1.DockerConfigJsonKey]); !strings.Contains(got, `"username":"aurora"`) {
+		t.Fatalf("Deploy() pull secret = %v, want the registry credentials", got)
+	}
+
+	statefulSet, err := runtime.Client.AppsV1().StatefulSets("games").Get(ctx, Name("my-unique-id"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Deploy() did not create the stateful set: \n%v", err)
+	}
+	want := []corev1.LocalObjectReference{{Name: PullSecretName}}
+	if diff := cmp.Diff(want, statefulSet.Spec.Template.Spec.ImagePullSecrets); diff != "" {
+		t.Fatalf("Deploy() image pull secrets mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestStartStop calls Stop, Update and Start on a deployed server,
+// checking the status follows and an update keeps a stopped server stopped.
+func TestStartStop(t *testing.T) {
+	ctx := context.Background()
+	runtime := newTestRuntime()
+
+	if err := runtime.Deploy(ctx, "my-unique-id", testSchema, testServer); err != nil {
+		t.Fatalf("Deploy() returned an error: \n%v", err)
+	}
+
+	status, err := runtime.Status(ctx, "my-unique-id")
+	if err != nil || status.State != types.StateStarting {
+		t.Fatalf("Status() = %v, %v, want state %v", status, err, types.StateStarting)
+	}
+
+	if err := runtime.Stop(ctx, "my-unique-id"); err != nil {
+		t.Fatalf("Stop() returned an error: \n%v", err)
+	}
+	if err := runtime.Update(ctx, "my-unique-id", testSchema, testServer); err != nil {
+		t.Fatalf("Update() returned an error: \n%v", err)
+	}
+	status, err = runtime.Status(ctx, "my-unique-id")
+	if err != nil || status.State != types.StateStopped {
+		t.Fatalf("Status() = %v, %v, want state %v", status, err, types.StateStopped)
+	}
+
+	if err := runtime.Start(ctx, "my-unique-id"); err != nil {
+		t.Fatalf("Start() returned an error: \n%v", err)
+	}
+	status, err = runtime.Status(ctx, "my-unique-id")
+	if err != nil || status.State != types.StateStarting {
+		t.Fatalf("Status() = %v, %v, want state %v", status, err, types.StateStarting)
+	}
+}
+
+// TestRemove calls Remove on a deployed server,
+// checking every object is deleted and the status is missing.
+func TestRemove(t *testing.T) {
+	ctx := context.Background()
+	runtime := newTestRuntime()
+
+	if err := runtime.Deploy(ctx, "my-unique-id", testSchema, testServer); err != nil {
+		t.Fatalf("Deploy() returned an error: \n%v", err)
+	}
+	if err := runtime.Remove(ctx, "my-unique-id"); err != nil {
+		t.Fatalf("Remove() returned an error: \n%v", err)
+	}
+
+	claims, err := runtime.Client.CoreV1().PersistentVolumeClaims("games").List(ctx, metav1.ListOptions{})
+	if err != nil || len(claims.Items) != 0 {
+		t.Fatalf("Remove() left persistent volume claims: %v, %v", claims, err)
+	}
+	status, err := runtime.Status(ctx, "my-unique-id")
+	if err != nil || status.State != types.StateMissing {
+		t.Fatalf("Status() = %v, %v, want state %v", status, err, types.StateMissing)
+	}
+
+	// Removing again is not an error.
+	if err := runtime.Remove(ctx, "my-unique-id"); err != nil {
+		t.Fatalf("Remove() (again) returned an error: \n%v", err)
+	}
+}
+
+// TestReplace calls Replace on a deployed server,
+// checking it refuses while the pod is running and otherwise mounts the volumes in a helper pod, which is deleted after.
+func TestReplace(t *testing.T) {
+	ctx := context.Background()
+	runtime := newTestRuntime()
+
+	if err := runtime.Deploy(ctx, "my-unique-id", testSchema, testServer); err != nil {
+		t.Fatalf("Deploy() returned an error: \n%v", err)
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: podName("my-unique-id"), Namespace: "games"}}
+	if _, err := runtime.Client.CoreV1().Pods("games").Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Create() returned an error: \n%v", err)
+	}
+	if err := runtime.Replace(ctx, "my-unique-id", "/data/world", strings.NewReader("")); err == nil {
+		t.Fatalf("Replace() of a running server expected an error, got %v", err)
+	}
+	if err := runtime.Client.CoreV1().Pods("games").Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Delete() returned an error: \n%v", err)
+	}
+
+	var helper *corev1.Pod
+	runtime.Client.(*fake.Clientset).PrependReactor("create", "pods", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		helper = action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+		return false, nil, nil
+	})
+
+	// The fake helper never starts, so replacing gives up when the context ends.
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := runtime.Replace(timeout, "my-unique-id", "/data/world", strings.NewReader("")); err == nil {
+		t.Fatalf("Replace() without a running helper expected an error, got %v", err)
+	}
+
+	if helper == nil || len(helper.Spec.Volumes) != 1 || helper.Spec.Volumes[0].PersistentVolumeClaim.ClaimName != VolumeName("my-unique-id", "data") {
+		t.Fatalf("Replace() helper pod %+v does not mount the data volume", helper)
+	}
+	if _, err := runtime.Client.CoreV1().Pods("games").Get(ctx, helperName("my-unique-id"), metav1.GetOptions{}); err == nil {
+		t.Fatalf("Replace() left the helper pod behind")
+	}
+}
+
+// TestLogs calls Logs on a deployed server,
+// checking the pod logs are returned.
+func TestLogs(t *testing.T) {
+	ctx := context.Background()
+	runtime := newTestRuntime()
+
+	logs, err := runtime.Logs(ctx, "my-unique-id", 10)
+	if err != nil {
+		t.Fatalf("Logs() returned an error: \n%v", err)
+	}
+	defer logs.Close()
+
+	got, err := io.ReadAll(logs)
+	if err != nil {
+		t.Fatalf("Logs() error reading logs: \n%v", err)
+	}
+	// The fake clientset returns fixed logs.
+	if string(got) != "fake logs" {
+		t.Fatalf("Logs() = %q, want %q", got, "fake logs")
+	}
+}
+
+// TestStats calls Stats on a deployed server with pod metrics,
+// checking the usage and the limit of the workload in return.
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	runtime := newTestRuntime()
+	podMetrics := &metricsv1beta1.PodMetrics{
+		ObjectMeta: metav1.ObjectMeta{Name: podName("my-unique-id"), Namespace: "games"},
+		Containers: []metricsv1beta1.ContainerMetrics{{
+			Name: containerName,
+			Usage: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("250m"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		}},
+	}
+	// The fake tracker guesses the wrong resource name for pod metrics, so it is given explicitly.
+	metricsClient := metricsfake.NewSimpleClientset()
+	gvr := metricsv1beta1.SchemeGroupVersion.WithResource("pods")
+	if err := metricsClient.Tracker().Create(gvr, podMetrics, "games"); err != nil {
+		t.Fatalf("TestStats() error adding pod metrics: \n%v", err)
+	}
+	runtime.Metrics = metricsClient
+
+	if err := runtime.Deploy(ctx, "my-unique-id", testSchema, testServer); err != nil {
+		t.Fatalf("Deploy() returned an error: \n%v", err)
+	}
+
+	var want types.Stats = types.Stats{
+		MilliCPU:    250,
+		Memory:      1 << 30,
+		MemoryLimit: 2000 << 20,
+	}
+
+	got, err := runtime.Stats(ctx, "my-unique-id")
+
+	if err != nil {
+		t.Fatalf("Stats() returned an error: \n%v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Stats() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestResources calls Resources on a cluster of two nodes,
+// checking the sum of their allocatable resources in return.
+func TestResources(t *testing.T) {
+	node := func(name string, cpu string, memory string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			}},
+		}
+	}
+	runtime := newTestRuntime()
+	runtime.Client = fake.NewSimpleClientset(node("a", "2", "4Gi"), node("b", "1500m", "2Gi"))
+
+	var want types.Resources = types.Resources{MilliCPU: 3500, Memory: 6 << 30}
+
+	got, err := runtime.Resources(context.Background())
+
+	if err != nil {
+		t.Fatalf("Resources() returned an error: \n%v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Resources() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,431 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/RicochetStudios/aurora/config"
+	"github.com/RicochetStudios/aurora/db"
+	"github.com/RicochetStudios/aurora/engine"
+	"github.com/RicochetStudios/aurora/files"
+	"github.com/RicochetStudios/aurora/jobs"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+)
+
+var (
+	// healthyTimeout is how long a workload may take to become ready after it is started.
+	healthyTimeout time.Duration = 10 * time.Minute
+
+	// healthyInterval is how often the state of a starting workload is checked.
+	healthyInterval time.Duration = 2 * time.Second
+)
+
+// Spec is a server and the game schema it runs with.
+type Spec struct {
+	Schema schema.Schema
+	Server types.Server
+}
+
+// Create returns the steps which deploy a new instance and record it on this node,
+// pinned to the current digest of its image. If any step fails, the workload, config
+// and database are returned to having no instance.
+func Create(runtime engine.Runtime, id string, spec Spec) []jobs.Step {
+	return []jobs.Step{
+		resolveStep(runtime, &spec),
+		{
+			Name: "deploying",
+			Do: func(ctx context.Context) error {
+				return runtime.Deploy(ctx, id, spec.Schema, spec.Server)
+			},
+			// A failed deploy may have pulled the image and created volumes, so is removed too.
+			Undo: func(ctx context.Context) error {
+				return runtime.Remove(ctx, id)
+			},
+		},
+		{
+			Name: "registering",
+			Do: func(ctx context.Context) error {
+				_, err := config.AddInstance(id)
+				return err
+			},
+			Undo: func(ctx context.Context) error {
+				_, err := config.RemoveInstance(id)
+				return err
+			},
+		},
+		{
+			Name: "saving",
+			Do: func(ctx context.Context) error {
+				_, err := db.SetServer(ctx, id, spec.Server)
+				return err
+			},
+			Undo: func(ctx context.Context) error {
+				return db.RemoveServer(ctx, id)
+			},
+		},
+		waitHealthyStep(runtime, id),
+	}
+}
+
+// Update returns the steps which apply a new configuration to an existing instance.
+// The image is resolved again if the next server is not pinned to one.
+// If any step fails, the workload and database are returned to the previous configuration.
+func Update(runtime engine.Runtime, id string, previous Spec, next Spec) []jobs.Step {
+	return []jobs.Step{
+		resolveStep(runtime, &next),
+		{
+			Name: "updating",
+			Do: func(ctx context.Context) error {
+				return runtime.Update(ctx, id, next.Schema, next.Server)
+			},
+			Undo: func(ctx context.Context) error {
+				return runtime.Update(ctx, id, previous.Schema, previous.Server)
+			},
+		},
+		{
+			Name: "saving",
+			Do: func(ctx context.Context) error {
+				_, err := db.SetServer(ctx, id, next.Server)
+				return err
+			},
+			Undo: func(ctx context.Context) error {
+				_, err := db.SetServer(ctx, id, previous.Server)
+				return err
+			},
+		},
+		waitHealthyStep(runtime, id),
+	}
+}
+
+// UpdateProperties returns the steps which apply the game properties of the next server to an existing instance.
+// Properties passed as environment variables are applied by updating the workload, which restarts it,
+// and properties written to files are written once it is healthy, restarting it again so the game reads them.
+// A stopped server is stopped again afterwards. If any step fails, the previous properties are restored.
+func UpdateProperties(runtime engine.Runtime, id string, previous Spec, next Spec) []jobs.Step {
+	previousFiles := map[string][]byte{}
+
+	steps := Update(runtime, id, previous, next)
+	steps = append(steps, jobs.Step{
+		Name: "writing properties",
+		Do: func(ctx context.Context) error {
+			limit := int64(config.Current().FileLimit) * 1024 * 1024
+			set := schema.PropertyFiles(next.Schema, next.Server)
+			for file, values := range schema.PropertyFiles(previous.Schema, previous.Server) {
+				// Files whose properties are unchanged are left alone, so the server is not restarted for them.
+				changed := len(set[file]) != len(values)
+				var remove []string
+				for key, value := range values {
+					if nextValue, ok := set[file][key]; !ok {
+						remove = append(remove, key)
+					} else if nextValue != value {
+						changed = true
+					}
+				}
+				if changed || len(remove) > 0 {
+					content, err := files.WriteProperties(ctx, runtime, id, file, set[file], remove, limit)
+					if err != nil {
+						return err
+					}
+					previousFiles[file] = content
+				}
+				delete(set, file)
+			}
+			for file, values := range set {
+				content, err := files.WriteProperties(ctx, runtime, id, file, values, nil, limit)
+				if err != nil {
+					return err
+				}
+				previousFiles[file] = content
+			}
+			return nil
+		},
+		Undo: func(ctx context.Context) error {
+			for file, content := range previousFiles {
+				if err := files.Write(ctx, runtime, id, file, content); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+
+	// The game only reads the files it was changed in when it starts.
+	restart := Restart(runtime, id, next)
+	for _, step := range restart {
+		do := step.Do
+		step.Do = func(ctx context.Context) error {
+			if len(previousFiles) == 0 {
+				return nil
+			}
+			return do(ctx)
+		}
+		steps = append(steps, step)
+	}
+
+	return append(steps, jobs.Step{
+		Name: "stopping",
+		Do: func(ctx context.Context) error {
+			if next.Server.Status != types.StateStopped {
+				return nil
+			}
+			return runtime.Stop(ctx, id)
+		},
+	})
+}
+
+// WithPending returns the spec of a server once the changes pending for it are applied.
+func WithPending(current Spec) Spec {
+	next := current
+	if current.Server.Pending != nil {
+		next.Server.Properties = current.Server.Pending.Properties
+		next.Server.Pending = nil
+	}
+	return next
+}
+
+// UpdateImage returns the steps which pin an existing instance to the current digest of
+// the image of its schema, and recreate its workload with it if that has changed.
+func UpdateImage(runtime engine.Runtime, id string, current Spec) []jobs.Step {
+	next := current
+	next.Server.Image = ""
+	return Update(runtime, id, current, next)
+}
+
+// Remove returns the steps which delete an instance and its records from this node.
+// The workload is stopped first and only removed once the records are gone, as its data
+// can not be restored. If any step fails, the records are restored and the workload restarted.
+// Previous is the stored server, or nil if the instance has no record.
+func Remove(runtime engine.Runtime, id string, previous *types.Server) []jobs.Step {
+	var wasRunning bool
+	var previousMods types.ModManifest
+
+	return []jobs.Step{
+		{
+			Name: "stopping",
+			Do: func(ctx context.Context) error {
+				status, err := runtime.Status(ctx, id)
+				if err != nil {
+					return err
+				}
+				wasRunning = status.State == types.StateRunning || status.State == types.StateStarting
+				if !wasRunning {
+					return nil
+				}
+				return runtime.Stop(ctx, id)
+			},
+			Undo: func(ctx context.Context) error {
+				if !wasRunning {
+					return nil
+				}
+				return runtime.Start(ctx, id)
+			},
+		},
+		{
+			Name: "deleting",
+			Do: func(ctx context.Context) error {
+				manifest, err := db.GetMods(ctx, id)
+				if err != nil {
+					return err
+				}
+				previousMods = manifest
+				if err := db.RemoveMods(ctx, id); err != nil {
+					return err
+				}
+				return db.RemoveServer(ctx, id)
+			},
+			Undo: func(ctx context.Context) error {
+				if _, err := db.SetMods(ctx, previousMods); err != nil {
+					return err
+				}
+				if previous == nil {
+					return nil
+				}
+				_, err := db.SetServer(ctx, id, *previous)
+				return err
+			},
+		},
+		{
+			Name: "unregistering",
+			Do: func(ctx context.Context) error {
+				_, err := config.RemoveInstance(id)
+				return err
+			},
+			Undo: func(ctx context.Context) error {
+				_, err := config.AddInstance(id)
+				return err
+			},
+		},
+		{
+			Name: "removing",
+			Do: func(ctx context.Context) error {
+				return runtime.Remove(ctx, id)
+			},
+		},
+	}
+}
+
+// Start returns the steps which start the stopped workload of an instance,
+// recording it should be running. If it does not become ready, it is stopped again.
+// Changes pending for the server are applied once it is running, as games only write
+// the files they are kept in when they start.
+func Start(runtime engine.Runtime, id string, current Spec) []jobs.Step {
+	steps := []jobs.Step{
+		desiredStatusStep(id, types.StateRunning, types.StateStopped),
+		{
+			Name: "starting",
+			Do: func(ctx context.Context) error {
+				return runtime.Start(ctx, id)
+			},
+			Undo: func(ctx context.Context) error {
+				return runtime.Stop(ctx, id)
+			},
+		},
+		waitHealthyStep(runtime, id),
+	}
+	if current.Server.Pending == nil {
+		return steps
+	}
+
+	started := current
+	started.Server.Status = types.StateRunning
+	return append(steps, UpdateProperties(runtime, id, started, WithPending(started))...)
+}
+
+// Stop returns the steps which stop the workload of an instance, keeping its data,
+// and record it should stay stopped.
+func Stop(runtime engine.Runtime, id string) []jobs.Step {
+	return []jobs.Step{
+		desiredStatusStep(id, types.StateStopped, types.StateRunning),
+		{
+			Name: "stopping",
+			Do: func(ctx context.Context) error {
+				return runtime.Stop(ctx, id)
+			},
+		},
+	}
+}
+
+// Restart returns the steps which stop and start the workload of an instance, so it loads changed files.
+// A workload which is not running is left stopped, as it loads them when it is next started.
+// Changes pending for a server which should be running are applied as it restarts.
+func Restart(runtime engine.Runtime, id string, current Spec) []jobs.Step {
+	if current.Server.Pending != nil && current.Server.Status != types.StateStopped {
+		return UpdateProperties(runtime, id, current, WithPending(current))
+	}
+
+	var wasRunning bool
+
+	return []jobs.Step{
+		{
+			Name: "restarting",
+			Do: func(ctx context.Context) error {
+				status, err := runtime.Status(ctx, id)
+				if err != nil {
+					return err
+				}
+				wasRunning = status.State == types.StateRunning || status.State == types.StateStarting
+				if !wasRunning {
+					return nil
+				}
+				if err := runtime.Stop(ctx, id); err != nil {
+					return err
+				}
+				return runtime.Start(ctx, id)
+			},
+		},
+		{
+			Name: "waiting for healthy",
+			Do: func(ctx context.Context) error {
+				if !wasRunning {
+					return nil
+				}
+				return WaitHealthy(ctx, runtime, id)
+			},
+		},
+	}
+}
+
+// desiredStatusStep returns the step which records the status an instance should have,
+// so the reconciler keeps it that way.
+func desiredStatusStep(id string, status string, previous string) jobs.Step {
+	setStatus := func(ctx context.Context, status string) error {
+		server, err := db.GetServer(ctx, id)
+		if err != nil {
+			return err
+		}
+		server.Status = status
+		_, err = db.SetServer(ctx, id, server)
+		return err
+	}
+
+	return jobs.Step{
+		Name: "saving",
+		Do: func(ctx context.Context) error {
+			return setStatus(ctx, status)
+		},
+		Undo: func(ctx context.Context) error {
+			return setStatus(ctx, previous)
+		},
+	}
+}
+
+// resolveStep returns the step which pins the server of a spec to the current digest of the image of its version,
+// if it is not pinned already. The later steps see the pinned image, as they share the spec.
+func resolveStep(runtime engine.Runtime, spec *Spec) jobs.Step {
+	return jobs.Step{
+		Name: "resolving",
+		Do: func(ctx context.Context) error {
+			if spec.Server.Image != "" {
+				return nil
+			}
+			tagged := schema.Image(spec.Schema, spec.Server)
+			image, err := runtime.Resolve(ctx, tagged)
+			if err != nil {
+				return fmt.Errorf("error resolving image %v: \n%v", tagged, err)
+			}
+			spec.Server.Image = image
+			return nil
+		},
+	}
+}
+
+// waitHealthyStep returns the step which waits for the workload of an instance to become ready.
+func waitHealthyStep(runtime engine.Runtime, id string) jobs.Step {
+	return jobs.Step{
+		Name: "waiting for healthy",
+		Do: func(ctx context.Context) error {
+			return WaitHealthy(ctx, runtime, id)
+		},
+	}
+}
+
+// WaitHealthy waits for the workload of an instance to become ready,
+// returning an error if it stops or does not become ready in time.
+func WaitHealthy(ctx context.Context, runtime engine.Runtime, id string) error {
+	deadline := time.Now().Add(healthyTimeout)
+	for {
+		status, err := runtime.Status(ctx, id)
+		if err != nil {
+			return fmt.Errorf("WaitHealthy() error getting status: \n%v", err)
+		}
+
+		switch status.State {
+		case types.StateRunning:
+			return nil
+		case types.StateStopped, types.StateMissing:
+			return fmt.Errorf("WaitHealthy() workload is %v: %v", status.State, status.Message)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("WaitHealthy() workload did not become healthy within %v", healthyTimeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(healthyInterval):
+		}
+	}
+}
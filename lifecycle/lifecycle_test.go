@@ -0,0 +1,380 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/RicochetStudios/aurora/config"
+	"github.com/RicochetStudios/aurora/config/configtest"
+	"github.com/RicochetStudios/aurora/db"
+	"github.com/RicochetStudios/aurora/engine/enginetest"
+	"github.com/RicochetStudios/aurora/jobs"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// state is everything recorded about an instance, by the runtime, config and database.
+type state struct {
+	Workload   *types.Server
+	Running    bool
+	Registered bool
+	Record     *types.Server
+}
+
+// readState reads everything recorded about an instance.
+func readState(t *testing.T, runtime *enginetest.Fake, id string) state {
+	var s state
+	if workload, running, ok := runtime.Workload(id); ok {
+		s.Workload = &workload
+		s.Running = running
+	}
+
+	registered, err := config.HasInstance(id)
+	if err != nil {
+		t.Fatalf("readState() error reading config: \n%v", err)
+	}
+	s.Registered = registered
+
+	record, err := db.GetServer(context.Background(), id)
+	if err == nil {
+		s.Record = &record
+	} else if !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("readState() error reading database: \n%v", err)
+	}
+
+	return s
+}
+
+// injectFailure replaces the action of a step with one which fails, keeping its undo.
+func injectFailure(steps []jobs.Step, i int) []jobs.Step {
+	failing := append([]jobs.Step{}, steps...)
+	failing[i].Do = func(ctx context.Context) error {
+		return errors.New("injected failure")
+	}
+	return failing
+}
+
+var (
+	previous   types.Server  = types.Server{Name: "before", Size: "xs", Game: types.Game{Name: "minecraft_java", Modloader: "vanilla"}, Image: "itzg/minecraft-server@sha256:1111111111111111111111111111111111111111111111111111111111111111", Status: "running"}
+	next       types.Server  = types.Server{Name: "after", Size: "s", Game: types.Game{Name: "minecraft_java", Modloader: "forge"}, Image: "itzg/minecraft-server@sha256:2222222222222222222222222222222222222222222222222222222222222222", Status: "running"}
+	testSchema schema.Schema = schema.Schema{Name: "minecraft_java", Image: "itzg/minecraft-server:latest"}
+)
+
+// setupExisting records a running instance of the previous server.
+func setupExisting(t *testing.T, runtime *enginetest.Fake, id string) {
+	steps := Create(runtime, id, Spec{Server: previous})
+	if err := jobs.RunSteps(context.Background(), steps); err != nil {
+		t.Fatalf("setupExisting() error creating instance: \n%v", err)
+	}
+}
+
+// TestCreate calls Create with a failure injected at each step,
+// checking the instance is fully created, or nothing is left behind.
+func TestCreate(t *testing.T) {
+	want := state{Workload: &next, Running: true, Registered: true, Record: &next}
+	steps := len(Create(enginetest.NewFake(), "00000001", Spec{}))
+
+	for i := -1; i < steps; i++ {
+		configtest.UseLocalStorage(t)
+		runtime := enginetest.NewFake()
+
+		create := Create(runtime, "00000001", Spec{Server: next})
+		if i >= 0 {
+			create = injectFailure(create, i)
+			want = state{}
+		}
+		err := jobs.RunSteps(context.Background(), create)
+
+		if (err != nil) != (i >= 0) {
+			t.Fatalf("Create() failing at step %v returned error %v", i, err)
+		}
+		if diff := cmp.Diff(want, readState(t, runtime, "00000001")); diff != "" {
+			t.Fatalf("Create() failing at step %v mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+// TestUpdate calls Update with a failure injected at each step,
+// checking the instance is fully updated, or left as it was.
+func TestUpdate(t *testing.T) {
+	want := state{Workload: &next, Running: true, Registered: true, Record: &next}
+	steps := len(Update(enginetest.NewFake(), "00000001", Spec{}, Spec{}))
+
+	for i := -1; i < steps; i++ {
+		configtest.UseLocalStorage(t)
+		runtime := enginetest.NewFake()
+		setupExisting(t, runtime, "00000001")
+
+		update := Update(runtime, "00000001", Spec{Server: previous}, Spec{Server: next})
+		if i >= 0 {
+			update = injectFailure(update, i)
+			want = state{Workload: &previous, Running: true, Registered: true, Record: &previous}
+		}
+		err := jobs.RunSteps(context.Background(), update)
+
+		if (err != nil) != (i >= 0) {
+			t.Fatalf("Update() failing at step %v returned error %v", i, err)
+		}
+		if diff := cmp.Diff(want, readState(t, runtime, "00000001")); diff != "" {
+			t.Fatalf("Update() failing at step %v mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+// TestCreateResolves calls Create with a server which is not pinned to an image,
+// checking the workload and record are pinned to the digest the image resolved to.
+func TestCreateResolves(t *testing.T) {
+	configtest.UseLocalStorage(t)
+	runtime := enginetest.NewFake()
+	runtime.Publish(testSchema.Image, "sha256:3333333333333333333333333333333333333333333333333333333333333333")
+
+	unpinned := next
+	unpinned.Image = ""
+	if err := jobs.RunSteps(context.Background(), Create(runtime, "00000001", Spec{Schema: testSchema, Server: unpinned})); err != nil {
+		t.Fatalf("Create() returned an error: \n%v", err)
+	}
+
+	pinned := next
+	pinned.Image = "itzg/minecraft-server@sha256:3333333333333333333333333333333333333333333333333333333333333333"
+	want := state{Workload: &pinned, Running: true, Registered: true, Record: &pinned}
+	if diff := cmp.Diff(want, readState(t, runtime, "00000001")); diff != "" {
+		t.Fatalf("Create() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestUpdateImage calls UpdateImage after a new image was published to the tag of the schema,
+// checking the instance is pinned to the new digest, or left on the old one if it fails.
+func TestUpdateImage(t *testing.T) {
+	configtest.UseLocalStorage(t)
+	runtime := enginetest.NewFake()
+	setupExisting(t, runtime, "00000001")
+	runtime.Publish(testSchema.Image, "sha256:4444444444444444444444444444444444444444444444444444444444444444")
+	current := Spec{Schema: testSchema, Server: previous}
+
+	runtime.Fail("Update", errors.New("injected failure"))
+	if err := jobs.RunSteps(context.Background(), UpdateImage(runtime, "00000001", current)); err == nil {
+		t.Fatalf("UpdateImage() expected the injected error, got %v", err)
+	}
+	want := state{Workload: &previous, Running: true, Registered: true, Record: &previous}
+	if diff := cmp.Diff(want, readState(t, runtime, "00000001")); diff != "" {
+		t.Fatalf("UpdateImage() failing mismatch (-want +got):\n%s", diff)
+	}
+
+	runtime.Fail("Update", nil)
+	if err := jobs.RunSteps(context.Background(), UpdateImage(runtime, "00000001", current)); err != nil {
+		t.Fatalf("UpdateImage() returned an error: \n%v", err)
+	}
+	updated := previous
+	updated.Image = "itzg/minecraft-server@sha256:4444444444444444444444444444444444444444444444444444444444444444"
+	want = state{Workload: &updated, Running: true, Registered: true, Record: &updated}
+	if diff := cmp.Diff(want, readState(t, runtime, "00000001")); diff != "" {
+		t.Fatalf("UpdateImage() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestRemove calls Remove with a failure injected at each step,
+// checking the instance is fully removed, or left running as it was.
+func TestRemove(t *testing.T) {
+	want := state{}
+	steps := len(Remove(enginetest.NewFake(), "00000001", nil))
+
+	for i := -1; i < steps; i++ {
+		configtest.UseLocalStorage(t)
+		runtime := enginetest.NewFake()
+		setupExisting(t, runtime, "00000001")
+
+		stored := previous
+		remove := Remove(runtime, "00000001", &stored)
+		if i >= 0 {
+			remove = injectFailure(remove, i)
+			want = state{Workload: &previous, Running: true, Registered: true, Record: &previous}
+		}
+		err := jobs.RunSteps(context.Background(), remove)
+
+		if (err != nil) != (i >= 0) {
+			t.Fatalf("Remove() failing at step %v returned error %v", i, err)
+		}
+		if diff := cmp.Diff(want, readState(t, runtime, "00000001")); diff != "" {
+			t.Fatalf("Remove() failing at step %v mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+// TestStartStop calls Stop and Start with a failure injected at each step,
+// checking the workload and its desired status change together, or not at all.
+func TestStartStop(t *testing.T) {
+	stopped := previous
+	stopped.Status = types.StateStopped
+	steps := len(Stop(enginetest.NewFake(), "00000001"))
+
+	for i := -1; i < steps; i++ {
+		configtest.UseLocalStorage(t)
+		runtime := enginetest.NewFake()
+		setupExisting(t, runtime, "00000001")
+
+		want := state{Workload: &previous, Running: false, Registered: true, Record: &stopped}
+		stop := Stop(runtime, "00000001")
+		if i >= 0 {
+			stop = injectFailure(stop, i)
+			want = state{Workload: &previous, Running: true, Registered: true, Record: &previous}
+		}
+		err := jobs.RunSteps(context.Background(), stop)
+
+		if (err != nil) != (i >= 0) {
+			t.Fatalf("Stop() failing at step %v returned error %v", i, err)
+		}
+		if diff := cmp.Diff(want, readState(t, runtime, "00000001")); diff != "" {
+			t.Fatalf("Stop() failing at step %v mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+
+	configtest.UseLocalStorage(t)
+	runtime := enginetest.NewFake()
+	setupExisting(t, runtime, "00000001")
+	if err := jobs.RunSteps(context.Background(), Stop(runtime, "00000001")); err != nil {
+		t.Fatalf("Stop() returned an error: \n%v", err)
+	}
+	if err := jobs.RunSteps(context.Background(), injectFailure(Start(runtime, "00000001", Spec{Schema: testSchema, Server: stopped}), 2)); err == nil {
+		t.Fatalf("Start() expected the injected error, got %v", err)
+	}
+	want := state{Workload: &previous, Running: false, Registered: true, Record: &stopped}
+	if diff := cmp.Diff(want, readState(t, runtime, "00000001")); diff != "" {
+		t.Fatalf("Start() failing at step 2 mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestWaitHealthy calls WaitHealthy on running and stopped workloads,
+// checking only the running workload is healthy.
+func TestWaitHealthy(t *testing.T) {
+	runtime := enginetest.NewFake()
+	runtime.Deploy(context.Background(), "running", schema.Schema{}, previous)
+	runtime.Deploy(context.Background(), "stopped", schema.Schema{}, previous)
+	runtime.Stop(context.Background(), "stopped")
+
+	if err := WaitHealthy(context.Background(), runtime, "running"); err != nil {
+		t.Fatalf("WaitHealthy() (running) returned an error: \n%v", err)
+	}
+	if err := WaitHealthy(context.Background(), runtime, "stopped"); err == nil {
+		t.Fatalf("WaitHealthy() (stopped) expected an error, got %v", err)
+	}
+	if err := WaitHealthy(context.Background(), runtime, "missing"); err == nil {
+		t.Fatalf("WaitHealthy() (missing) expected an error, got %v", err)
+	}
+}
+
+// TestRestart calls Restart on running and stopped workloads,
+// checking only the running workload is restarted.
+func TestRestart(t *testing.T) {
+	runtime := enginetest.NewFake()
+	runtime.Deploy(context.Background(), "running", schema.Schema{}, previous)
+	runtime.Deploy(context.Background(), "stopped", schema.Schema{}, previous)
+	runtime.Stop(context.Background(), "stopped")
+	runtime.Calls()
+
+	if err := jobs.RunSteps(context.Background(), Restart(runtime, "running", Spec{Schema: testSchema, Server: previous})); err != nil {
+		t.Fatalf("Restart() (running) returned an error: \n%v", err)
+	}
+	if err := jobs.RunSteps(context.Background(), Restart(runtime, "stopped", Spec{Schema: testSchema, Server: previous})); err != nil {
+		t.Fatalf("Restart() (stopped) returned an error: \n%v", err)
+	}
+
+	want := []string{"Stop running", "Start running"}
+	if diff := cmp.Diff(want, runtime.Calls()); diff != "" {
+		t.Fatalf("Restart() calls mismatch (-want +got):\n%s", diff)
+	}
+	if _, running, _ := runtime.Workload("stopped"); running {
+		t.Fatalf("Restart() started the stopped workload")
+	}
+}
+
+// TestUpdateProperties calls UpdateProperties changing a property written to a file and then one passed
+// as an environment variable, checking the file is rewritten and the workload restarted only for the file.
+func TestUpdateProperties(t *testing.T) {
+	configtest.UseLocalStorage(t)
+	runtime := enginetest.NewFake()
+	setupExisting(t, runtime, "00000001")
+	runtime.WriteFile("00000001", "/data/server.properties", []byte("#Minecraft server properties\nmotd=A Minecraft Server\nspawn-protection=16\n"))
+
+	propertiesSchema := testSchema
+	propertiesSchema.Properties = []schema.Property{
+		{Name: "pvp", Type: schema.PropertyBool, Env: "PVP"},
+		{Name: "spawn-protection", Type: schema.PropertyInt, File: "/data/server.properties", Key: "spawn-protection"},
+	}
+	before := Spec{Schema: propertiesSchema, Server: previous}
+	after := before
+	after.Server.Properties = map[string]string{"spawn-protection": "0"}
+	runtime.Calls()
+
+	if err := jobs.RunSteps(context.Background(), UpdateProperties(runtime, "00000001", before, after)); err != nil {
+		t.Fatalf("UpdateProperties() returned an error: \n%v", err)
+	}
+	content, _ := runtime.File("00000001", "/data/server.properties")
+	if diff := cmp.Diff("#Minecraft server properties\nmotd=A Minecraft Server\nspawn-protection=0\n", string(content)); diff != "" {
+		t.Fatalf("UpdateProperties() file mismatch (-want +got):\n%s", diff)
+	}
+	want := []string{"Update 00000001", "CopyTo 00000001", "Stop 00000001", "Start 00000001"}
+	if diff := cmp.Diff(want, runtime.Calls()); diff != "" {
+		t.Fatalf("UpdateProperties() calls mismatch (-want +got):\n%s", diff)
+	}
+
+	final := after
+	final.Server.Properties = map[string]string{"spawn-protection": "0", "pvp": "false"}
+	if err := jobs.RunSteps(context.Background(), UpdateProperties(runtime, "00000001", after, final)); err != nil {
+		t.Fatalf("UpdateProperties() (environment) returned an error: \n%v", err)
+	}
+	if diff := cmp.Diff([]string{"Update 00000001"}, runtime.Calls()); diff != "" {
+		t.Fatalf("UpdateProperties() (environment) calls mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(state{Workload: &final.Server, Running: true, Registered: true, Record: &final.Server}, readState(t, runtime, "00000001")); diff != "" {
+		t.Fatalf("UpdateProperties() (environment) mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestStartPending calls Restart and then Start on a stopped server with pending properties,
+// checking they are left pending until it is started, and then written and recorded.
+func TestStartPending(t *testing.T) {
+	configtest.UseLocalStorage(t)
+	runtime := enginetest.NewFake()
+	setupExisting(t, runtime, "00000001")
+	if err := jobs.RunSteps(context.Background(), Stop(runtime, "00000001")); err != nil {
+		t.Fatalf("Stop() returned an error: \n%v", err)
+	}
+	runtime.WriteFile("00000001", "/data/server.properties", []byte("#Minecraft server properties\nspawn-protection=16\n"))
+
+	propertiesSchema := testSchema
+	propertiesSchema.Properties = []schema.Property{
+		{Name: "pvp", Type: schema.PropertyBool, Env: "PVP"},
+		{Name: "spawn-protection", Type: schema.PropertyInt, File: "/data/server.properties", Key: "spawn-protection"},
+	}
+	pending := previous
+	pending.Status = types.StateStopped
+	pending.Pending = &types.Pending{Properties: map[string]string{"pvp": "false", "spawn-protection": "0"}}
+	if _, err := db.SetServer(context.Background(), "00000001", pending); err != nil {
+		t.Fatalf("SetServer() returned an error: \n%v", err)
+	}
+	current := Spec{Schema: propertiesSchema, Server: pending}
+	runtime.Calls()
+
+	if err := jobs.RunSteps(context.Background(), Restart(runtime, "00000001", current)); err != nil {
+		t.Fatalf("Restart() returned an error: \n%v", err)
+	}
+	if calls := runtime.Calls(); len(calls) != 0 {
+		t.Fatalf("Restart() of the stopped server made calls %v, want none", calls)
+	}
+
+	if err := jobs.RunSteps(context.Background(), Start(runtime, "00000001", current)); err != nil {
+		t.Fatalf("Start() returned an error: \n%v", err)
+	}
+	content, _ := runtime.File("00000001", "/data/server.properties")
+	if diff := cmp.Diff("#Minecraft server properties\nspawn-protection=0\n", string(content)); diff != "" {
+		t.Fatalf("Start() file mismatch (-want +got):\n%s", diff)
+	}
+	started := previous
+	started.Properties = map[string]string{"pvp": "false", "spawn-protection": "0"}
+	if diff := cmp.Diff(state{Workload: &started, Running: true, Registered: true, Record: &started}, readState(t, runtime, "00000001")); diff != "" {
+		t.Fatalf("Start() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,87 @@
+package modpack
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/RicochetStudios/aurora/types"
+)
+
+// curseForgeManifest is the file describing a CurseForge modpack, at the root of its archive.
+const curseForgeManifest string = "manifest.json"
+
+// curseForgePack is the manifest of a CurseForge modpack.
+type curseForgePack struct {
+	ManifestType    string `json:"manifestType"`
+	ManifestVersion int    `json:"manifestVersion"`
+	Name            string `json:"name"`
+	Version         string `json:"version"`
+	Minecraft       struct {
+		Version    string `json:"version"`
+		ModLoaders []struct {
+			ID      string `json:"id"` // The modloader and its version e.g. forge-47.2.0.
+			Primary bool   `json:"primary"`
+		} `json:"modLoaders"`
+	} `json:"minecraft"`
+	Files []struct {
+		ProjectID int  `json:"projectID"`
+		FileID    int  `json:"fileID"`
+		Required  bool `json:"required"`
+	} `json:"files"`
+	Overrides string `json:"overrides"`
+}
+
+// parseCurseForge reads a CurseForge modpack. Its mods are only given as CurseForge projects,
+// so they are listed as downloads without a path or URL.
+func parseCurseForge(reader *zip.Reader) (Pack, error) {
+	budget := maxExtractedSize
+	content, err := readFile(findFile(reader, curseForgeManifest), &budget)
+	if err != nil {
+		return Pack{}, err
+	}
+	var manifest curseForgePack
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return Pack{}, fmt.Errorf("error decoding %v: %v", curseForgeManifest, err)
+	}
+	if manifest.ManifestType != "minecraftModpack" {
+		return Pack{}, fmt.Errorf("manifest type %q is not supported", manifest.ManifestType)
+	}
+	if manifest.Minecraft.Version == "" {
+		return Pack{}, fmt.Errorf("%v has no minecraft version", curseForgeManifest)
+	}
+
+	pack := Pack{Modpack: types.Modpack{
+		Name:    manifest.Name,
+		Version: manifest.Version,
+		Game:    types.Game{Modloader: "vanilla", Version: manifest.Minecraft.Version},
+	}}
+	for i, loader := range manifest.Minecraft.ModLoaders {
+		if i == 0 || loader.Primary {
+			modloader, version, _ := strings.Cut(loader.ID, "-")
+			pack.Game.Modloader = modloader
+			pack.Game.ModloaderVersion = version
+		}
+	}
+
+	for _, file := range manifest.Files {
+		if !file.Required {
+			continue
+		}
+		pack.Downloads = append(pack.Downloads, types.ModpackDownload{
+			URLs:      []string{},
+			ProjectID: file.ProjectID,
+			FileID:    file.FileID,
+		})
+	}
+
+	overrides := manifest.Overrides
+	if overrides == "" {
+		overrides = "overrides"
+	}
+	if pack.Files, err = readOverrides(reader, &budget, strings.TrimSuffix(overrides, "/")); err != nil {
+		return Pack{}, err
+	}
+	return pack, nil
+}
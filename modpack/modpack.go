@@ -0,0 +1,318 @@
+package modpack
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/RicochetStudios/aurora/engine"
+	"github.com/RicochetStudios/aurora/jobs"
+	"github.com/RicochetStudios/aurora/mods"
+	"github.com/RicochetStudios/aurora/types"
+)
+
+const (
+	// FormatModrinth is the format of Modrinth (.mrpack) modpacks.
+	FormatModrinth string = "modrinth"
+
+	// FormatCurseForge is the format of CurseForge modpacks.
+	FormatCurseForge string = "curseforge"
+
+	// game is the game schema modpacks run on, as both formats are for Minecraft: Java Edition.
+	game string = "minecraft_java"
+
+	// maxExtractedSize is the most an archive may hold once decompressed, so a small upload can not fill the disk.
+	maxExtractedSize int64 = 1 << 30
+)
+
+// errUnknownFormat is returned for archives which are not a modpack of a known format.
+var errUnknownFormat error = errors.New("archive is not a Modrinth (.mrpack) or CurseForge modpack")
+
+// File is a file of a modpack archive, installed into the data volume of a server.
+// Its content stays in the archive, which is read again when it is installed.
+type File struct {
+	Path   string // Path of the file relative to the data volume e.g. config/jei.toml.
+	Size   int64  // Bytes the file extracts to.
+	SHA256 string // Hex encoded sha256 sum of the file.
+	zip    *zip.File
+}
+
+// Pack is a modpack read from an archive.
+type Pack struct {
+	types.Modpack
+	Files []File // Files of the archive to install into the data volume, in order of their paths.
+	file  *os.File
+}
+
+// Read spools a Modrinth (.mrpack) or CurseForge modpack archive to a temporary file, keeping the parts a server needs.
+// Files the archive only refers to by where to download them are listed, but not downloaded. Files to install are
+// read once to check they extract to at most maxExtractedSize bytes together. The pack must be closed to remove its file.
+func Read(r io.Reader) (*Pack, error) {
+	file, err := os.CreateTemp("", "aurora-modpack-*")
+	if err != nil {
+		return nil, fmt.Errorf("Read() error creating temporary file: %v", err)
+	}
+
+	pack, err := read(file, r)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("Read() %w", err)
+	}
+	pack.file = file
+	return pack, nil
+}
+
+// read spools an archive to a file and reads the modpack in it.
+func read(file *os.File, r io.Reader) (*Pack, error) {
+	size, err := io.Copy(file, r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading archive: %v", err)
+	}
+	reader, err := zip.NewReader(file, size)
+	if err != nil {
+		return nil, fmt.Errorf("error reading archive: %v", err)
+	}
+
+	var pack Pack
+	var format string
+	switch {
+	case findFile(reader, modrinthIndex) != nil:
+		format = FormatModrinth
+		pack, err = parseModrinth(reader)
+	case findFile(reader, curseForgeManifest) != nil:
+		format = FormatCurseForge
+		pack, err = parseCurseForge(reader)
+	default:
+		return nil, errUnknownFormat
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %v modpack: \n%v", format, err)
+	}
+
+	pack.Format = format
+	pack.Game.Name = game
+	pack.Modpack.Files = make([]string, len(pack.Files))
+	for i, file := range pack.Files {
+		pack.Modpack.Files[i] = file.Path
+	}
+	if pack.Downloads == nil {
+		pack.Downloads = []types.ModpackDownload{}
+	}
+	return &pack, nil
+}
+
+// Close removes the archive of a pack.
+func (p *Pack) Close() error {
+	p.file.Close()
+	return os.Remove(p.file.Name())
+}
+
+// HasJars returns whether the pack installs any jars, which only a modloader that loads mods can use.
+func (p *Pack) HasJars() bool {
+	for _, file := range p.Files {
+		if strings.HasSuffix(file.Path, ".jar") {
+			return true
+		}
+	}
+	return false
+}
+
+// Server returns a server running the game of a modpack, named after the modpack unless it already has a name.
+func (p Pack) Server(server types.Server) types.Server {
+	server.Game = p.Game
+	if server.Name == "" {
+		server.Name = p.Name
+	}
+	return server
+}
+
+// Install returns the steps which copy the files of a modpack into the data volume of an instance,
+// and record the jars it adds to the mods directory in the mods manifest of the instance.
+// The files are streamed from the archive of the pack, which must stay open until the steps have run.
+// ModsDir is empty if the modloader of the instance loads no mods. If any step fails, the files are deleted again.
+func Install(runtime engine.Runtime, id string, dataDir string, modsDir string, files []File) []jobs.Step {
+	jars := map[string]types.Mod{}
+	if modsDir != "" {
+		relative := strings.TrimPrefix(strings.TrimPrefix(modsDir, dataDir), "/")
+		for _, file := range files {
+			name := path.Base(file.Path)
+			if path.Dir(file.Path) == relative && mods.ValidName(name) == nil {
+				jars[name] = mods.NewMod(name, file.SHA256, file.Size)
+			}
+		}
+	}
+	var copied bool
+
+	return []jobs.Step{
+		{
+			Name: "installing",
+			Do: func(ctx context.Context) error {
+				reader, writer := io.Pipe()
+				go func() {
+					writer.CloseWithError(writeTar(writer, files))
+				}()
+				// Closing the reader stops the writer, if the runtime gives up before reading all of it.
+				defer reader.Close()
+				copied = true
+				return runtime.CopyTo(ctx, id, dataDir, reader)
+			},
+			Undo: func(ctx context.Context) error {
+				if !copied || len(files) == 0 {
+					return nil
+				}
+				command := []string{"rm", "-f"}
+				for _, file := range files {
+					command = append(command, path.Join(dataDir, file.Path))
+				}
+				result, err := runtime.Exec(ctx, id, command)
+				if err != nil {
+					return err
+				}
+				if result.ExitCode != 0 {
+					return fmt.Errorf("rm exited with code %v: %v", result.ExitCode, result.Output)
+				}
+				return nil
+			},
+		},
+		mods.Record(id, jars),
+	}
+}
+
+// findFile returns the file of an archive with a name, or nil if it has none.
+func findFile(reader *zip.Reader, name string) *zip.File {
+	for _, file := range reader.File {
+		if file.Name == name {
+			return file
+		}
+	}
+	return nil
+}
+
+// readFile reads a file of an archive, counting its size against the budget of the whole archive.
+func readFile(file *zip.File, budget *int64) ([]byte, error) {
+	opened, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("error opening %v: %v", file.Name, err)
+	}
+	defer opened.Close()
+
+	content, err := io.ReadAll(io.LimitReader(opened, *budget+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading %v: %v", file.Name, err)
+	}
+	*budget -= int64(len(content))
+	if *budget < 0 {
+		return nil, fmt.Errorf("archive holds more than %v bytes", maxExtractedSize)
+	}
+	return content, nil
+}
+
+// readOverrides lists the files of an archive under override directories, which are copied over the data volume.
+// Files of later directories replace files of earlier ones with the same path. Each file is read to find its sum,
+// counting its size against the budget of the whole archive.
+func readOverrides(reader *zip.Reader, budget *int64, dirs ...string) ([]File, error) {
+	files := map[string]File{}
+	for _, dir := range dirs {
+		for _, file := range reader.File {
+			name, found := strings.CutPrefix(file.Name, dir+"/")
+			if !found || file.FileInfo().IsDir() || name == "" {
+				continue
+			}
+			clean, err := safePath(name)
+			if err != nil {
+				return nil, err
+			}
+			if files[clean], err = sumFile(file, clean, budget); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	overrides := make([]File, len(paths))
+	for i, p := range paths {
+		overrides[i] = files[p]
+	}
+	return overrides, nil
+}
+
+// sumFile reads a file of an archive to find its sum and size, counting its size against the budget of the whole archive.
+func sumFile(file *zip.File, p string, budget *int64) (File, error) {
+	opened, err := file.Open()
+	if err != nil {
+		return File{}, fmt.Errorf("error opening %v: %v", file.Name, err)
+	}
+	defer opened.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, io.LimitReader(opened, *budget+1))
+	if err != nil {
+		return File{}, fmt.Errorf("error reading %v: %v", file.Name, err)
+	}
+	*budget -= size
+	if *budget < 0 {
+		return File{}, fmt.Errorf("archive holds more than %v bytes", maxExtractedSize)
+	}
+	return File{Path: p, Size: size, SHA256: hex.EncodeToString(hash.Sum(nil)), zip: file}, nil
+}
+
+// safePath cleans the path of a file of a modpack, returning an error if it would leave the data volume.
+func safePath(p string) (string, error) {
+	clean := path.Clean(p)
+	if strings.Contains(p, "\\") || path.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("file path %q is outside the server directory", p)
+	}
+	return clean, nil
+}
+
+// writeTar writes a tar archive of files, read from the archive of their modpack, to be extracted into the data volume.
+// Directories have no entries, so existing directories keep their owners and permissions.
+func writeTar(w io.Writer, files []File) error {
+	tw := tar.NewWriter(w)
+	for _, file := range files {
+		header := &tar.Header{
+			Name:     file.Path,
+			Mode:     0644,
+			Size:     file.Size,
+			Typeflag: tar.TypeReg,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("error writing archive header: %v", err)
+		}
+		if err := copyFile(tw, file); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("error closing archive: %v", err)
+	}
+	return nil
+}
+
+// copyFile copies the content of a file of a modpack archive.
+func copyFile(w io.Writer, file File) error {
+	opened, err := file.zip.Open()
+	if err != nil {
+		return fmt.Errorf("error opening %v: %v", file.Path, err)
+	}
+	defer opened.Close()
+	if _, err := io.Copy(w, opened); err != nil {
+		return fmt.Errorf("error writing %v: %v", file.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package modpack
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/RicochetStudios/aurora/config/configtest"
+	"github.com/RicochetStudios/aurora/db"
+	"github.com/RicochetStudios/aurora/engine/enginetest"
+	"github.com/RicochetStudios/aurora/jobs"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// readArchive calls Read with a zip archive of files, by name, removing the pack once the test finishes.
+func readArchive(t *testing.T, files map[string]string) (*Pack, error) {
+	pack, err := Read(bytes.NewReader(archive(t, files)))
+	if err == nil {
+		t.Cleanup(func() { pack.Close() })
+	}
+	return pack, err
+}
+
+// testFile returns a file of a modpack with its content, as Read lists it.
+func testFile(p string, content string) File {
+	sum := sha256.Sum256([]byte(content))
+	return File{Path: p, Size: int64(len(content)), SHA256: hex.EncodeToString(sum[:])}
+}
+
+// archive returns a zip archive of files, by name.
+func archive(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("archive() error creating %v: %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("archive() error writing %v: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("archive() error closing: %v", err)
+	}
+	return buf.Bytes()
+}
+
+const testModrinthIndex string = `{
+	"formatVersion": 1,
+	"game": "minecraft",
+	"versionId": "2.1.0",
+	"name": "Cosy Pack",
+	"files": [
+		{
+			"path": "mods/sodium.jar",
+			"hashes": {"sha1": "aa", "sha512": "bb"},
+			"env": {"client": "required", "server": "unsupported"},
+			"downloads": ["https://cdn.modrinth.com/sodium.jar"],
+			"fileSize": 10
+		},
+		{
+			"path": "mods/lithium.jar",
+			"hashes": {"sha1": "cc", "sha512": "dd"},
+			"env": {"client": "optional", "server": "required"},
+			"downloads": ["https://cdn.modrinth.com/lithium.jar"],
+			"fileSize": 20
+		}
+	],
+	"dependencies": {"minecraft": "1.20.1", "fabric-loader": "0.14.21"}
+}`
+
+// TestReadModrinth calls Read with a Modrinth modpack,
+// checking the game, server side downloads and overrides are read, with server overrides taking precedence.
+func TestReadModrinth(t *testing.T) {
+	pack, err := readArchive(t, map[string]string{
+		"modrinth.index.json":                  testModrinthIndex,
+		"overrides/config/lithium.toml":        "shared",
+		"overrides/mods/bundled.jar":           "bundled",
+		"server-overrides/config/lithium.toml": "server",
+		"client-overrides/options.txt":         "client",
+	})
+	if err != nil {
+		t.Fatalf("Read() returned an error: \n%v", err)
+	}
+
+	want := Pack{
+		Modpack: types.Modpack{
+			Format:  FormatModrinth,
+			Name:    "Cosy Pack",
+			Version: "2.1.0",
+			Game:    types.Game{Name: "minecraft_java", Modloader: "fabric", Version: "1.20.1", ModloaderVersion: "0.14.21"},
+			Files:   []string{"config/lithium.toml", "mods/bundled.jar"},
+			Downloads: []types.ModpackDownload{
+				{Path: "mods/lithium.jar", URLs: []string{"https://cdn.modrinth.com/lithium.jar"}, SHA512: "dd", Size: 20},
+			},
+		},
+		Files: []File{
+			testFile("config/lithium.toml", "server"),
+			testFile("mods/bundled.jar", "bundled"),
+		},
+	}
+	if diff := cmp.Diff(&want, pack, cmpopts.IgnoreUnexported(Pack{}, File{})); diff != "" {
+		t.Fatalf("Read() mismatch (-want +got):\n%s", diff)
+	}
+	if !pack.HasJars() {
+		t.Fatalf("HasJars() = false, want true for the bundled jar")
+	}
+}
+
+// TestReadCurseForge calls Read with a CurseForge modpack,
+// checking the primary modloader, required projects and overrides are read.
+func TestReadCurseForge(t *testing.T) {
+	pack, err := readArchive(t, map[string]string{
+		"manifest.json": `{
+			"manifestType": "minecraftModpack",
+			"manifestVersion": 1,
+			"name": "Big Pack",
+			"version": "1.0",
+			"minecraft": {"version": "1.20.1", "modLoaders": [{"id": "forge-47.2.0", "primary": true}]},
+			"files": [{"projectID": 1, "fileID": 2, "required": true}, {"projectID": 3, "fileID": 4, "required": false}],
+			"overrides": "extras"
+		}`,
+		"extras/config/jei.toml": "jei",
+		"modlist.html":           "<ul></ul>",
+	})
+	if err != nil {
+		t.Fatalf("Read() returned an error: \n%v", err)
+	}
+
+	want := Pack{
+		Modpack: types.Modpack{
+			Format:    FormatCurseForge,
+			Name:      "Big Pack",
+			Version:   "1.0",
+			Game:      types.Game{Name: "minecraft_java", Modloader: "forge", Version: "1.20.1", ModloaderVersion: "47.2.0"},
+			Files:     []string{"config/jei.toml"},
+			Downloads: []types.ModpackDownload{{URLs: []string{}, ProjectID: 1, FileID: 2}},
+		},
+		Files: []File{testFile("config/jei.toml", "jei")},
+	}
+	if diff := cmp.Diff(&want, pack, cmpopts.IgnoreUnexported(Pack{}, File{})); diff != "" {
+		t.Fatalf("Read() mismatch (-want +got):\n%s", diff)
+	}
+	if pack.HasJars() {
+		t.Fatalf("HasJars() = true, want false without jars")
+	}
+}
+
+// TestReadInvalid calls Read with archives which are not modpacks or would write outside the server,
+// checking each is rejected.
+func TestReadInvalid(t *testing.T) {
+	tests := map[string][]byte{
+		"not a zip":  []byte("jar"),
+		"no index":   archive(t, map[string]string{"readme.txt": "hi"}),
+		"bad index":  archive(t, map[string]string{"modrinth.index.json": "{"}),
+		"other game": archive(t, map[string]string{"modrinth.index.json": `{"formatVersion": 1, "game": "terraria", "dependencies": {"minecraft": "1.20.1"}}`}),
+		"escaping override": archive(t, map[string]string{
+			"modrinth.index.json":        `{"formatVersion": 1, "game": "minecraft", "dependencies": {"minecraft": "1.20.1"}}`,
+			"overrides/../../etc/passwd": "root",
+		}),
+		"escaping download": archive(t, map[string]string{
+			"modrinth.index.json": `{"formatVersion": 1, "game": "minecraft", "files": [{"path": "/etc/passwd"}], "dependencies": {"minecraft": "1.20.1"}}`,
+		}),
+	}
+
+	for name, content := range tests {
+		if _, err := Read(bytes.NewReader(content)); err == nil {
+			t.Fatalf("Read() (%v) expected an error, got %v", name, err)
+		}
+	}
+}
+
+// TestInstall calls Install with the files of a modpack,
+// checking they are copied into the data volume and the jars of the mods directory are recorded.
+func TestInstall(t *testing.T) {
+	configtest.UseLocalStorage(t)
+	ctx := context.Background()
+	runtime := enginetest.NewFake()
+	if err := runtime.Deploy(ctx, "00000001", schema.Schema{}, types.Server{Name: "myserver"}); err != nil {
+		t.Fatalf("Deploy() returned an error: \n%v", err)
+	}
+
+	pack, err := readArchive(t, map[string]string{
+		"modrinth.index.json":       `{"formatVersion": 1, "game": "minecraft", "dependencies": {"minecraft": "1.20.1"}}`,
+		"overrides/config/jei.toml": "config",
+		"overrides/mods/jei.jar":    "jei",
+	})
+	if err != nil {
+		t.Fatalf("Read() returned an error: \n%v", err)
+	}
+	if err := jobs.RunSteps(ctx, Install(runtime, "00000001", "/data", "/data/mods", pack.Files)); err != nil {
+		t.Fatalf("Install() returned an error: \n%v", err)
+	}
+
+	for p, want := range map[string]string{"config/jei.toml": "config", "mods/jei.jar": "jei"} {
+		if content, ok := runtime.File("00000001", "/data/"+p); !ok || string(content) != want {
+			t.Fatalf("Install() file %v = %q, %v, want %q", p, content, ok, want)
+		}
+	}
+	manifest, err := db.GetMods(ctx, "00000001")
+	if err != nil {
+		t.Fatalf("GetMods() returned an error: \n%v", err)
+	}
+	if len(manifest.Mods) != 1 || manifest.Mods[0].Name != "jei.jar" || !manifest.Mods[0].Enabled || manifest.Mods[0].SHA256 != testFile("", "jei").SHA256 {
+		t.Fatalf("Install() recorded mods %+v, want jei.jar", manifest.Mods)
+	}
+}
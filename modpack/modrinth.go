@@ -0,0 +1,94 @@
+package modpack
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+
+	"github.com/RicochetStudios/aurora/types"
+)
+
+// modrinthIndex is the file describing a Modrinth modpack, at the root of its archive.
+const modrinthIndex string = "modrinth.index.json"
+
+// modrinthLoaders are the modloaders of Modrinth dependencies, by dependency id.
+var modrinthLoaders map[string]string = map[string]string{
+	"forge":         "forge",
+	"neoforge":      "neoforge",
+	"fabric-loader": "fabric",
+	"quilt-loader":  "quilt",
+}
+
+// modrinthPack is the index of a Modrinth modpack, following https://support.modrinth.com/en/articles/8802351-modrinth-modpack-format-mrpack.
+type modrinthPack struct {
+	FormatVersion int    `json:"formatVersion"`
+	Game          string `json:"game"`
+	VersionID     string `json:"versionId"`
+	Name          string `json:"name"`
+	Files         []struct {
+		Path   string            `json:"path"`
+		Hashes map[string]string `json:"hashes"`
+		Env    *struct {
+			Server string `json:"server"`
+		} `json:"env"`
+		Downloads []string `json:"downloads"`
+		FileSize  int64    `json:"fileSize"`
+	} `json:"files"`
+	Dependencies map[string]string `json:"dependencies"`
+}
+
+// parseModrinth reads a Modrinth modpack. Files for clients only are skipped, and the server overrides
+// replace the overrides of every side.
+func parseModrinth(reader *zip.Reader) (Pack, error) {
+	budget := maxExtractedSize
+	content, err := readFile(findFile(reader, modrinthIndex), &budget)
+	if err != nil {
+		return Pack{}, err
+	}
+	var index modrinthPack
+	if err := json.Unmarshal(content, &index); err != nil {
+		return Pack{}, fmt.Errorf("error decoding %v: %v", modrinthIndex, err)
+	}
+	if index.FormatVersion != 1 {
+		return Pack{}, fmt.Errorf("format version %v is not supported", index.FormatVersion)
+	}
+	if index.Game != "minecraft" {
+		return Pack{}, fmt.Errorf("game %q is not supported", index.Game)
+	}
+
+	pack := Pack{Modpack: types.Modpack{
+		Name:    index.Name,
+		Version: index.VersionID,
+		Game:    types.Game{Modloader: "vanilla", Version: index.Dependencies["minecraft"]},
+	}}
+	if pack.Game.Version == "" {
+		return Pack{}, fmt.Errorf("%v has no minecraft dependency", modrinthIndex)
+	}
+	for id, version := range index.Dependencies {
+		if modloader, ok := modrinthLoaders[id]; ok {
+			pack.Game.Modloader = modloader
+			pack.Game.ModloaderVersion = version
+		}
+	}
+
+	for _, file := range index.Files {
+		if file.Env != nil && file.Env.Server == "unsupported" {
+			continue
+		}
+		clean, err := safePath(file.Path)
+		if err != nil {
+			return Pack{}, err
+		}
+		pack.Downloads = append(pack.Downloads, types.ModpackDownload{
+			Path:   clean,
+			URLs:   file.Downloads,
+			SHA512: file.Hashes["sha512"],
+			Size:   file.FileSize,
+		})
+	}
+
+	if pack.Files, err = readOverrides(reader, &budget, "overrides", "server-overrides"); err != nil {
+		return Pack{}, err
+	}
+	return pack, nil
+}
@@ -0,0 +1,266 @@
+package mods
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/RicochetStudios/aurora/db"
+	"github.com/RicochetStudios/aurora/engine"
+	"github.com/RicochetStudios/aurora/jobs"
+	"github.com/RicochetStudios/aurora/types"
+)
+
+// disabledSuffix is added to the file name of a disabled jar, so the modloader skips it.
+const disabledSuffix string = ".disabled"
+
+// namePattern matches the file names of jars which may be added, which can not leave the mods directory.
+var namePattern *regexp.Regexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+\- ]*\.jar$`)
+
+// now is the clock the manifest records times with.
+var now func() time.Time = time.Now
+
+// ValidName returns an error if a file name is not one a mod may be added with.
+func ValidName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("mod name %q must be a .jar file name of letters, numbers, spaces and ._+-", name)
+	}
+	return nil
+}
+
+// Find returns the mod of a manifest with a name.
+func Find(manifest types.ModManifest, name string) (types.Mod, bool) {
+	if i := index(manifest, name); i >= 0 {
+		return manifest.Mods[i], true
+	}
+	return types.Mod{}, false
+}
+
+// Add returns the steps which copy a jar into the mods directory of an instance, enabled,
+// and record it in the manifest of the instance. A mod which has already been added must be removed first.
+// If recording fails, the jar is deleted again.
+func Add(runtime engine.Runtime, id string, dir string, name string, content []byte) []jobs.Step {
+	var copied bool
+
+	return []jobs.Step{
+		{
+			Name: "uploading",
+			Do: func(ctx context.Context) error {
+				// The jar of an added mod must not be overwritten, as it could not be restored.
+				manifest, err := db.GetMods(ctx, id)
+				if err != nil {
+					return err
+				}
+				if _, ok := Find(manifest, name); ok {
+					return fmt.Errorf("mod %v has already been added", name)
+				}
+
+				archive, err := jarArchive(path.Base(dir), name, content)
+				if err != nil {
+					return err
+				}
+				copied = true
+				return runtime.CopyTo(ctx, id, path.Dir(dir), archive)
+			},
+			Undo: func(ctx context.Context) error {
+				if !copied {
+					return nil
+				}
+				return run(ctx, runtime, id, "rm", "-f", path.Join(dir, name))
+			},
+		},
+		manifestStep(id, func(manifest *types.ModManifest) error {
+			if index(*manifest, name) >= 0 {
+				return fmt.Errorf("mod %v has already been added", name)
+			}
+			manifest.Mods = append(manifest.Mods, newMod(name, content))
+			return nil
+		}),
+	}
+}
+
+// Record returns the step which records jars already copied into the mods directory of an instance,
+// such as those installed from a modpack, replacing the records of mods with the same names.
+func Record(id string, jars map[string]types.Mod) jobs.Step {
+	names := make([]string, 0, len(jars))
+	for name := range jars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return manifestStep(id, func(manifest *types.ModManifest) error {
+		for _, name := range names {
+			mod := jars[name]
+			if i := index(*manifest, name); i >= 0 {
+				manifest.Mods[i] = mod
+			} else {
+				manifest.Mods = append(manifest.Mods, mod)
+			}
+		}
+		return nil
+	})
+}
+
+// SetEnabled returns the steps which enable or disable a mod of an instance, by renaming its jar
+// so the modloader loads or skips it, and record the change in the manifest of the instance.
+func SetEnabled(runtime engine.Runtime, id string, dir string, name string, enabled bool) []jobs.Step {
+	from, to := path.Join(dir, name), path.Join(dir, name+disabledSuffix)
+	if enabled {
+		from, to = to, from
+	}
+	var changed bool
+
+	return []jobs.Step{
+		{
+			Name: "renaming",
+			Do: func(ctx context.Context) error {
+				manifest, err := db.GetMods(ctx, id)
+				if err != nil {
+					return err
+				}
+				mod, ok := Find(manifest, name)
+				if !ok {
+					return fmt.Errorf("mod %v has not been added", name)
+				}
+				if changed = mod.Enabled != enabled; !changed {
+					return nil
+				}
+				return run(ctx, runtime, id, "mv", from, to)
+			},
+			Undo: func(ctx context.Context) error {
+				if !changed {
+					return nil
+				}
+				return run(ctx, runtime, id, "mv", to, from)
+			},
+		},
+		manifestStep(id, func(manifest *types.ModManifest) error {
+			i := index(*manifest, name)
+			if i < 0 {
+				return fmt.Errorf("mod %v has not been added", name)
+			}
+			manifest.Mods[i].Enabled = enabled
+			return nil
+		}),
+	}
+}
+
+// Remove returns the steps which delete a mod from the manifest of an instance and then its jar,
+// whether it is enabled or not. The jar can not be restored, so is only deleted once the manifest has changed.
+func Remove(runtime engine.Runtime, id string, dir string, name string) []jobs.Step {
+	return []jobs.Step{
+		manifestStep(id, func(manifest *types.ModManifest) error {
+			i := index(*manifest, name)
+			if i < 0 {
+				return fmt.Errorf("mod %v has not been added", name)
+			}
+			manifest.Mods = append(manifest.Mods[:i], manifest.Mods[i+1:]...)
+			return nil
+		}),
+		{
+			Name: "deleting",
+			Do: func(ctx context.Context) error {
+				return run(ctx, runtime, id, "rm", "-f", path.Join(dir, name), path.Join(dir, name+disabledSuffix))
+			},
+		},
+	}
+}
+
+// manifestStep returns the step which changes the manifest of an instance, restoring it if a later step fails.
+func manifestStep(id string, change func(manifest *types.ModManifest) error) jobs.Step {
+	var previous types.ModManifest
+
+	return jobs.Step{
+		Name: "recording",
+		Do: func(ctx context.Context) error {
+			manifest, err := db.GetMods(ctx, id)
+			if err != nil {
+				return err
+			}
+			previous = manifest
+
+			manifest.Mods = append([]types.Mod{}, manifest.Mods...)
+			if err := change(&manifest); err != nil {
+				return err
+			}
+			manifest.UpdatedAt = now().UTC()
+			_, err = db.SetMods(ctx, manifest)
+			return err
+		},
+		Undo: func(ctx context.Context) error {
+			_, err := db.SetMods(ctx, previous)
+			return err
+		},
+	}
+}
+
+// newMod returns the record of an enabled jar added now.
+func newMod(name string, content []byte) types.Mod {
+	sum := sha256.Sum256(content)
+	return NewMod(name, hex.EncodeToString(sum[:]), int64(len(content)))
+}
+
+// NewMod returns the record of an enabled mod added now, whose jar has a hex encoded sha256 sum and size.
+func NewMod(name string, sum string, size int64) types.Mod {
+	return types.Mod{
+		Name:    name,
+		SHA256:  sum,
+		Size:    size,
+		Enabled: true,
+		AddedAt: now().UTC(),
+	}
+}
+
+// index returns the position of the mod with a name in a manifest, or -1 if it has not been added.
+func index(manifest types.ModManifest, name string) int {
+	for i, mod := range manifest.Mods {
+		if mod.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// jarArchive returns a tar archive holding a jar inside a directory, to be extracted into the parent of the directory.
+// The directory itself has no entry, so an existing directory keeps its owner and permissions.
+func jarArchive(dir string, name string, content []byte) (*bytes.Buffer, error) {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+
+	header := &tar.Header{
+		Name:     path.Join(dir, name),
+		Mode:     0644,
+		Size:     int64(len(content)),
+		ModTime:  now(),
+		Typeflag: tar.TypeReg,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return nil, fmt.Errorf("error writing archive header: %v", err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		return nil, fmt.Errorf("error writing archive: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("error closing archive: %v", err)
+	}
+	return &archive, nil
+}
+
+// run executes a command in the workload of an instance, returning its output as an error if it fails.
+func run(ctx context.Context, runtime engine.Runtime, id string, command ...string) error {
+	result, err := runtime.Exec(ctx, id, command)
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("%v exited with code %v: %v", command[0], result.ExitCode, result.Output)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package mods
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RicochetStudios/aurora/config/configtest"
+	"github.com/RicochetStudios/aurora/db"
+	"github.com/RicochetStudios/aurora/engine/enginetest"
+	"github.com/RicochetStudios/aurora/jobs"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// setup returns a runtime with a deployed workload, and a fixed clock.
+func setup(t *testing.T) *enginetest.Fake {
+	configtest.UseLocalStorage(t)
+
+	fixed := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	now = func() time.Time { return fixed }
+	t.Cleanup(func() { now = time.Now })
+
+	runtime := enginetest.NewFake()
+	if err := runtime.Deploy(context.Background(), "00000001", schema.Schema{}, types.Server{Name: "myserver"}); err != nil {
+		t.Fatalf("setup() error deploying workload: \n%v", err)
+	}
+	return runtime
+}
+
+// manifest reads the names and enabled state of the mods of the instance.
+func manifest(t *testing.T) map[string]bool {
+	stored, err := db.GetMods(context.Background(), "00000001")
+	if err != nil {
+		t.Fatalf("GetMods() returned an error: \n%v", err)
+	}
+	mods := map[string]bool{}
+	for _, mod := range stored.Mods {
+		mods[mod.Name] = mod.Enabled
+	}
+	return mods
+}
+
+// TestValidName calls ValidName with file names,
+// checking only jar names which stay in the mods directory are accepted.
+func TestValidName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"jei-1.20.1-forge-15.2.0.27.jar", true},
+		{"Create 0.5.1.jar", true},
+		{"mod.zip", false},
+		{"../server.jar", false},
+		{"mods/inner.jar", false},
+		{".hidden.jar", false},
+		{"", false},
+	}
+
+	for _, tc := range tests {
+		if err := ValidName(tc.name); (err == nil) != tc.valid {
+			t.Fatalf("ValidName(%q) returned %v, want valid %v", tc.name, err, tc.valid)
+		}
+	}
+}
+
+// TestAdd calls Add, checking the jar is copied into the mods directory
+// and recorded with its hash, and that adding it again fails without changing it.
+func TestAdd(t *testing.T) {
+	runtime := setup(t)
+	ctx := context.Background()
+
+	if err := jobs.RunSteps(ctx, Add(runtime, "00000001", "/data/mods", "jei.jar", []byte("jei"))); err != nil {
+		t.Fatalf("Add() returned an error: \n%v", err)
+	}
+	if content, ok := runtime.File("00000001", "/data/mods/jei.jar"); !ok || string(content) != "jei" {
+		t.Fatalf("Add() file = %q, %v, want the jar", content, ok)
+	}
+
+	stored, err := db.GetMods(ctx, "00000001")
+	if err != nil {
+		t.Fatalf("GetMods() returned an error: \n%v", err)
+	}
+	want := types.ModManifest{
+		InstanceID: "00000001",
+		Mods: []types.Mod{{
+			Name:    "jei.jar",
+			SHA256:  "bf710f10ed653da2556694d0e9d756067e7062f4060c14c093cd1050264f2679",
+			Size:    3,
+			Enabled: true,
+			AddedAt: now(),
+		}},
+		UpdatedAt: now(),
+	}
+	if diff := cmp.Diff(want, stored); diff != "" {
+		t.Fatalf("Add() manifest mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := jobs.RunSteps(ctx, Add(runtime, "00000001", "/data/mods", "jei.jar", []byte("other"))); err == nil {
+		t.Fatalf("Add() of an added mod expected an error, got %v", err)
+	}
+	if content, _ := runtime.File("00000001", "/data/mods/jei.jar"); string(content) != "jei" {
+		t.Fatalf("Add() of an added mod changed its jar to %q", content)
+	}
+}
+
+// TestAddFailure calls Add when the manifest can not be recorded,
+// checking the uploaded jar is deleted again.
+func TestAddFailure(t *testing.T) {
+	runtime := setup(t)
+
+	add := Add(runtime, "00000001", "/data/mods", "jei.jar", []byte("jei"))
+	add[1].Do = func(ctx context.Context) error { return errors.New("injected failure") }
+	if err := jobs.RunSteps(context.Background(), add); err == nil {
+		t.Fatalf("Add() expected the injected error, got %v", err)
+	}
+	if _, ok := runtime.File("00000001", "/data/mods/jei.jar"); ok {
+		t.Fatalf("Add() left the jar behind after failing")
+	}
+}
+
+// TestSetEnabled calls SetEnabled to disable and enable a mod,
+// checking its jar is renamed and the manifest follows.
+func TestSetEnabled(t *testing.T) {
+	runtime := setup(t)
+	ctx := context.Background()
+	if err := jobs.RunSteps(ctx, Add(runtime, "00000001", "/data/mods", "jei.jar", []byte("jei"))); err != nil {
+		t.Fatalf("Add() returned an error: \n%v", err)
+	}
+
+	if err := jobs.RunSteps(ctx, SetEnabled(runtime, "00000001", "/data/mods", "jei.jar", false)); err != nil {
+		t.Fatalf("SetEnabled(false) returned an error: \n%v", err)
+	}
+	if _, ok := runtime.File("00000001", "/data/mods/jei.jar.disabled"); !ok {
+		t.Fatalf("SetEnabled(false) did not rename the jar")
+	}
+	if diff := cmp.Diff(map[string]bool{"jei.jar": false}, manifest(t)); diff != "" {
+		t.Fatalf("SetEnabled(false) manifest mismatch (-want +got):\n%s", diff)
+	}
+
+	// Disabling twice leaves the jar where it is.
+	if err := jobs.RunSteps(ctx, SetEnabled(runtime, "00000001", "/data/mods", "jei.jar", false)); err != nil {
+		t.Fatalf("SetEnabled(false) of a disabled mod returned an error: \n%v", err)
+	}
+
+	if err := jobs.RunSteps(ctx, SetEnabled(runtime, "00000001", "/data/mods", "jei.jar", true)); err != nil {
+		t.Fatalf("SetEnabled(true) returned an error: \n%v", err)
+	}
+	if _, ok := runtime.File("00000001", "/data/mods/jei.jar"); !ok {
+		t.Fatalf("SetEnabled(true) did not rename the jar back")
+	}
+	if diff := cmp.Diff(map[string]bool{"jei.jar": true}, manifest(t)); diff != "" {
+		t.Fatalf("SetEnabled(true) manifest mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := jobs.RunSteps(ctx, SetEnabled(runtime, "00000001", "/data/mods", "missing.jar", true)); err == nil {
+		t.Fatalf("SetEnabled() of a missing mod expected an error, got %v", err)
+	}
+}
+
+// TestRemove calls Remove on a disabled mod,
+// checking its jar and record are deleted and other mods are kept.
+func TestRemove(t *testing.T) {
+	runtime := setup(t)
+	ctx := context.Background()
+	steps := [][]jobs.Step{
+		Add(runtime, "00000001", "/data/mods", "jei.jar", []byte("jei")),
+		Add(runtime, "00000001", "/data/mods", "create.jar", []byte("create")),
+		SetEnabled(runtime, "00000001", "/data/mods", "jei.jar", false),
+		Remove(runtime, "00000001", "/data/mods", "jei.jar"),
+	}
+	for _, s := range steps {
+		if err := jobs.RunSteps(ctx, s); err != nil {
+			t.Fatalf("RunSteps() returned an error: \n%v", err)
+		}
+	}
+
+	if _, ok := runtime.File("00000001", "/data/mods/jei.jar.disabled"); ok {
+		t.Fatalf("Remove() left the disabled jar behind")
+	}
+	if _, ok := runtime.File("00000001", "/data/mods/create.jar"); !ok {
+		t.Fatalf("Remove() deleted another jar")
+	}
+	if diff := cmp.Diff(map[string]bool{"create.jar": true}, manifest(t)); diff != "" {
+		t.Fatalf("Remove() manifest mismatch (-want +got):\n%s", diff)
+	}
+}
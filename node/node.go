@@ -0,0 +1,205 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/RicochetStudios/aurora/capacity"
+	"github.com/RicochetStudios/aurora/config"
+	"github.com/RicochetStudios/aurora/db"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+)
+
+const (
+	// StatusOnline is the status of a node which is sending heartbeats.
+	StatusOnline string = "online"
+
+	// StatusOffline is the status of a node which has missed heartbeats for longer than the node timeout.
+	StatusOffline string = "offline"
+)
+
+// Run registers this node with its cluster, then sends heartbeats and marks stale nodes offline
+// every heartbeat interval until the context is cancelled.
+// Failures are logged and retried on the next interval, so a database outage does not stop the node.
+func Run(ctx context.Context) {
+	if _, err := Register(ctx); err != nil {
+		log.Printf("error registering node: %v\n", err)
+	}
+
+	ticker := time.NewTicker(config.Current().HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := Heartbeat(ctx); err != nil {
+				log.Printf("error sending node heartbeat: %v\n", err)
+			}
+			if _, err := MarkStale(ctx, time.Now().UTC(), config.Current().NodeTimeout); err != nil {
+				log.Printf("error marking stale nodes offline: %v\n", err)
+			}
+		}
+	}
+}
+
+// Register records this node in its cluster, with its hostname, address, labels, capacity, version and instances.
+// Registering again refreshes the details, keeping the original registration time.
+func Register(ctx context.Context) (types.Node, error) {
+	id, err := config.GetNodeId()
+	if err != nil {
+		return types.Node{}, fmt.Errorf("Register() error getting node id: %v", err)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return types.Node{}, fmt.Errorf("Register() error getting hostname: %v", err)
+	}
+
+	host, err := capacity.Host()
+	if err != nil {
+		return types.Node{}, fmt.Errorf("Register() error getting host capacity: %v", err)
+	}
+
+	labels, err := config.ParseLabels(config.Current().Labels)
+	if err != nil {
+		return types.Node{}, fmt.Errorf("Register() error parsing labels: %v", err)
+	}
+
+	now := time.Now().UTC()
+	node := types.Node{
+		ID:           id,
+		Hostname:     hostname,
+		Address:      config.Current().AdvertiseURL,
+		Labels:       labels,
+		Version:      config.Version,
+		Capacity:     host,
+		RegisteredAt: now,
+	}
+
+	// Keep the original registration time.
+	existing, err := db.GetNode(ctx, id)
+	if err == nil {
+		node.RegisteredAt = existing.RegisteredAt
+	} else if !errors.Is(err, db.ErrNotFound) {
+		return types.Node{}, fmt.Errorf("Register() error reading node: %v", err)
+	}
+
+	node, err = refresh(ctx, node, now)
+	if err != nil {
+		return types.Node{}, fmt.Errorf("Register() error refreshing node: %v", err)
+	}
+
+	return node, nil
+}
+
+// Heartbeat tells the cluster this node is online, refreshing its instances.
+// The node is registered if it is not already.
+func Heartbeat(ctx context.Context) (types.Node, error) {
+	id, err := config.GetNodeId()
+	if err != nil {
+		return types.Node{}, fmt.Errorf("Heartbeat() error getting node id: %v", err)
+	}
+
+	node, err := db.GetNode(ctx, id)
+	if errors.Is(err, db.ErrNotFound) {
+		return Register(ctx)
+	} else if err != nil {
+		return types.Node{}, fmt.Errorf("Heartbeat() error reading node: %v", err)
+	}
+
+	node, err = refresh(ctx, node, time.Now().UTC())
+	if err != nil {
+		return types.Node{}, fmt.Errorf("Heartbeat() error refreshing node: %v", err)
+	}
+
+	return node, nil
+}
+
+// MarkStale marks online nodes offline when their last heartbeat is older than the timeout,
+// returning the nodes that were marked.
+// Every node runs this, so the cluster notices when any one of them disappears.
+func MarkStale(ctx context.Context, now time.Time, timeout time.Duration) ([]types.Node, error) {
+	nodes, err := db.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("MarkStale() error listing nodes: %v", err)
+	}
+
+	marked := []types.Node{}
+	for _, node := range nodes {
+		if node.Status != StatusOnline || now.Sub(node.LastHeartbeat) <= timeout {
+			continue
+		}
+
+		node.Status = StatusOffline
+		if _, err := db.SetNode(ctx, node); err != nil {
+			return nil, fmt.Errorf("MarkStale() error updating node %v: %v", node.ID, err)
+		}
+		marked = append(marked, node)
+	}
+
+	return marked, nil
+}
+
+// Allocated returns the resources requested by the instances on this node.
+func Allocated(ctx context.Context) (types.Resources, error) {
+	cfg, err := config.Read()
+	if err != nil {
+		return types.Resources{}, fmt.Errorf("Allocated() error reading from config: %v", err)
+	}
+
+	return AllocatedTo(ctx, cfg)
+}
+
+// AllocatedTo returns the resources requested by the instances and reservations of a config.
+// Instances which are still reserved are counted by their reservation, as they may not be saved yet.
+func AllocatedTo(ctx context.Context, cfg config.Config) (types.Resources, error) {
+	var used []types.Resources
+	for _, reserved := range cfg.Reservations {
+		used = append(used, reserved)
+	}
+	for _, id := range cfg.Instances {
+		if _, ok := cfg.Reservations[id]; ok {
+			continue
+		}
+		server, err := db.GetServer(ctx, id)
+		if err != nil {
+			return types.Resources{}, fmt.Errorf("AllocatedTo() error reading server %v: %v", id, err)
+		}
+		gameSchema, err := schema.GetSchema(server.Game.Name)
+		if err != nil {
+			return types.Resources{}, fmt.Errorf("AllocatedTo() error reading schema of server %v: %v", id, err)
+		}
+		resources, err := capacity.ForServer(gameSchema, server)
+		if err != nil {
+			return types.Resources{}, fmt.Errorf("AllocatedTo() error reading resources of server %v: %v", id, err)
+		}
+		used = append(used, resources)
+	}
+
+	return capacity.Sum(used...), nil
+}
+
+// refresh updates the instances and heartbeat of a node and stores it.
+func refresh(ctx context.Context, node types.Node, now time.Time) (types.Node, error) {
+	instances, err := config.GetInstances()
+	if err != nil {
+		return types.Node{}, fmt.Errorf("refresh() error reading from config: %v", err)
+	}
+	allocated, err := Allocated(ctx)
+	if err != nil {
+		return types.Node{}, fmt.Errorf("refresh() error calculating allocated resources: %v", err)
+	}
+
+	node.Instances = append([]string{}, instances...)
+	node.Allocated = allocated
+	node.Status = StatusOnline
+	node.LastHeartbeat = now
+
+	return db.SetNode(ctx, node)
+}
@@ -0,0 +1,115 @@
+package node
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/RicochetStudios/aurora/config"
+	"github.com/RicochetStudios/aurora/config/configtest"
+	"github.com/RicochetStudios/aurora/db"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestRegister calls Register twice,
+// checking the node is stored with its details and keeps its registration time.
+func TestRegister(t *testing.T) {
+	configtest.UseLocalStorage(t)
+	ctx := context.Background()
+
+	first, err := Register(ctx)
+	if err != nil {
+		t.Fatalf("Register() returned an error: \n%v", err)
+	}
+
+	hostname, _ := os.Hostname()
+	if first.ID == "" || first.Hostname != hostname || first.Version != config.Version || first.Status != StatusOnline {
+		t.Fatalf("Register() = %+v, want an online node with an id, hostname %v and version %v", first, hostname, config.Version)
+	}
+	if first.Capacity.MilliCPU <= 0 || first.Capacity.Memory <= 0 {
+		t.Fatalf("Register() capacity = %+v, want positive cpu and memory", first.Capacity)
+	}
+
+	second, err := Register(ctx)
+	if err != nil {
+		t.Fatalf("Register() returned an error: \n%v", err)
+	}
+	if second.ID != first.ID || !second.RegisteredAt.Equal(first.RegisteredAt) {
+		t.Fatalf("Register() again = %+v, want id %v registered at %v", second, first.ID, first.RegisteredAt)
+	}
+
+	stored, err := db.GetNode(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("GetNode() returned an error: \n%v", err)
+	}
+	if diff := cmp.Diff(second, stored); diff != "" {
+		t.Fatalf("GetNode() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestHeartbeat calls Heartbeat on an offline node,
+// checking it is brought back online with a newer heartbeat.
+func TestHeartbeat(t *testing.T) {
+	configtest.UseLocalStorage(t)
+	ctx := context.Background()
+
+	registered, err := Register(ctx)
+	if err != nil {
+		t.Fatalf("Register() returned an error: \n%v", err)
+	}
+	registered.Status = StatusOffline
+	registered.LastHeartbeat = registered.LastHeartbeat.Add(-time.Hour)
+	if _, err := db.SetNode(ctx, registered); err != nil {
+		t.Fatalf("SetNode() returned an error: \n%v", err)
+	}
+
+	got, err := Heartbeat(ctx)
+	if err != nil {
+		t.Fatalf("Heartbeat() returned an error: \n%v", err)
+	}
+	if got.Status != StatusOnline || !got.LastHeartbeat.After(registered.LastHeartbeat) {
+		t.Fatalf("Heartbeat() = %+v, want an online node with a heartbeat after %v", got, registered.LastHeartbeat)
+	}
+}
+
+// TestMarkStale calls MarkStale with a mix of fresh and stale nodes,
+// checking only the stale online nodes are marked offline.
+func TestMarkStale(t *testing.T) {
+	configtest.UseLocalStorage(t)
+	ctx := context.Background()
+
+	now := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	for _, node := range []types.Node{
+		{ID: "fresh", Status: StatusOnline, LastHeartbeat: now.Add(-30 * time.Second)},
+		{ID: "stale", Status: StatusOnline, LastHeartbeat: now.Add(-5 * time.Minute)},
+		{ID: "gone", Status: StatusOffline, LastHeartbeat: now.Add(-time.Hour)},
+	} {
+		if _, err := db.SetNode(ctx, node); err != nil {
+			t.Fatalf("SetNode() returned an error: \n%v", err)
+		}
+	}
+
+	marked, err := MarkStale(ctx, now, time.Minute)
+	if err != nil {
+		t.Fatalf("MarkStale() returned an error: \n%v", err)
+	}
+	if len(marked) != 1 || marked[0].ID != "stale" {
+		t.Fatalf("MarkStale() = %v, want only the stale node", marked)
+	}
+
+	want := map[string]string{"fresh": StatusOnline, "stale": StatusOffline, "gone": StatusOffline}
+	nodes, err := db.ListNodes(ctx)
+	if err != nil {
+		t.Fatalf("ListNodes() returned an error: \n%v", err)
+	}
+	got := map[string]string{}
+	for _, node := range nodes {
+		got[node.ID] = node.Status
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("MarkStale() statuses mismatch (-want +got):\n%s", diff)
+	}
+}
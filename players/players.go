@@ -0,0 +1,207 @@
+package players
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/RicochetStudios/aurora/engine"
+	"github.com/RicochetStudios/aurora/files"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+)
+
+var (
+	// ErrFailed is returned when the command changing a player list of a running server fails.
+	ErrFailed error = errors.New("player list command failed")
+
+	// ErrUnavailable is returned when a player list can not be changed in the state the server is in.
+	ErrUnavailable error = errors.New("player list can not be changed")
+)
+
+// List returns the players on a list of the workload of an instance, read from the file of the list.
+// Files larger than limit bytes are not read.
+func List(ctx context.Context, runtime engine.Runtime, id string, list schema.PlayerList, limit int64) ([]types.Player, error) {
+	content, err := files.Read(ctx, runtime, id, list.File, limit)
+	if err != nil {
+		return nil, fmt.Errorf("List() error reading %v: \n%v", list.File, err)
+	}
+
+	if list.Format == schema.PlayersLines {
+		players := []types.Player{}
+		for _, name := range parseLines(content) {
+			players = append(players, types.Player{Name: name})
+		}
+		return players, nil
+	}
+
+	entries, err := parseJSON(content)
+	if err != nil {
+		return nil, fmt.Errorf("List() error reading %v: %v", list.File, err)
+	}
+	players := []types.Player{}
+	for _, entry := range entries {
+		name, _ := entry["name"].(string)
+		uuid, _ := entry["uuid"].(string)
+		reason, _ := entry["reason"].(string)
+		players = append(players, types.Player{Name: name, UUID: uuid, Reason: reason})
+	}
+	return players, nil
+}
+
+// Add adds a player to a list of the workload of an instance. A running server is sent the add command of the list,
+// so it takes effect at once, while the file of the list is changed for a stopped server.
+// Players already on the list are left as they are.
+func Add(ctx context.Context, runtime engine.Runtime, id string, list schema.PlayerList, player types.Player, limit int64) error {
+	return change(ctx, runtime, id, list, list.Add, player, limit, func(content []byte) ([]byte, error) {
+		if list.Format == schema.PlayersLines {
+			names := parseLines(content)
+			if indexOf(names, player.Name) != -1 {
+				return content, nil
+			}
+			return []byte(strings.Join(append(names, player.Name), "\n") + "\n"), nil
+		}
+
+		entries, err := parseJSON(content)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if name, _ := entry["name"].(string); strings.EqualFold(name, player.Name) {
+				return content, nil
+			}
+		}
+		entry := map[string]any{}
+		for field, value := range list.Fields {
+			entry[field] = value
+		}
+		entry["name"] = player.Name
+		if player.UUID != "" {
+			entry["uuid"] = player.UUID
+		}
+		if player.Reason != "" {
+			entry["reason"] = player.Reason
+		}
+		return formatJSON(append(entries, entry))
+	})
+}
+
+// Remove removes a player from a list of the workload of an instance, in the same way as Add.
+// Players which are not on the list are ignored.
+func Remove(ctx context.Context, runtime engine.Runtime, id string, list schema.PlayerList, name string, limit int64) error {
+	return change(ctx, runtime, id, list, list.Remove, types.Player{Name: name}, limit, func(content []byte) ([]byte, error) {
+		if list.Format == schema.PlayersLines {
+			names := parseLines(content)
+			i := indexOf(names, name)
+			if i == -1 {
+				return content, nil
+			}
+			return []byte(strings.Join(append(names[:i], names[i+1:]...), "\n") + "\n"), nil
+		}
+
+		entries, err := parseJSON(content)
+		if err != nil {
+			return nil, err
+		}
+		kept := []map[string]any{}
+		for _, entry := range entries {
+			if entryName, _ := entry["name"].(string); !strings.EqualFold(entryName, name) {
+				kept = append(kept, entry)
+			}
+		}
+		if len(kept) == len(entries) {
+			return content, nil
+		}
+		return formatJSON(kept)
+	})
+}
+
+// change runs a command of a list for a player if the workload of an instance is running,
+// or otherwise rewrites the file of the list with edit.
+func change(ctx context.Context, runtime engine.Runtime, id string, list schema.PlayerList, command []string, player types.Player, limit int64, edit func(content []byte) ([]byte, error)) error {
+	status, err := runtime.Status(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error getting status: \n%v", err)
+	}
+
+	if status.State == types.StateRunning {
+		if len(command) == 0 {
+			return fmt.Errorf("%w while the server is running: %v has no command", ErrUnavailable, list.Name)
+		}
+		command = schema.PlayerCommand(command, player)
+		result, err := runtime.Exec(ctx, id, command)
+		if err != nil {
+			return fmt.Errorf("error running %v: \n%v", command[0], err)
+		}
+		if result.ExitCode != 0 {
+			return fmt.Errorf("%w: %v exited with code %v: %v", ErrFailed, command[0], result.ExitCode, strings.TrimSpace(result.Output))
+		}
+		return nil
+	}
+
+	// A starting server may load the file before it is changed, so it must be stopped.
+	if status.State != types.StateStopped {
+		return fmt.Errorf("%w while the server is %v", ErrUnavailable, status.State)
+	}
+	content, err := files.Read(ctx, runtime, id, list.File, limit)
+	if err != nil {
+		return fmt.Errorf("error reading %v: \n%v", list.File, err)
+	}
+	edited, err := edit(content)
+	if err != nil {
+		return fmt.Errorf("error changing %v: %v", list.File, err)
+	}
+	if bytes.Equal(edited, content) {
+		return nil
+	}
+	return files.Write(ctx, runtime, id, list.File, edited)
+}
+
+// parseLines returns the player names of a lines file, skipping blank lines and comments.
+func parseLines(content []byte) []string {
+	names := []string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			names = append(names, line)
+		}
+	}
+	return names
+}
+
+// parseJSON returns the entries of a json file, keeping numbers as they are written.
+// An empty file has no entries.
+func parseJSON(content []byte) ([]map[string]any, error) {
+	entries := []map[string]any{}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return entries, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// formatJSON returns the content of a json file with entries, indented as games write them.
+func formatJSON(entries []map[string]any) ([]byte, error) {
+	content, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
+
+// indexOf returns the index of a name in a list of names regardless of case, or -1 if it is not in it.
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if strings.EqualFold(n, name) {
+			return i
+		}
+	}
+	return -1
+}
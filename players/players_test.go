@@ -0,0 +1,128 @@
+package players
+
+import (
+	"context"
+	"testing"
+
+	"github.com/RicochetStudios/aurora/engine/enginetest"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	// bans is a list kept in a json file, whose new entries have extra fields.
+	bans schema.PlayerList = schema.PlayerList{
+		Name:   "bans",
+		Add:    []string{"rcon-cli", "ban", "{{ .player }}", "{{ .reason }}"},
+		Remove: []string{"rcon-cli", "pardon", "{{ .player }}"},
+		File:   "/data/banned-players.json",
+		Format: schema.PlayersJSON,
+		Fields: map[string]any{"source": "Aurora", "expires": "forever"},
+	}
+
+	// admins is a list kept in a file of names, which can only be changed while the server is stopped.
+	admins schema.PlayerList = schema.PlayerList{Name: "admins", File: "/data/admins.txt", Format: schema.PlayersLines}
+)
+
+// setup returns a runtime with a stopped workload.
+func setup(t *testing.T) *enginetest.Fake {
+	runtime := enginetest.NewFake()
+	if err := runtime.Deploy(context.Background(), "00000001", schema.Schema{}, types.Server{Name: "myserver"}); err != nil {
+		t.Fatalf("setup() error deploying workload: \n%v", err)
+	}
+	if err := runtime.Stop(context.Background(), "00000001"); err != nil {
+		t.Fatalf("setup() error stopping workload: \n%v", err)
+	}
+	return runtime
+}
+
+// TestJSON calls Add, Remove and List on a json list of a stopped server,
+// checking entries are added once with the fields of the list, and fields of other entries are kept.
+func TestJSON(t *testing.T) {
+	runtime := setup(t)
+	ctx := context.Background()
+	runtime.WriteFile("00000001", bans.File, []byte(`[{"uuid": "069a79f4-44e9-4726-a5be-fca90e38aaf5", "name": "Notch", "created": "2023-01-01 00:00:00 +0000", "level": 4}]`))
+
+	if err := Add(ctx, runtime, "00000001", bans, types.Player{Name: "griefer", Reason: "Griefing"}, 1024); err != nil {
+		t.Fatalf("Add() returned an error: \n%v", err)
+	}
+	if err := Add(ctx, runtime, "00000001", bans, types.Player{Name: "GRIEFER"}, 1024); err != nil {
+		t.Fatalf("Add() of a player on the list returned an error: \n%v", err)
+	}
+	want := []types.Player{
+		{Name: "Notch", UUID: "069a79f4-44e9-4726-a5be-fca90e38aaf5"},
+		{Name: "griefer", Reason: "Griefing"},
+	}
+	got, err := List(ctx, runtime, "00000001", bans, 1024)
+	if err != nil {
+		t.Fatalf("List() returned an error: \n%v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("List() after Add() mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := Remove(ctx, runtime, "00000001", bans, "notch", 1024); err != nil {
+		t.Fatalf("Remove() returned an error: \n%v", err)
+	}
+	content, _ := runtime.File("00000001", bans.File)
+	wantContent := `[
+  {
+    "expires": "forever",
+    "name": "griefer",
+    "reason": "Griefing",
+    "source": "Aurora"
+  }
+]
+`
+	if diff := cmp.Diff(wantContent, string(content)); diff != "" {
+		t.Fatalf("Remove() file mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestLines calls Add and Remove on a list of names of a stopped server,
+// checking names are added once and matched regardless of case.
+func TestLines(t *testing.T) {
+	runtime := setup(t)
+	ctx := context.Background()
+	runtime.WriteFile("00000001", admins.File, []byte("# Admins\nalice\n"))
+
+	if err := Add(ctx, runtime, "00000001", admins, types.Player{Name: "bob"}, 1024); err != nil {
+		t.Fatalf("Add() returned an error: \n%v", err)
+	}
+	if err := Remove(ctx, runtime, "00000001", admins, "ALICE", 1024); err != nil {
+		t.Fatalf("Remove() returned an error: \n%v", err)
+	}
+	got, err := List(ctx, runtime, "00000001", admins, 1024)
+	if err != nil {
+		t.Fatalf("List() returned an error: \n%v", err)
+	}
+	if diff := cmp.Diff([]types.Player{{Name: "bob"}}, got); diff != "" {
+		t.Fatalf("List() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestRunning calls Add on running servers,
+// checking the command of the list is run instead of changing its file, and lists without one return an error.
+func TestRunning(t *testing.T) {
+	runtime := setup(t)
+	ctx := context.Background()
+	runtime.Start(ctx, "00000001")
+	runtime.WriteFile("00000001", bans.File, []byte("[]\n"))
+	runtime.Calls()
+
+	if err := Add(ctx, runtime, "00000001", bans, types.Player{Name: "griefer"}, 1024); err != nil {
+		t.Fatalf("Add() returned an error: \n%v", err)
+	}
+	if diff := cmp.Diff([]string{"Exec 00000001"}, runtime.Calls()); diff != "" {
+		t.Fatalf("Add() calls mismatch (-want +got):\n%s", diff)
+	}
+	if content, _ := runtime.File("00000001", bans.File); string(content) != "[]\n" {
+		t.Fatalf("Add() changed the file of a running server: %s", content)
+	}
+
+	if err := Add(ctx, runtime, "00000001", admins, types.Player{Name: "bob"}, 1024); err == nil {
+		t.Fatalf("Add() to a list without a command of a running server returned no error")
+	}
+}
@@ -0,0 +1,244 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/RicochetStudios/aurora/audit"
+	"github.com/RicochetStudios/aurora/config"
+	"github.com/RicochetStudios/aurora/db"
+	"github.com/RicochetStudios/aurora/engine"
+	"github.com/RicochetStudios/aurora/jobs"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+)
+
+const (
+	// authMethod is recorded on the audit events of the reconciler, which acts without a user.
+	authMethod string = "internal"
+
+	// baseBackoff is how long to wait before restarting a workload again after the first restart.
+	baseBackoff time.Duration = 10 * time.Second
+
+	// maxBackoff is the longest wait between restarts of a workload which keeps stopping.
+	// A workload which stays running for this long is considered recovered.
+	maxBackoff time.Duration = 5 * time.Minute
+)
+
+// restart tracks the restarts of a workload which keeps stopping.
+type restart struct {
+	attempts int       // Restarts since the workload last recovered.
+	last     time.Time // When the workload was last restarted.
+}
+
+// Reconciler compares the stored servers of this node against their workloads, and repairs any difference.
+type Reconciler struct {
+	Runtime engine.Runtime
+
+	schemas  func(game string) (schema.Schema, error)
+	now      func() time.Time
+	restarts map[string]restart // Restarts of workloads which stopped unexpectedly, by instance id.
+}
+
+// New creates a Reconciler repairing workloads of a runtime.
+func New(runtime engine.Runtime) *Reconciler {
+	return &Reconciler{
+		Runtime:  runtime,
+		schemas:  schema.GetSchema,
+		now:      time.Now,
+		restarts: map[string]restart{},
+	}
+}
+
+// Run reconciles the servers of this node every reconcile interval until the context is cancelled.
+// Failures are logged and retried on the next interval.
+func Run(ctx context.Context) {
+	runtime, err := engine.Current()
+	if err != nil {
+		log.Printf("error creating runtime for the reconciler: %v\n", err)
+		return
+	}
+	r := New(runtime)
+
+	ticker := time.NewTicker(config.Current().ReconcileInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Reconcile(ctx); err != nil {
+				log.Printf("error reconciling servers: %v\n", err)
+			}
+		}
+	}
+}
+
+// Reconcile makes a single pass over the instances of this node, repairing each workload
+// which differs from its stored server. Repairs run as jobs, so they never act on an instance
+// alongside another job, and instances with a job in progress are skipped, as the job is
+// already changing them. Each repair is waited for, and all of the errors found are returned.
+func (r *Reconciler) Reconcile(ctx context.Context) error {
+	ids, err := config.GetInstances()
+	if err != nil {
+		return fmt.Errorf("Reconcile() error getting instances: \n%v", err)
+	}
+
+	var errs []error
+	for _, id := range ids {
+		if err := r.reconcileInstance(ctx, id); err != nil {
+			errs = append(errs, fmt.Errorf("Reconcile() error reconciling instance %v: \n%v", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// correction is an action which repairs the workload of an instance.
+type correction struct {
+	action string // Audit action the correction is recorded and run as a job under.
+	run    func(ctx context.Context) error
+}
+
+// reconcileInstance repairs the workload of an instance to match its stored server, in a job of the action
+// which repairs it. The instance is checked again in the job, as another job may have changed it in between.
+func (r *Reconciler) reconcileInstance(ctx context.Context, id string) error {
+	planned, err := r.diagnose(ctx, id)
+	if err != nil || planned == nil {
+		return err
+	}
+
+	var runErr error
+	job, err := jobs.Submit(planned.action, id, func(jobCtx context.Context) error {
+		corrected, err := r.diagnose(jobCtx, id)
+		if err != nil || corrected == nil {
+			runErr = err
+			return err
+		}
+		runErr = r.record(jobCtx, corrected.action, id, corrected.run(jobCtx))
+		return runErr
+	})
+	if errors.Is(err, jobs.ErrActive) {
+		return nil
+	}
+
+	// The updates of the job are closed once it has finished.
+	updates, _, ok := jobs.Subscribe(job.ID)
+	if !ok {
+		return nil
+	}
+	for range updates {
+	}
+	return runErr
+}
+
+// diagnose compares the workload of an instance with its stored server, returning the correction
+// which repairs it, or nil if it needs none.
+func (r *Reconciler) diagnose(ctx context.Context, id string) (*correction, error) {
+	server, err := db.GetServer(ctx, id)
+	if errors.Is(err, db.ErrNotFound) {
+		// The instance is being created or removed, which is not for the reconciler to finish.
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading server: \n%v", err)
+	}
+
+	gameSchema, err := r.schemas(server.Game.Name)
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema: \n%v", err)
+	}
+
+	status, err := r.Runtime.Status(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting status: \n%v", err)
+	}
+
+	revision, err := schema.Revision(gameSchema, server)
+	if err != nil {
+		return nil, err
+	}
+
+	shouldRun := server.Status != types.StateStopped
+	running := status.State == types.StateRunning || status.State == types.StateStarting
+
+	// Deploying and updating start the workload, so it is stopped again if it should not run.
+	switch {
+	case status.State == types.StateMissing:
+		return &correction{action: audit.ActionReconcileRedeploy, run: func(ctx context.Context) error {
+			err := r.Runtime.Deploy(ctx, id, gameSchema, server)
+			if err == nil && !shouldRun {
+				err = r.Runtime.Stop(ctx, id)
+			}
+			return err
+		}}, nil
+
+	// Workloads deployed before revisions were recorded have none, and are left as they are.
+	case status.Revision != "" && status.Revision != revision:
+		return &correction{action: audit.ActionReconcileUpdate, run: func(ctx context.Context) error {
+			err := r.Runtime.Update(ctx, id, gameSchema, server)
+			if err == nil && !shouldRun {
+				err = r.Runtime.Stop(ctx, id)
+			}
+			return err
+		}}, nil
+
+	case shouldRun && !running:
+		previous := r.restarts[id]
+		if previous.attempts > 0 && r.now().Before(previous.last.Add(backoff(previous.attempts))) {
+			return nil, nil
+		}
+		return &correction{action: audit.ActionReconcileRestart, run: func(ctx context.Context) error {
+			r.restarts[id] = restart{attempts: previous.attempts + 1, last: r.now()}
+			return r.Runtime.Start(ctx, id)
+		}}, nil
+
+	case !shouldRun && running:
+		return &correction{action: audit.ActionReconcileStop, run: func(ctx context.Context) error {
+			return r.Runtime.Stop(ctx, id)
+		}}, nil
+
+	case running:
+		if previous, ok := r.restarts[id]; ok && r.now().Sub(previous.last) > maxBackoff {
+			delete(r.restarts, id)
+		}
+	}
+
+	return nil, nil
+}
+
+// backoff returns how long to wait after a number of restarts before restarting again,
+// doubling after each restart up to the maximum.
+func backoff(attempts int) time.Duration {
+	wait := baseBackoff
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		return maxBackoff
+	}
+	return wait
+}
+
+// record stores an audit event for a corrective action and its result, returning the error of the action.
+func (r *Reconciler) record(ctx context.Context, action string, id string, actionErr error) error {
+	event := types.AuditEvent{
+		Actor:      audit.Reconciler,
+		AuthMethod: authMethod,
+		Action:     action,
+		InstanceID: id,
+		Changes:    []types.AuditChange{},
+		Result:     audit.ResultSuccess,
+		Timestamp:  r.now().UTC(),
+	}
+	if actionErr != nil {
+		event.Result = audit.ResultFailure
+		event.Error = actionErr.Error()
+	}
+
+	if _, err := db.AddAuditEvent(ctx, event); err != nil {
+		return errors.Join(actionErr, fmt.Errorf("error recording %v event: \n%v", action, err))
+	}
+	return actionErr
+}
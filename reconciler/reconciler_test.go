@@ -0,0 +1,208 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RicochetStudios/aurora/audit"
+	"github.com/RicochetStudios/aurora/config"
+	"github.com/RicochetStudios/aurora/config/configtest"
+	"github.com/RicochetStudios/aurora/db"
+	"github.com/RicochetStudios/aurora/engine/enginetest"
+	"github.com/RicochetStudios/aurora/jobs"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var stored types.Server = types.Server{Name: "myserver", Size: "xs", Game: types.Game{Name: "minecraft_java", Modloader: "vanilla"}, Status: types.StateRunning}
+
+// storedSchema is the schema of the stored server, whose sizes differ in resources.
+var storedSchema schema.Schema = schema.Schema{Sizes: map[string]schema.Size{
+	"xs": {Resources: schema.Resources{CPU: "1000m", Memory: "2000Mi"}},
+	"m":  {Resources: schema.Resources{CPU: "2000m", Memory: "6000Mi"}},
+}}
+
+// setup records an instance of the stored server, with a running workload, returning a reconciler
+// for it with a clock which can be moved forward.
+func setup(t *testing.T, server types.Server) (*Reconciler, *enginetest.Fake, *time.Time) {
+	configtest.UseLocalStorage(t)
+	ctx := context.Background()
+
+	if _, err := config.AddInstance("00000001"); err != nil {
+		t.Fatalf("setup() error registering instance: \n%v", err)
+	}
+	if _, err := db.SetServer(ctx, "00000001", server); err != nil {
+		t.Fatalf("setup() error saving server: \n%v", err)
+	}
+
+	runtime := enginetest.NewFake()
+	if err := runtime.Deploy(ctx, "00000001", storedSchema, server); err != nil {
+		t.Fatalf("setup() error deploying workload: \n%v", err)
+	}
+	runtime.Calls()
+
+	now := time.Now()
+	r := New(runtime)
+	r.schemas = func(game string) (schema.Schema, error) { return storedSchema, nil }
+	r.now = func() time.Time { return now }
+	return r, runtime, &now
+}
+
+// reconcile calls Reconcile, checking it succeeds, and returns the runtime calls it made.
+func reconcile(t *testing.T, r *Reconciler, runtime *enginetest.Fake) []string {
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("Reconcile() returned an error: \n%v", err)
+	}
+	return runtime.Calls()
+}
+
+// TestReconcileInSync calls Reconcile when the workload matches the stored server,
+// checking nothing is changed.
+func TestReconcileInSync(t *testing.T) {
+	r, runtime, _ := setup(t, stored)
+
+	if calls := reconcile(t, r, runtime); len(calls) != 0 {
+		t.Fatalf("Reconcile() made calls %v, want none", calls)
+	}
+}
+
+// TestReconcileMissing calls Reconcile after the workload was deleted,
+// checking it is deployed again and the action is recorded.
+func TestReconcileMissing(t *testing.T) {
+	r, runtime, _ := setup(t, stored)
+	runtime.Delete("00000001")
+
+	want := []string{"Deploy 00000001"}
+	if diff := cmp.Diff(want, reconcile(t, r, runtime)); diff != "" {
+		t.Fatalf("Reconcile() calls mismatch (-want +got):\n%s", diff)
+	}
+
+	events, err := db.GetAuditEvents(context.Background(), audit.Filter{Actor: audit.Reconciler})
+	if err != nil {
+		t.Fatalf("GetAuditEvents() returned an error: \n%v", err)
+	}
+	if len(events) != 1 || events[0].Action != audit.ActionReconcileRedeploy || events[0].InstanceID != "00000001" || events[0].Result != audit.ResultSuccess {
+		t.Fatalf("GetAuditEvents() = %+v, want a successful redeploy event", events)
+	}
+}
+
+// TestReconcileDrift calls Reconcile after the stored server changed without updating the workload,
+// checking the workload is updated to the stored server.
+func TestReconcileDrift(t *testing.T) {
+	r, runtime, _ := setup(t, stored)
+	changed := stored
+	changed.Size = "m"
+	if _, err := db.SetServer(context.Background(), "00000001", changed); err != nil {
+		t.Fatalf("SetServer() returned an error: \n%v", err)
+	}
+
+	want := []string{"Update 00000001"}
+	if diff := cmp.Diff(want, reconcile(t, r, runtime)); diff != "" {
+		t.Fatalf("Reconcile() calls mismatch (-want +got):\n%s", diff)
+	}
+	if workload, _, _ := runtime.Workload("00000001"); workload.Size != "m" {
+		t.Fatalf("Reconcile() workload size = %v, want m", workload.Size)
+	}
+}
+
+// TestReconcileCrashed calls Reconcile while a workload keeps stopping,
+// checking it is restarted with a growing wait between restarts, and failures are recorded.
+func TestReconcileCrashed(t *testing.T) {
+	r, runtime, now := setup(t, stored)
+	restart := []string{"Start 00000001"}
+
+	runtime.Crash("00000001")
+	if diff := cmp.Diff(restart, reconcile(t, r, runtime)); diff != "" {
+		t.Fatalf("Reconcile() first crash calls mismatch (-want +got):\n%s", diff)
+	}
+
+	// The second restart waits for the base backoff.
+	runtime.Crash("00000001")
+	*now = now.Add(baseBackoff / 2)
+	if calls := reconcile(t, r, runtime); len(calls) != 0 {
+		t.Fatalf("Reconcile() within the backoff made calls %v, want none", calls)
+	}
+	*now = now.Add(baseBackoff)
+	if diff := cmp.Diff(restart, reconcile(t, r, runtime)); diff != "" {
+		t.Fatalf("Reconcile() after the backoff calls mismatch (-want +got):\n%s", diff)
+	}
+
+	// The third restart waits twice as long, and fails.
+	runtime.Crash("00000001")
+	runtime.Fail("Start", errors.New("port in use"))
+	*now = now.Add(baseBackoff + time.Second)
+	if calls := reconcile(t, r, runtime); len(calls) != 0 {
+		t.Fatalf("Reconcile() within the doubled backoff made calls %v, want none", calls)
+	}
+	*now = now.Add(baseBackoff)
+	if err := r.Reconcile(context.Background()); err == nil {
+		t.Fatalf("Reconcile() expected the start error, got %v", err)
+	}
+
+	events, err := db.GetAuditEvents(context.Background(), audit.Filter{Action: audit.ActionReconcileRestart})
+	if err != nil {
+		t.Fatalf("GetAuditEvents() returned an error: \n%v", err)
+	}
+	if len(events) != 3 || events[0].Result != audit.ResultFailure || events[0].Error != "port in use" {
+		t.Fatalf("GetAuditEvents() = %+v, want three restart events, the last failing", events)
+	}
+}
+
+// TestReconcileStopped calls Reconcile for a server which should be stopped,
+// checking a running workload is stopped and a stopped workload is left alone.
+func TestReconcileStopped(t *testing.T) {
+	stopped := stored
+	stopped.Status = types.StateStopped
+	r, runtime, _ := setup(t, stopped)
+
+	want := []string{"Stop 00000001"}
+	if diff := cmp.Diff(want, reconcile(t, r, runtime)); diff != "" {
+		t.Fatalf("Reconcile() calls mismatch (-want +got):\n%s", diff)
+	}
+	if calls := reconcile(t, r, runtime); len(calls) != 0 {
+		t.Fatalf("Reconcile() of a stopped workload made calls %v, want none", calls)
+	}
+}
+
+// TestReconcileActive calls Reconcile while another job acts on an instance whose workload is missing,
+// checking it is left to the job, and redeployed in a job of the reconciler once the other job has finished.
+func TestReconcileActive(t *testing.T) {
+	r, runtime, _ := setup(t, stored)
+	runtime.Delete("00000001")
+
+	release := make(chan struct{})
+	job, err := jobs.Submit(audit.ActionServerStop, "00000001", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Submit() returned an error: \n%v", err)
+	}
+	if calls := reconcile(t, r, runtime); len(calls) != 0 {
+		t.Fatalf("Reconcile() during another job made calls %v, want none", calls)
+	}
+
+	close(release)
+	updates, _, _ := jobs.Subscribe(job.ID)
+	for range updates {
+	}
+	want := []string{"Deploy 00000001"}
+	if diff := cmp.Diff(want, reconcile(t, r, runtime)); diff != "" {
+		t.Fatalf("Reconcile() calls mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestBackoff calls backoff with increasing attempts,
+// checking the wait doubles up to the maximum.
+func TestBackoff(t *testing.T) {
+	got := []time.Duration{backoff(1), backoff(2), backoff(3), backoff(100)}
+	want := []time.Duration{baseBackoff, 2 * baseBackoff, 4 * baseBackoff, maxBackoff}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("backoff() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,226 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RicochetStudios/aurora/config"
+
+	"github.com/docker/distribution/reference"
+	"github.com/opencontainers/go-digest"
+)
+
+const (
+	// dockerHub is the domain of images without a registry e.g. itzg/minecraft-server.
+	dockerHub string = "docker.io"
+
+	// dockerHubAPI is the host serving the registry API of Docker Hub.
+	dockerHubAPI string = "registry-1.docker.io"
+)
+
+// manifestTypes are the manifests accepted when resolving a digest, preferring lists
+// of images for every platform so the digest is the same one a pull records.
+var manifestTypes []string = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// httpClient makes the requests to registries.
+// Registries may be unreachable, so requests give up rather than holding the caller forever.
+var httpClient *http.Client = &http.Client{Timeout: 30 * time.Second}
+
+// Credentials are a username and password for a registry.
+type Credentials struct {
+	Server   string // The domain of the registry e.g. ghcr.io.
+	Username string
+	Password string
+}
+
+// Lookup returns the credentials configured for the registry of an image, if any.
+func Lookup(image string) (Credentials, bool) {
+	settings := config.Current()
+	if settings.RegistryUsername == "" {
+		return Credentials{}, false
+	}
+
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil || reference.Domain(named) != Domain(settings.RegistryServer) {
+		return Credentials{}, false
+	}
+
+	return Credentials{
+		Server:   Domain(settings.RegistryServer),
+		Username: settings.RegistryUsername,
+		Password: settings.RegistryPassword,
+	}, true
+}
+
+// Domain normalises the domain of a registry, so the aliases of Docker Hub compare equal.
+func Domain(server string) string {
+	server = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://"), "/")
+	switch server {
+	case "", "index.docker.io", dockerHubAPI:
+		return dockerHub
+	}
+	return server
+}
+
+// Pinned returns an image reference pinned to a digest, dropping its tag
+// e.g. itzg/minecraft-server@sha256:...
+func Pinned(image string, sum string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("Pinned() error parsing image %q: %v", image, err)
+	}
+	if err := digest.Digest(sum).Validate(); err != nil {
+		return "", fmt.Errorf("Pinned() digest %q is not valid: %v", sum, err)
+	}
+	canonical, err := reference.WithDigest(reference.TrimNamed(named), digest.Digest(sum))
+	if err != nil {
+		return "", fmt.Errorf("Pinned() error adding digest %q: %v", sum, err)
+	}
+	return reference.FamiliarString(canonical), nil
+}
+
+// IsPinned returns whether an image reference includes a digest.
+func IsPinned(image string) bool {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return false
+	}
+	_, ok := named.(reference.Digested)
+	return ok
+}
+
+// Resolve asks the registry of an image for the digest its tag currently points to,
+// returning the image pinned to that digest. Images which are already pinned are returned as they are.
+func Resolve(ctx context.Context, image string) (string, error) {
+	if IsPinned(image) {
+		return image, nil
+	}
+
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("Resolve() error parsing image %q: %v", image, err)
+	}
+	tagged := reference.TagNameOnly(named).(reference.Tagged)
+
+	host := reference.Domain(named)
+	if host == dockerHub {
+		host = dockerHubAPI
+	}
+	manifestURL := fmt.Sprintf("https://%v/v2/%v/manifests/%v", host, reference.Path(named), tagged.Tag())
+
+	resp, err := headManifest(ctx, manifestURL, "")
+	if err != nil {
+		return "", fmt.Errorf("Resolve() error requesting manifest: \n%v", err)
+	}
+
+	// Registries which require a token say where to get one.
+	if resp.StatusCode == http.StatusUnauthorized {
+		token, err := fetchToken(ctx, resp.Header.Get("WWW-Authenticate"), image)
+		if err != nil {
+			return "", fmt.Errorf("Resolve() error authenticating with %v: \n%v", host, err)
+		}
+		if resp, err = headManifest(ctx, manifestURL, "Bearer "+token); err != nil {
+			return "", fmt.Errorf("Resolve() error requesting manifest: \n%v", err)
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Resolve() registry returned %v for %v", resp.Status, image)
+	}
+	sum := resp.Header.Get("Docker-Content-Digest")
+	if sum == "" {
+		return "", fmt.Errorf("Resolve() registry returned no digest for %v", image)
+	}
+
+	return Pinned(image, sum)
+}
+
+// headManifest requests the headers of a manifest, with an optional authorization header.
+func headManifest(ctx context.Context, manifestURL string, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// fetchToken gets a bearer token for pulling an image, from the realm given in a WWW-Authenticate challenge.
+// The configured credentials of the registry are sent, if any.
+func fetchToken(ctx context.Context, challenge string, image string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+
+	// Parameters are comma separated key="value" pairs, and the scope may itself contain commas.
+	values := url.Values{}
+	var realm string
+	for _, param := range strings.Split(params, "\",") {
+		key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if !found {
+			continue
+		}
+		value = strings.Trim(value, "\"")
+		if key == "realm" {
+			realm = value
+		} else {
+			values.Set(key, value)
+		}
+	}
+	if realm == "" {
+		return "", fmt.Errorf("authentication challenge %q has no realm", challenge)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm+"?"+values.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if credentials, ok := Lookup(image); ok {
+		req.SetBasicAuth(credentials.Username, credentials.Password)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request returned %v", resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("error decoding token: %v", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", errors.New("token response has no token")
+}
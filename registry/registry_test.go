@@ -0,0 +1,107 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RicochetStudios/aurora/config"
+)
+
+const testDigest string = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+// useRegistry starts a registry serving a single image behind token authentication,
+// configured with credentials for it, returning its host.
+func useRegistry(t *testing.T) string {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if username, password, ok := r.BasicAuth(); !ok || username != "aurora" || password != "hunter2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"token": "pull-token"}`))
+		case "/v2/games/minecraft/manifests/1.20":
+			if r.Header.Get("Authorization") != "Bearer pull-token" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="https://`+r.Host+`/token",service="test",scope="repository:games/minecraft:pull"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", testDigest)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	host := strings.TrimPrefix(server.URL, "https://")
+	settings := config.Defaults()
+	settings.RegistryServer = host
+	settings.RegistryUsername = "aurora"
+	settings.RegistryPassword = "hunter2"
+	config.SetCurrent(settings)
+	defaultClient := httpClient
+	httpClient = server.Client()
+	t.Cleanup(func() {
+		config.SetCurrent(config.Defaults())
+		httpClient = defaultClient
+	})
+
+	return host
+}
+
+// TestResolve calls Resolve with a tagged image from a private registry,
+// checking the image is pinned to the digest of its tag.
+func TestResolve(t *testing.T) {
+	host := useRegistry(t)
+
+	got, err := Resolve(context.Background(), host+"/games/minecraft:1.20")
+	if err != nil {
+		t.Fatalf("Resolve() returned an error: \n%v", err)
+	}
+	if want := host + "/games/minecraft@" + testDigest; got != want {
+		t.Fatalf("Resolve() = %v, want %v", got, want)
+	}
+
+	if _, err := Resolve(context.Background(), host+"/games/missing:1.20"); err == nil {
+		t.Fatalf("Resolve() expected an error for a missing image, got %v", err)
+	}
+}
+
+// TestLookup calls Lookup with images on and off the configured registry,
+// checking credentials are only returned for the configured registry.
+func TestLookup(t *testing.T) {
+	t.Cleanup(func() { config.SetCurrent(config.Defaults()) })
+	settings := config.Defaults()
+	settings.RegistryUsername = "aurora"
+	settings.RegistryPassword = "hunter2"
+	config.SetCurrent(settings)
+
+	if _, ok := Lookup("itzg/minecraft-server:latest"); !ok {
+		t.Fatalf("Lookup() found no credentials for a Docker Hub image")
+	}
+	if _, ok := Lookup("ghcr.io/itzg/minecraft-server:latest"); ok {
+		t.Fatalf("Lookup() found credentials for another registry")
+	}
+}
+
+// TestPinned calls Pinned and IsPinned with tagged images,
+// checking the tag is replaced by the digest.
+func TestPinned(t *testing.T) {
+	got, err := Pinned("itzg/minecraft-server:latest", testDigest)
+	if err != nil {
+		t.Fatalf("Pinned() returned an error: \n%v", err)
+	}
+	if want := "itzg/minecraft-server@" + testDigest; got != want {
+		t.Fatalf("Pinned() = %v, want %v", got, want)
+	}
+	if !IsPinned(got) || IsPinned("itzg/minecraft-server:latest") {
+		t.Fatalf("IsPinned() did not tell pinned and tagged images apart")
+	}
+
+	if _, err := Pinned("itzg/minecraft-server:latest", "sha256:short"); err == nil {
+		t.Fatalf("Pinned() expected an invalid digest error, got %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RicochetStudios/aurora/capacity"
+	"github.com/RicochetStudios/aurora/config"
+	"github.com/RicochetStudios/aurora/node"
+	"github.com/RicochetStudios/aurora/types"
+)
+
+// dispatchClient sends servers to the nodes chosen to host them.
+// Nodes may be unreachable, so requests give up rather than holding the caller forever.
+var dispatchClient *http.Client = &http.Client{Timeout: 30 * time.Second}
+
+// Candidate is a node which can host a server, with how well it suits it.
+type Candidate struct {
+	Node  types.Node // The node which can host the server.
+	Score float64    // Higher is better, the share of cpu and memory left free after placement.
+}
+
+// Rank scores every node which can host a server needing the requested resources
+// within the placement constraints, best first.
+// Nodes are ruled out if they are offline, unreachable, do not match the placement labels,
+// or do not have enough free resources. If no node can host the server,
+// the error explains why each node was ruled out.
+func Rank(nodes []types.Node, requested types.Resources, placement types.Placement) ([]Candidate, error) {
+	candidates := []Candidate{}
+	var rejections []string
+	for _, n := range nodes {
+		if reason := reject(n, requested, placement); reason != "" {
+			rejections = append(rejections, fmt.Sprintf("%v: %v", n.ID, reason))
+			continue
+		}
+		candidates = append(candidates, Candidate{Node: n, Score: score(n, requested)})
+	}
+
+	if len(candidates) == 0 {
+		if len(rejections) == 0 {
+			return nil, fmt.Errorf("no nodes are registered to the cluster")
+		}
+		return nil, fmt.Errorf("no node can host the server: %v", strings.Join(rejections, "; "))
+	}
+
+	// Sort by score, falling back to the id so placement is deterministic.
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].Node.ID < candidates[j].Node.ID
+	})
+
+	return candidates, nil
+}
+
+// Place returns the best node to host a server, see Rank.
+func Place(nodes []types.Node, requested types.Resources, placement types.Placement) (types.Node, error) {
+	candidates, err := Rank(nodes, requested, placement)
+	if err != nil {
+		return types.Node{}, err
+	}
+	return candidates[0].Node, nil
+}
+
+// reject returns why a node can not host a server, or an empty string if it can.
+func reject(n types.Node, requested types.Resources, placement types.Placement) string {
+	if n.Status != node.StatusOnline {
+		return "node is " + n.Status
+	}
+	if n.Address == "" {
+		return "node has no advertised address"
+	}
+	for key, value := range placement.Affinity {
+		if actual, ok := n.Labels[key]; !ok || actual != value {
+			return fmt.Sprintf("node does not have label %v=%v", key, value)
+		}
+	}
+	for key, value := range placement.AntiAffinity {
+		if actual, ok := n.Labels[key]; ok && actual == value {
+			return fmt.Sprintf("node has label %v=%v", key, value)
+		}
+	}
+	if err := capacity.Check(n.Capacity, n.Allocated, requested); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// score rates a node which can host a server, preferring the node with the most
+// cpu and memory left free afterwards so load is spread across the cluster.
+// Each resource contributes the fraction of the node's capacity left free, from 0 to 1.
+func score(n types.Node, requested types.Resources) float64 {
+	free := capacity.Free(n.Capacity, capacity.Sum(n.Allocated, requested))
+
+	var total float64
+	if n.Capacity.MilliCPU > 0 {
+		total += float64(free.MilliCPU) / float64(n.Capacity.MilliCPU)
+	}
+	if n.Capacity.Memory > 0 {
+		total += float64(free.Memory) / float64(n.Capacity.Memory)
+	}
+	return total
+}
+
+// Dispatch hands a server to a node's API to create, authenticated as the original caller,
+// returning the job the node is creating the instance with.
+// Node addresses are reported by the nodes themselves, so the caller's credentials are only sent
+// over https, or to addresses listed in the insecureNodes setting.
+func Dispatch(ctx context.Context, n types.Node, server types.Server, authorization string) (types.Job, error) {
+	if err := checkAddress(n.Address, config.Current().InsecureNodes); err != nil {
+		return types.Job{}, fmt.Errorf("Dispatch() node %v can not be sent credentials: %v", n.ID, err)
+	}
+
+	body, err := json.Marshal(server)
+	if err != nil {
+		return types.Job{}, fmt.Errorf("Dispatch() error converting server to json: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(n.Address, "/")+"/api/servers", bytes.NewReader(body))
+	if err != nil {
+		return types.Job{}, fmt.Errorf("Dispatch() error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", authorization)
+
+	resp, err := dispatchClient.Do(req)
+	if err != nil {
+		return types.Job{}, fmt.Errorf("Dispatch() error sending request to node %v: %v", n.ID, err)
+	}
+	defer resp.Body.Close()
+
+	// Nodes respond in the usual presenter format, where data is empty on errors.
+	var result struct {
+		Status bool            `json:"status"`
+		Data   json.RawMessage `json:"data"`
+		Error  string          `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return types.Job{}, fmt.Errorf("Dispatch() error reading response from node %v: %v", n.ID, err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest || !result.Status {
+		return types.Job{}, fmt.Errorf("Dispatch() node %v returned %v: %v", n.ID, resp.StatusCode, result.Error)
+	}
+
+	var job types.Job
+	if err := json.Unmarshal(result.Data, &job); err != nil {
+		return types.Job{}, fmt.Errorf("Dispatch() error reading job from node %v: %v", n.ID, err)
+	}
+
+	return job, nil
+}
+
+// checkAddress returns an error unless a node address uses https, or is one of the comma separated insecure addresses.
+func checkAddress(address string, insecure string) error {
+	parsed, err := url.Parse(address)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("address %q is not a valid URL", address)
+	}
+	if parsed.Scheme == "https" {
+		return nil
+	}
+	for _, allowed := range strings.Split(insecure, ",") {
+		if allowed = strings.TrimSpace(allowed); allowed != "" && strings.TrimSuffix(allowed, "/") == strings.TrimSuffix(address, "/") {
+			return nil
+		}
+	}
+	return fmt.Errorf("address %q must use https, or be listed in insecureNodes", address)
+}
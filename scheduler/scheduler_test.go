@@ -0,0 +1,202 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RicochetStudios/aurora/config"
+	"github.com/RicochetStudios/aurora/node"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// gi is a gibibyte of memory.
+const gi int64 = 1 << 30
+
+// inventory is a simulated cluster of nodes.
+func inventory() []types.Node {
+	return []types.Node{
+		{
+			ID: "eu-busy", Status: node.StatusOnline, Address: "http://eu-busy:6969",
+			Labels:    map[string]string{"region": "eu-west", "class": "ssd"},
+			Capacity:  types.Resources{MilliCPU: 8000, Memory: 32 * gi},
+			Allocated: types.Resources{MilliCPU: 6000, Memory: 24 * gi},
+		},
+		{
+			ID: "eu-idle", Status: node.StatusOnline, Address: "http://eu-idle:6969",
+			Labels:    map[string]string{"region": "eu-west", "class": "hdd"},
+			Capacity:  types.Resources{MilliCPU: 8000, Memory: 32 * gi},
+			Allocated: types.Resources{MilliCPU: 1000, Memory: 2 * gi},
+		},
+		{
+			ID: "us-idle", Status: node.StatusOnline, Address: "http://us-idle:6969",
+			Labels:    map[string]string{"region": "us-east", "class": "ssd"},
+			Capacity:  types.Resources{MilliCPU: 4000, Memory: 16 * gi},
+			Allocated: types.Resources{},
+		},
+		{
+			ID: "eu-offline", Status: node.StatusOffline, Address: "http://eu-offline:6969",
+			Labels:   map[string]string{"region": "eu-west", "class": "ssd"},
+			Capacity: types.Resources{MilliCPU: 64000, Memory: 256 * gi},
+		},
+		{
+			ID: "eu-hidden", Status: node.StatusOnline,
+			Labels:   map[string]string{"region": "eu-west", "class": "ssd"},
+			Capacity: types.Resources{MilliCPU: 64000, Memory: 256 * gi},
+		},
+	}
+}
+
+// ids returns the node ids of the candidates, in order.
+func ids(candidates []Candidate) []string {
+	var out []string
+	for _, c := range candidates {
+		out = append(out, c.Node.ID)
+	}
+	return out
+}
+
+// TestRank calls Rank with a range of requests and placements against a simulated inventory,
+// checking the eligible nodes are returned best first.
+func TestRank(t *testing.T) {
+	small := types.Resources{MilliCPU: 1000, Memory: 2 * gi}
+
+	tests := []struct {
+		name      string
+		requested types.Resources
+		placement types.Placement
+		want      []string
+	}{
+		{
+			name:      "least allocated first",
+			requested: small,
+			want:      []string{"eu-idle", "us-idle", "eu-busy"},
+		},
+		{
+			name:      "affinity",
+			requested: small,
+			placement: types.Placement{Affinity: map[string]string{"class": "ssd"}},
+			want:      []string{"us-idle", "eu-busy"},
+		},
+		{
+			name:      "anti-affinity",
+			requested: small,
+			placement: types.Placement{AntiAffinity: map[string]string{"region": "us-east"}},
+			want:      []string{"eu-idle", "eu-busy"},
+		},
+		{
+			name:      "affinity and anti-affinity",
+			requested: small,
+			placement: types.Placement{
+				Affinity:     map[string]string{"region": "eu-west"},
+				AntiAffinity: map[string]string{"class": "hdd"},
+			},
+			want: []string{"eu-busy"},
+		},
+		{
+			name:      "too large for busy and small nodes",
+			requested: types.Resources{MilliCPU: 5000, Memory: 16 * gi},
+			want:      []string{"eu-idle"},
+		},
+	}
+
+	for _, test := range tests {
+		got, err := Rank(inventory(), test.requested, test.placement)
+		if err != nil {
+			t.Fatalf("Rank() (%v) returned an error: \n%v", test.name, err)
+		}
+		if diff := cmp.Diff(test.want, ids(got)); diff != "" {
+			t.Fatalf("Rank() (%v) mismatch (-want +got):\n%s", test.name, diff)
+		}
+	}
+}
+
+// TestPlaceNoNode calls Place with requests no node can satisfy,
+// checking for an error in return.
+func TestPlaceNoNode(t *testing.T) {
+	if _, err := Place(inventory(), types.Resources{MilliCPU: 16000, Memory: gi}, types.Placement{}); err == nil {
+		t.Fatalf("Place() expected an insufficient capacity error, got %v", err)
+	}
+	if _, err := Place(inventory(), types.Resources{}, types.Placement{Affinity: map[string]string{"region": "ap-south"}}); err == nil {
+		t.Fatalf("Place() expected an affinity error, got %v", err)
+	}
+	if _, err := Place(nil, types.Resources{}, types.Placement{}); err == nil {
+		t.Fatalf("Place() expected an empty cluster error, got %v", err)
+	}
+}
+
+// TestDispatch calls Dispatch against a simulated node API,
+// checking the server is sent with the caller's authorization and the job returned.
+func TestDispatch(t *testing.T) {
+	server := types.Server{Name: "mytest", Size: "xs", Game: types.Game{Name: "minecraft_java"}}
+	want := types.Job{ID: "00000002", Action: "server.create", InstanceID: "00000001", Status: "pending"}
+
+	api := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var got types.Server
+		if r.Method != http.MethodPost || r.URL.Path != "/api/servers" || r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]any{"status": false, "data": "", "error": "unexpected request"})
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil || got.Name != server.Name {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]any{"status": false, "data": "", "error": "unexpected body"})
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]any{"status": true, "data": want, "error": nil})
+	}))
+	defer api.Close()
+
+	// Trust the certificate of the simulated node.
+	defaultClient := dispatchClient
+	dispatchClient = api.Client()
+	t.Cleanup(func() { dispatchClient = defaultClient })
+
+	got, err := Dispatch(context.Background(), types.Node{ID: "node", Address: api.URL}, server, "Bearer token")
+	if err != nil {
+		t.Fatalf("Dispatch() returned an error: \n%v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Dispatch() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := Dispatch(context.Background(), types.Node{ID: "node", Address: api.URL}, server, "Bearer wrong"); err == nil {
+		t.Fatalf("Dispatch() expected an error from the node, got %v", err)
+	}
+}
+
+// TestDispatchInsecure calls Dispatch with a node reached over plain http,
+// checking the credentials are only sent once the address is listed in the insecureNodes setting.
+func TestDispatchInsecure(t *testing.T) {
+	want := types.Job{ID: "00000002", Action: "server.create", InstanceID: "00000001", Status: "pending"}
+	requests := 0
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]any{"status": true, "data": want, "error": nil})
+	}))
+	defer api.Close()
+
+	n := types.Node{ID: "node", Address: api.URL}
+	if _, err := Dispatch(context.Background(), n, types.Server{}, "Bearer token"); err == nil || requests != 0 {
+		t.Fatalf("Dispatch() = %v after %v requests, want an error before any request", err, requests)
+	}
+
+	settings := config.Defaults()
+	settings.InsecureNodes = "http://elsewhere:6969, " + api.URL + "/"
+	config.SetCurrent(settings)
+	t.Cleanup(func() { config.SetCurrent(config.Defaults()) })
+
+	got, err := Dispatch(context.Background(), n, types.Server{}, "Bearer token")
+	if err != nil {
+		t.Fatalf("Dispatch() returned an error: \n%v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Dispatch() mismatch (-want +got):\n%s", diff)
+	}
+}
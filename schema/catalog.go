@@ -0,0 +1,88 @@
+package schema
+
+import (
+	"sort"
+
+	"github.com/RicochetStudios/aurora/types"
+)
+
+// sizeOrder is the order of the usual size names, from smallest to largest.
+var sizeOrder = []string{"xs", "s", "m", "l", "xl"}
+
+// Catalog returns a summary of a game schema for clients, with the sizes, versions, modloaders and
+// properties a server of the game may choose, so they can offer them without knowing the game.
+func Catalog(g Schema) types.GameSchema {
+	catalog := types.GameSchema{
+		Name:       g.Name,
+		URL:        g.URL,
+		Sizes:      []types.GameSize{},
+		Ports:      []types.GamePort{},
+		Versions:   catalogVersions(g.Versions),
+		Modloaders: []types.GameModloader{},
+		Properties: Properties(g, types.Server{}),
+	}
+	for _, name := range sizeNames(g) {
+		size := g.Sizes[name]
+		catalog.Sizes = append(catalog.Sizes, types.GameSize{
+			Name:    name,
+			Players: size.Players,
+			CPU:     size.Resources.CPU,
+			Memory:  size.Resources.Memory,
+		})
+	}
+	for _, network := range g.Network {
+		catalog.Ports = append(catalog.Ports, types.GamePort{Name: network.Name, Port: network.Port, Protocol: network.Protocol})
+	}
+	for _, modloader := range g.Modloaders {
+		catalog.Modloaders = append(catalog.Modloaders, types.GameModloader{
+			Name:     modloader.Name,
+			Versions: catalogVersions(versionsFor(g, modloader.Name)),
+			Mods:     modloader.Mods != "",
+		})
+	}
+	return catalog
+}
+
+// catalogVersions returns the versions a server may choose, without how they are applied.
+func catalogVersions(v Versions) types.GameVersions {
+	supported := v.Supported
+	if supported == nil {
+		supported = []string{}
+	}
+	return types.GameVersions{Default: v.Default, Supported: supported, Pattern: v.Pattern}
+}
+
+// sizeNames returns the names of the sizes of a game schema, the usual sizes from smallest to largest
+// followed by any others by the players they allow.
+func sizeNames(g Schema) []string {
+	names := []string{}
+	for _, name := range sizeOrder {
+		if _, ok := g.Sizes[name]; ok {
+			names = append(names, name)
+		}
+	}
+	others := []string{}
+	for name := range g.Sizes {
+		if !containsString(sizeOrder, name) {
+			others = append(others, name)
+		}
+	}
+	sort.Slice(others, func(i, j int) bool {
+		a, b := g.Sizes[others[i]], g.Sizes[others[j]]
+		if a.Players != b.Players {
+			return a.Players < b.Players
+		}
+		return others[i] < others[j]
+	})
+	return append(names, others...)
+}
+
+// containsString returns whether a list of strings contains a string.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestCatalog calls Catalog with a schema with modloaders and an unusual size,
+// checking sizes are ordered from smallest to largest and modloaders narrow the versions of the game.
+func TestCatalog(t *testing.T) {
+	g := Schema{
+		Name: "minecraft_java",
+		URL:  "https://github.com/itzg/docker-minecraft-server",
+		Sizes: map[string]Size{
+			"huge": {Resources: Resources{CPU: "8000m", Memory: "64000Mi"}, Players: 256},
+			"s":    {Resources: Resources{CPU: "1500m", Memory: "4000Mi"}, Players: 16},
+			"xs":   {Resources: Resources{CPU: "1000m", Memory: "2000Mi"}, Players: 8},
+		},
+		Network:    []Network{{Name: "game", Port: 25565, Protocol: "tcp"}},
+		Settings:   []Setting{{Name: "EULA", Value: "TRUE"}},
+		Properties: []Property{{Name: "pvp", Type: PropertyBool, Description: "Whether players can damage each other.", Default: "true", Env: "PVP"}},
+		Versions:   Versions{Default: "LATEST", Supported: []string{"LATEST"}, Pattern: `^1\.\d+(\.\d+)?$`, Image: "itzg/minecraft-server:{{ .version }}"},
+		Modloaders: []Modloader{
+			{Name: "vanilla"},
+			{Name: "forge", Versions: Versions{Default: "1.20.1", Supported: []string{}}, Mods: "/data/mods"},
+		},
+	}
+
+	want := types.GameSchema{
+		Name: "minecraft_java",
+		URL:  "https://github.com/itzg/docker-minecraft-server",
+		Sizes: []types.GameSize{
+			{Name: "xs", Players: 8, CPU: "1000m", Memory: "2000Mi"},
+			{Name: "s", Players: 16, CPU: "1500m", Memory: "4000Mi"},
+			{Name: "huge", Players: 256, CPU: "8000m", Memory: "64000Mi"},
+		},
+		Ports:    []types.GamePort{{Name: "game", Port: 25565, Protocol: "tcp"}},
+		Versions: types.GameVersions{Default: "LATEST", Supported: []string{"LATEST"}, Pattern: `^1\.\d+(\.\d+)?$`},
+		Modloaders: []types.GameModloader{
+			{Name: "vanilla", Versions: types.GameVersions{Default: "LATEST", Supported: []string{"LATEST"}, Pattern: `^1\.\d+(\.\d+)?$`}},
+			{Name: "forge", Versions: types.GameVersions{Default: "1.20.1", Supported: []string{}, Pattern: `^1\.\d+(\.\d+)?$`}, Mods: true},
+		},
+		Properties: []types.Property{{Name: "pvp", Type: PropertyBool, Description: "Whether players can damage each other.", Default: true, Value: true}},
+	}
+
+	if diff := cmp.Diff(want, Catalog(g)); diff != "" {
+		t.Fatalf("Catalog() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,190 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// baseDir is the directory of the schema directory holding base schemas, which are not games themselves.
+const baseDir string = "base"
+
+// readBase reads the yaml of a base schema, which is the schema of another game,
+// or otherwise a file of the base directory e.g. base/source.yaml. It returns the path
+// of the file it was read from, relative to the schema directory.
+func readBase(name string) ([]byte, string, error) {
+	// The name is used in the file path, so it must not be able to escape the schema directory.
+	if !regexp.MustCompile(gameRegex).MatchString(name) {
+		return nil, "", fmt.Errorf("base name %q is not valid", name)
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		return nil, "", err
+	}
+	file := path.Join(name, "schema.yaml")
+	content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+	if errors.Is(err, fs.ErrNotExist) {
+		file = path.Join(baseDir, name+".yaml")
+		content, err = os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, "", fmt.Errorf("base %v does not exist", name)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading base %v: %v", name, err)
+	}
+	return content, file, nil
+}
+
+// extend merges the yaml document of a schema over the base it extends, and any base that extends in turn,
+// returning the merged document. The nodes of each base are recorded in files with the file they were read from,
+// so the problems they cause are reported there. Problems with a base itself are returned as ValidationErrors of its file.
+func extend(root *yaml.Node, name string, load func(name string) ([]byte, string, error), files map[*yaml.Node]string, seen map[string]bool) (*yaml.Node, error) {
+	if seen[name] {
+		return nil, fmt.Errorf("base %v extends itself", name)
+	}
+	seen[name] = true
+
+	content, file, err := load(name)
+	if err != nil {
+		return nil, err
+	}
+	base, g, errs, err := decode(content)
+	if err != nil {
+		errs = ValidationErrors{yamlError(err)}
+	}
+	for i := range errs {
+		errs[i].File = file
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	mark(base, file, files)
+
+	if g.Extends != "" {
+		extended, err := extend(base, g.Extends, load, files, seen)
+		var problems ValidationErrors
+		if errors.As(err, &problems) {
+			return nil, problems
+		} else if err != nil {
+			extends := nodeOf(base, []any{"extends"})
+			return nil, ValidationErrors{{File: file, Line: extends.Line, Field: "extends", Message: err.Error()}}
+		}
+		base = extended
+	}
+
+	// An empty base adds nothing.
+	if len(base.Content) == 0 {
+		return root, nil
+	}
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{merge(base.Content[0], root.Content[0])}}, nil
+}
+
+// merge merges a yaml node of a schema over the node of its base:
+//   - mappings are merged by key, merging the values of keys both have,
+//   - lists of named items, such as network, settings and modloaders, are merged by name,
+//     merging items both have in the place of the base item and adding new items after those of the base,
+//   - anything else, including other lists, empty lists and null, replaces the base.
+//
+// Merged mappings and lists are the node of the schema with its content replaced,
+// so they keep the file they were read from.
+func merge(base, node *yaml.Node) *yaml.Node {
+	switch {
+	case base.Kind == yaml.MappingNode && node.Kind == yaml.MappingNode:
+		var content []*yaml.Node
+		used := map[int]bool{}
+		for i := 0; i+1 < len(base.Content); i += 2 {
+			j := keyIndex(node, base.Content[i].Value)
+			if j == -1 {
+				content = append(content, base.Content[i], base.Content[i+1])
+				continue
+			}
+			used[j] = true
+			content = append(content, node.Content[j], merge(base.Content[i+1], node.Content[j+1]))
+		}
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if !used[j] {
+				content = append(content, node.Content[j], node.Content[j+1])
+			}
+		}
+		node.Content = content
+		return node
+
+	case base.Kind == yaml.SequenceNode && node.Kind == yaml.SequenceNode && named(base) && named(node):
+		var content []*yaml.Node
+		used := map[int]bool{}
+		for _, item := range base.Content {
+			j := itemIndex(node, itemName(item))
+			if j == -1 {
+				content = append(content, item)
+				continue
+			}
+			used[j] = true
+			content = append(content, merge(item, node.Content[j]))
+		}
+		for j, item := range node.Content {
+			if !used[j] {
+				content = append(content, item)
+			}
+		}
+		node.Content = content
+		return node
+	}
+	return node
+}
+
+// named returns whether a yaml node is a list which is not empty, holding only mappings with a name.
+func named(node *yaml.Node) bool {
+	if len(node.Content) == 0 {
+		return false
+	}
+	for _, item := range node.Content {
+		if item.Kind != yaml.MappingNode || itemName(item) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// itemName returns the name of a mapping in a list, or nothing if it has none.
+func itemName(item *yaml.Node) string {
+	if i := keyIndex(item, "name"); i != -1 && item.Content[i+1].Kind == yaml.ScalarNode {
+		return item.Content[i+1].Value
+	}
+	return ""
+}
+
+// itemIndex returns the index of the item of a list with a name, or -1 if it has none.
+func itemIndex(node *yaml.Node, name string) int {
+	for i, item := range node.Content {
+		if itemName(item) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// keyIndex returns the index of a key of a mapping, or -1 if it does not have the key.
+func keyIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// mark records the file of a yaml node and everything in it.
+func mark(node *yaml.Node, file string, files map[*yaml.Node]string) {
+	files[node] = file
+	for _, child := range node.Content {
+		mark(child, file, files)
+	}
+}
@@ -0,0 +1,196 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testBases are base schemas by name, read by validate in place of the schema directory.
+var testBases map[string]string = map[string]string{
+	"source": `image: cm2network/steamcmd:latest
+sizes:
+  s:
+    resources:
+      cpu: 1000m
+      memory: 2000Mi
+    players: 12
+  m:
+    resources:
+      cpu: 2000m
+      memory: 4000Mi
+    players: 24
+network:
+  - name: game
+    port: 27015
+    protocol: udp
+  - name: rcon
+    port: 27015
+    protocol: tcp
+settings:
+  - name: SRCDS_MAXPLAYERS
+    value: "{{ .players }}"
+  - name: SRCDS_HOSTNAME
+    value: "{{ .name }}"
+volumes:
+  - name: data
+    path: /home/steam/server
+    size: 10Gi
+probes:
+  command: ["rcon", "status"]
+  livenessProbe:
+    periodSeconds: 30
+    failureThreshold: 3
+`,
+	"tf2": `extends: source
+name: tf2
+settings:
+  - name: SRCDS_GAME
+    value: tf
+`,
+	"broken": `image: example
+sizes: [s]
+`,
+	"loop": `extends: loop
+`,
+	"nested": `extends: missing
+`,
+	"badport": `extends: source
+network:
+  - name: tv
+    port: 70000
+    protocol: udp
+`,
+}
+
+// loadTestBase reads a base schema from testBases, as if it were a file of the base directory.
+func loadTestBase(name string) ([]byte, string, error) {
+	content, ok := testBases[name]
+	if !ok {
+		return nil, "", fmt.Errorf("base %v does not exist", name)
+	}
+	return []byte(content), "base/" + name + ".yaml", nil
+}
+
+// TestExtend calls validate with a schema extending a base which extends another,
+// checking mappings are merged by key, lists of named items by name and anything else is replaced.
+func TestExtend(t *testing.T) {
+	content := `name: tf2_competitive
+extends: tf2
+sizes:
+  m:
+    players: 18
+  l:
+    resources:
+      cpu: 3000m
+      memory: 8000Mi
+    players: 32
+network:
+  - name: rcon
+    port: 27020
+  - name: tv
+    port: 27020
+    protocol: udp
+settings:
+  - name: SRCDS_GAME
+    value: tf_competitive
+probes:
+  command: ["rcon", "ping"]
+  livenessProbe:
+    periodSeconds: 10
+volumes: []
+`
+
+	want := Schema{
+		Name:    "tf2_competitive",
+		Extends: "tf2",
+		Image:   "cm2network/steamcmd:latest",
+		Sizes: map[string]Size{
+			"s": {Resources: Resources{CPU: "1000m", Memory: "2000Mi"}, Players: 12},
+			"m": {Resources: Resources{CPU: "2000m", Memory: "4000Mi"}, Players: 18},
+			"l": {Resources: Resources{CPU: "3000m", Memory: "8000Mi"}, Players: 32},
+		},
+		Network: []Network{
+			{Name: "game", Port: 27015, Protocol: "udp"},
+			{Name: "rcon", Port: 27020, Protocol: "tcp"},
+			{Name: "tv", Port: 27020, Protocol: "udp"},
+		},
+		Settings: []Setting{
+			{Name: "SRCDS_MAXPLAYERS", Value: "{{ .players }}"},
+			{Name: "SRCDS_HOSTNAME", Value: "{{ .name }}"},
+			{Name: "SRCDS_GAME", Value: "tf_competitive"},
+		},
+		Volumes: []Volume{},
+		Probes: Probes{
+			Command:       []string{"rcon", "ping"},
+			LivenessProbe: Probe{PeriodSeconds: 10, FailureThreshold: 3},
+		},
+	}
+
+	got, err := validate([]byte(content), loadTestBase)
+	if err != nil {
+		t.Fatalf("validate() returned an error: \n%v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("validate() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestExtendErrors calls validate with schemas extending bases which are missing, not valid or extend themselves,
+// and fields which are not valid in the schema and its bases, checking each problem is reported in the file it is in.
+func TestExtendErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		want    ValidationErrors
+	}{
+		{
+			"name: tf2\nextends: missing\n",
+			ValidationErrors{{Line: 2, Field: "extends", Message: "base missing does not exist"}},
+		},
+		{
+			"name: tf2\nextends: broken\n",
+			ValidationErrors{{File: "base/broken.yaml", Line: 2, Message: "cannot unmarshal !!seq into map[string]schema.Size"}},
+		},
+		{
+			"name: tf2\nextends: loop\n",
+			ValidationErrors{{File: "base/loop.yaml", Line: 1, Field: "extends", Message: "base loop extends itself"}},
+		},
+		{
+			"name: tf2\nextends: nested\n",
+			ValidationErrors{{File: "base/nested.yaml", Line: 1, Field: "extends", Message: "base missing does not exist"}},
+		},
+		{
+			"name: tf2\nextends: badport\n",
+			ValidationErrors{{File: "base/badport.yaml", Line: 4, Field: "network[2].port", Message: "must be between 1 and 65535, not 70000"}},
+		},
+		{
+			"name: tf2\nextends: source\nnetwork:\n  - name: rcon\n    protocol: udp\n",
+			ValidationErrors{{File: "base/source.yaml", Line: 18, Field: "network[1].port", Message: "27015/udp is used by another port"}},
+		},
+		{
+			"name: tf2\nextends: source\nnetwork:\n  - name: tv\n    port: 27015\n    protocol: udp\n",
+			ValidationErrors{{Line: 5, Field: "network[2].port", Message: "27015/udp is used by another port"}},
+		},
+		{
+			"name: tf2\nextends: source\nsizes:\n  s:\n    players: 0\n",
+			ValidationErrors{{Line: 5, Field: "sizes.s.players", Message: "must be at least 1, not 0"}},
+		},
+		{
+			"name: tf2\n\nextends: source\nsettings:\n  - name: SRCDS_HOSTNAME\n    value: \"{{ .motd }}\"\n  - name: SRCDS_MAXPLAYERS\n",
+			ValidationErrors{{Line: 6, Field: "settings[1].value", Message: `template "{{ .motd }}" must be the whole value and one of {{ .modloader }}, {{ .modloaderVersion }}, {{ .name }}, {{ .players }}, {{ .version }}`}},
+		},
+	}
+
+	for _, test := range tests {
+		_, err := validate([]byte(test.content), loadTestBase)
+		var got ValidationErrors
+		if !errors.As(err, &got) {
+			t.Fatalf("validate(%q) returned %v, want ValidationErrors", test.content, err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Fatalf("validate(%q) mismatch (-want +got):\n%s", test.content, diff)
+		}
+	}
+}
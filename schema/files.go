@@ -0,0 +1,56 @@
+package schema
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Files are the rules the file manager follows for the volumes of a server.
+type Files struct {
+	Writable []string `yaml:"writable"` // Patterns of paths which may be changed, every other path being read-only. Every path may be changed when empty.
+}
+
+// VolumePath cleans an absolute path of a server, returning an error unless it is inside one of the volumes of its schema.
+func VolumePath(g Schema, p string) (string, error) {
+	clean := path.Clean(p)
+	if !path.IsAbs(p) || strings.Contains(p, "\\") {
+		return "", fmt.Errorf("path %q must be absolute", p)
+	}
+	for _, volume := range g.Volumes {
+		root := path.Clean(volume.Path)
+		if clean == root || strings.HasPrefix(clean, root+"/") {
+			return clean, nil
+		}
+	}
+	return "", fmt.Errorf("path %q is not inside a volume of %v", p, g.Name)
+}
+
+// Writable returns whether the file manager may change a clean path inside a volume of a server.
+// The volumes themselves may not be changed. Patterns are matched with path.Match,
+// and a pattern ending in /** also matches everything inside the directories matching the rest of it.
+func Writable(g Schema, p string) bool {
+	for _, volume := range g.Volumes {
+		if p == path.Clean(volume.Path) {
+			return false
+		}
+	}
+	if len(g.Files.Writable) == 0 {
+		return true
+	}
+
+	for _, pattern := range g.Files.Writable {
+		if dir, found := strings.CutSuffix(pattern, "/**"); found {
+			for parent := path.Dir(p); parent != "/"; parent = path.Dir(parent) {
+				if matched, _ := path.Match(dir, parent); matched {
+					return true
+				}
+			}
+			continue
+		}
+		if matched, _ := path.Match(pattern, p); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package schema
+
+import (
+	"testing"
+)
+
+// testFiles is a schema with two volumes and an allow-list of writable paths.
+var testFiles Schema = Schema{
+	Name:    "minecraft_java",
+	Volumes: []Volume{{Name: "data", Path: "/data"}, {Name: "backups", Path: "/backups/"}},
+	Files:   Files{Writable: []string{"/data/server.properties", "/data/*.json", "/data/plugins/*/**"}},
+}
+
+// TestVolumePath calls VolumePath with paths inside and outside the volumes of a schema,
+// checking paths are cleaned and only those inside a volume are accepted.
+func TestVolumePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"/data", "/data", false},
+		{"/data/config/../server.properties", "/data/server.properties", false},
+		{"/backups/daily/", "/backups/daily", false},
+		{"/data/../etc/passwd", "", true},
+		{"/database", "", true},
+		{"data/server.properties", "", true},
+		{"/data\\..\\etc", "", true},
+	}
+
+	for _, test := range tests {
+		got, err := VolumePath(testFiles, test.path)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Fatalf("VolumePath(%q) = %q, %v, want %q, error %v", test.path, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+// TestWritable calls Writable with paths of a schema with an allow-list,
+// checking only listed paths may be changed, and never the volumes themselves.
+func TestWritable(t *testing.T) {
+	tests := map[string]bool{
+		"/data":                                   false,
+		"/data/server.properties":                 true,
+		"/data/ops.json":                          true,
+		"/data/world/level.dat":                   false,
+		"/data/plugins/EssentialsX.jar":           false,
+		"/data/plugins/Essentials/config.yml":     true,
+		"/data/plugins/Essentials/userdata/a.yml": true,
+	}
+
+	for p, want := range tests {
+		if got := Writable(testFiles, p); got != want {
+			t.Fatalf("Writable(%q) = %v, want %v", p, got, want)
+		}
+	}
+
+	open := Schema{Volumes: testFiles.Volumes}
+	if !Writable(open, "/data/world/level.dat") || Writable(open, "/backups") {
+		t.Fatalf("Writable() without an allow-list should allow every path but the volumes")
+	}
+}
@@ -0,0 +1,95 @@
+package schema
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// meta is the JSON Schema of game schemas, generated from the Schema type by GenerateMeta.
+// Regenerate it with `go test ./schema -run 'TestMeta$' -update` after changing the type.
+//
+//go:embed meta.json
+var meta []byte
+
+// Meta returns the JSON Schema of game schema files, which editors can check schema.yaml files with.
+func Meta() []byte {
+	return meta
+}
+
+// GenerateMeta generates the JSON Schema of game schema files from the Schema type, following the yaml names
+// of its fields. Descriptions are taken from docs, by type name or by type and field name e.g. Network.Port,
+// and fields with an enum tag may only be one of its comma separated values.
+func GenerateMeta(docs map[string]string) ([]byte, error) {
+	defs := map[string]any{}
+	root := metaType(reflect.TypeOf(Schema{}), docs, defs)
+
+	document := map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   "Aurora game schema",
+		"$ref":    root["$ref"],
+		"$defs":   defs,
+	}
+	content, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("GenerateMeta() error encoding JSON Schema: %v", err)
+	}
+	return append(content, '\n'), nil
+}
+
+// metaType returns the JSON Schema of a type, adding the definitions of the structs it uses to defs.
+func metaType(t reflect.Type, docs map[string]string, defs map[string]any) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return metaType(t.Elem(), docs, defs)
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": metaType(t.Elem(), docs, defs)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": metaType(t.Elem(), docs, defs)}
+	case reflect.Struct:
+		ref := map[string]any{"$ref": "#/$defs/" + t.Name()}
+		if _, ok := defs[t.Name()]; ok {
+			return ref
+		}
+		def := map[string]any{"type": "object", "additionalProperties": false}
+		defs[t.Name()] = def
+		if doc := docs[t.Name()]; doc != "" {
+			def["description"] = doc
+		}
+
+		properties := map[string]any{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if !field.IsExported() || name == "" || name == "-" {
+				continue
+			}
+			property := metaType(field.Type, docs, defs)
+			if doc := docs[t.Name()+"."+field.Name]; doc != "" {
+				// References may not have siblings in older drafts, so they are wrapped to be described.
+				if _, ok := property["$ref"]; ok {
+					property = map[string]any{"allOf": []any{property}}
+				}
+				property["description"] = doc
+			}
+			if enum := field.Tag.Get("enum"); enum != "" {
+				property["enum"] = strings.Split(enum, ",")
+			}
+			properties[name] = property
+		}
+		def["properties"] = properties
+		return ref
+	}
+	// Fields of any type, such as the fields of player list entries, may hold any value.
+	return map[string]any{}
+}
@@ -0,0 +1,164 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// update rewrites meta.json with the generated JSON Schema, run with `go test ./schema -run 'TestMeta$' -update`.
+var update = flag.Bool("update", false, "update meta.json")
+
+// typeDocs returns the doc comments of the struct types of the package and their fields,
+// by type name or by type and field name, as GenerateMeta takes them.
+func typeDocs(t *testing.T) map[string]string {
+	packages, err := parser.ParseDir(token.NewFileSet(), ".", func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("typeDocs() error parsing package: %v", err)
+	}
+
+	docs := map[string]string{}
+	text := func(groups ...*ast.CommentGroup) string {
+		for _, group := range groups {
+			if group != nil {
+				return strings.Join(strings.Fields(group.Text()), " ")
+			}
+		}
+		return ""
+	}
+	for _, file := range packages["schema"].Files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				structType, ok := typeSpec.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+				docs[typeSpec.Name.Name] = text(typeSpec.Doc, gen.Doc)
+				for _, field := range structType.Fields.List {
+					for _, name := range field.Names {
+						docs[typeSpec.Name.Name+"."+name.Name] = text(field.Comment, field.Doc)
+					}
+				}
+			}
+		}
+	}
+	return docs
+}
+
+// TestMeta calls GenerateMeta with the doc comments of the package,
+// checking the embedded JSON Schema is up to date with the Schema type.
+func TestMeta(t *testing.T) {
+	generated, err := GenerateMeta(typeDocs(t))
+	if err != nil {
+		t.Fatalf("GenerateMeta() returned an error: \n%v", err)
+	}
+	if *update {
+		if err := os.WriteFile("meta.json", generated, 0644); err != nil {
+			t.Fatalf("TestMeta() error updating meta.json: %v", err)
+		}
+		return
+	}
+	if diff := cmp.Diff(string(Meta()), string(generated)); diff != "" {
+		t.Fatalf("GenerateMeta() does not match meta.json, run with -update (-want +got):\n%s", diff)
+	}
+}
+
+// TestMetaSchemas checks every bundled game schema against the JSON Schema of game schemas,
+// and that a schema with an unknown field and a value outside an enum does not pass.
+func TestMetaSchemas(t *testing.T) {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("meta.json", bytes.NewReader(Meta())); err != nil {
+		t.Fatalf("AddResource() returned an error: \n%v", err)
+	}
+	meta, err := compiler.Compile("meta.json")
+	if err != nil {
+		t.Fatalf("Compile() of meta.json returned an error: \n%v", err)
+	}
+
+	games, err := Games()
+	if err != nil {
+		t.Fatalf("Games() returned an error: \n%v", err)
+	}
+	if len(games) == 0 {
+		t.Fatalf("Games() returned no games")
+	}
+	for _, game := range games {
+		content, err := os.ReadFile(filepath.Join(game, "schema.yaml"))
+		if err != nil {
+			t.Fatalf("TestMetaSchemas() error reading schema of %v: %v", game, err)
+		}
+		if problems := checkMeta(t, meta, content); len(problems) > 0 {
+			t.Fatalf("schema of %v does not match meta.json:\n%v", game, strings.Join(problems, "\n"))
+		}
+	}
+
+	invalid := []byte("name: broken\nunknown: true\nnetwork:\n  - name: game\n    port: 25565\n    protocol: sctp\n")
+	want := []string{`#/network/0/protocol: value must be one of "tcp", "udp"`, "#: additionalProperties 'unknown' not allowed"}
+	if diff := cmp.Diff(want, checkMeta(t, meta, invalid)); diff != "" {
+		t.Fatalf("checkMeta() of an invalid schema mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// checkMeta returns the problems with yaml content against the compiled JSON Schema of game schemas,
+// each as the JSON pointer of the value and what is wrong with it.
+func checkMeta(t *testing.T, meta *jsonschema.Schema, content []byte) []string {
+	// The content is decoded as JSON would be, as the validator only knows JSON values.
+	var value any
+	if err := yaml.NewDecoder(bytes.NewReader(content)).Decode(&value); err != nil {
+		t.Fatalf("checkMeta() error decoding yaml: %v", err)
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("checkMeta() error encoding json: %v", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		t.Fatalf("checkMeta() error decoding json: %v", err)
+	}
+
+	err = meta.Validate(value)
+	var validationErr *jsonschema.ValidationError
+	if err == nil {
+		return nil
+	} else if !errors.As(err, &validationErr) {
+		t.Fatalf("checkMeta() error validating: %v", err)
+	}
+
+	var problems []string
+	var collect func(err *jsonschema.ValidationError)
+	collect = func(err *jsonschema.ValidationError) {
+		if len(err.Causes) == 0 {
+			problems = append(problems, fmt.Sprintf("#%v: %v", err.InstanceLocation, err.Message))
+		}
+		for _, cause := range err.Causes {
+			collect(cause)
+		}
+	}
+	collect(validationErr)
+
+	// Problems are sorted, so they are compared regardless of the order of map keys.
+	sort.Strings(problems)
+	return problems
+}
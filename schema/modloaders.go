@@ -0,0 +1,92 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/RicochetStudios/aurora/types"
+)
+
+// Modloader is software which loads mods into a game, or vanilla for none, which servers may choose.
+type Modloader struct {
+	Name     string    `yaml:"name"`     // Name servers choose the modloader by e.g. forge.
+	Image    string    `yaml:"image"`    // Image the modloader runs instead of the schema image, with {{ .version }} replaced by the version.
+	Settings []Setting `yaml:"settings"` // Settings added for the modloader, replacing schema settings of the same name.
+	Versions Versions  `yaml:"versions"` // Versions the modloader supports, replacing each field of the schema versions which is set.
+	Mods     string    `yaml:"mods"`     // Directory the modloader loads mod or plugin jars from, if it loads any.
+}
+
+// ChooseGame checks the modloader and version a server requests are supported together by its game schema,
+// returning the game as the schema spells it, with the defaults filled in.
+func ChooseGame(g Schema, requested types.Game) (types.Game, error) {
+	modloader, err := ChooseModloader(g, requested.Modloader)
+	if err != nil {
+		return types.Game{}, err
+	}
+	version, err := ChooseVersion(g, modloader, requested.Version)
+	if err != nil {
+		return types.Game{}, err
+	}
+
+	return types.Game{Name: requested.Name, Modloader: modloader, Version: version}, nil
+}
+
+// ChooseModloader checks a requested modloader is supported by a game schema, returning it as the schema spells it.
+// The first modloader of the schema is the default, returned if none is requested.
+// Schemas which declare no modloaders accept any modloader.
+func ChooseModloader(g Schema, requested string) (string, error) {
+	if len(g.Modloaders) == 0 {
+		return requested, nil
+	}
+	if requested == "" {
+		return g.Modloaders[0].Name, nil
+	}
+	if modloader, ok := findModloader(g, requested); ok {
+		return modloader.Name, nil
+	}
+
+	var names []string
+	for _, modloader := range g.Modloaders {
+		names = append(names, modloader.Name)
+	}
+	return "", fmt.Errorf("modloader %q of %v is not supported, choose one of %v", requested, g.Name, strings.Join(names, ", "))
+}
+
+// ModsDir returns the directory the modloader of a server loads mods from,
+// or an error if it does not load mods.
+func ModsDir(g Schema, s types.Server) (string, error) {
+	modloader, _ := findModloader(g, s.Game.Modloader)
+	if modloader.Mods == "" {
+		return "", fmt.Errorf("modloader %q of %v does not load mods", s.Game.Modloader, g.Name)
+	}
+	return modloader.Mods, nil
+}
+
+// findModloader returns the modloader of a game schema with a name, regardless of case.
+func findModloader(g Schema, name string) (Modloader, bool) {
+	for _, modloader := range g.Modloaders {
+		if strings.EqualFold(modloader.Name, name) {
+			return modloader, true
+		}
+	}
+	return Modloader{}, false
+}
+
+// versionsFor returns the versions of a game schema supported with a modloader.
+func versionsFor(g Schema, name string) Versions {
+	versions := g.Versions
+	modloader, _ := findModloader(g, name)
+	if modloader.Versions.Default != "" {
+		versions.Default = modloader.Versions.Default
+	}
+	if modloader.Versions.Supported != nil {
+		versions.Supported = modloader.Versions.Supported
+	}
+	if modloader.Versions.Pattern != "" {
+		versions.Pattern = modloader.Versions.Pattern
+	}
+	if modloader.Versions.Image != "" {
+		versions.Image = modloader.Versions.Image
+	}
+	return versions
+}
@@ -0,0 +1,73 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testModloaders is a schema with a catalogue of modloaders, some restricting the versions of the game.
+var testModloaders Schema = Schema{
+	Name:     "minecraft_java",
+	Image:    "itzg/minecraft-server:latest",
+	Settings: []Setting{{Name: "TYPE", Value: "{{ .modloader }}"}, {Name: "VERSION", Value: "{{ .version }}"}},
+	Versions: Versions{Default: "LATEST", Supported: []string{"LATEST", "SNAPSHOT"}, Pattern: `^1\.[0-9]+(\.[0-9]+)?$`},
+	Modloaders: []Modloader{
+		{Name: "vanilla"},
+		{Name: "forge", Settings: []Setting{{Name: "TYPE", Value: "FORGE"}, {Name: "FORGE_VERSION", Value: "{{ .modloaderVersion }}"}}, Versions: Versions{Default: "1.20.1", Supported: []string{"LATEST"}}},
+		{Name: "bedrock-bridge", Image: "example/bridge:{{ .version }}"},
+	},
+}
+
+// TestChooseGame calls ChooseGame with supported and unsupported combinations of modloader and version,
+// checking the game is returned with its defaults or an error is returned.
+func TestChooseGame(t *testing.T) {
+	tests := []struct {
+		requested types.Game
+		want      types.Game
+		wantErr   bool
+	}{
+		{types.Game{Name: "minecraft_java"}, types.Game{Name: "minecraft_java", Modloader: "vanilla", Version: "LATEST"}, false},
+		{types.Game{Name: "minecraft_java", Modloader: "Forge"}, types.Game{Name: "minecraft_java", Modloader: "forge", Version: "1.20.1"}, false},
+		{types.Game{Name: "minecraft_java", Modloader: "vanilla", Version: "snapshot"}, types.Game{Name: "minecraft_java", Modloader: "vanilla", Version: "SNAPSHOT"}, false},
+		{types.Game{Name: "minecraft_java", Modloader: "forge", Version: "SNAPSHOT"}, types.Game{}, true},
+		{types.Game{Name: "minecraft_java", Modloader: "forgee"}, types.Game{}, true},
+	}
+
+	for _, test := range tests {
+		got, err := ChooseGame(testModloaders, test.requested)
+		if (err != nil) != test.wantErr {
+			t.Fatalf("ChooseGame(%+v) returned error %v, want error %v", test.requested, err, test.wantErr)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Fatalf("ChooseGame(%+v) mismatch (-want +got):\n%s", test.requested, diff)
+		}
+	}
+}
+
+// TestEnvironmentModloader calls Environment and Image for servers with different modloaders,
+// checking the settings and image of the modloader replace those of the schema,
+// and settings templated to nothing are left out.
+func TestEnvironmentModloader(t *testing.T) {
+	forge := types.Server{Game: types.Game{Name: "minecraft_java", Modloader: "forge"}}
+	want := []Setting{{Name: "VERSION", Value: "1.20.1"}, {Name: "TYPE", Value: "FORGE"}}
+	if diff := cmp.Diff(want, Environment(testModloaders, forge)); diff != "" {
+		t.Fatalf("Environment() mismatch (-want +got):\n%s", diff)
+	}
+
+	forge.Game.ModloaderVersion = "47.2.0"
+	want = append(want, Setting{Name: "FORGE_VERSION", Value: "47.2.0"})
+	if diff := cmp.Diff(want, Environment(testModloaders, forge)); diff != "" {
+		t.Fatalf("Environment() with a modloader version mismatch (-want +got):\n%s", diff)
+	}
+
+	bridge := types.Server{Game: types.Game{Name: "minecraft_java", Modloader: "bedrock-bridge", Version: "1.20.4"}}
+	if got, want := Image(testModloaders, bridge), "example/bridge:1.20.4"; got != want {
+		t.Fatalf("Image() = %v, want %v", got, want)
+	}
+	if got := Image(testModloaders, forge); got != testModloaders.Image {
+		t.Fatalf("Image() = %v, want the schema image %v", got, testModloaders.Image)
+	}
+}
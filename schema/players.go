@@ -0,0 +1,96 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/RicochetStudios/aurora/types"
+)
+
+const (
+	// PlayersJSON is the format of player list files holding a JSON array of objects,
+	// each with a name field and optionally uuid and reason fields.
+	PlayersJSON string = "json"
+
+	// PlayersLines is the format of player list files holding one player name per line.
+	PlayersLines string = "lines"
+
+	// playerTemplate is replaced by the name of a player in the commands of a player list.
+	playerTemplate string = "{{ .player }}"
+
+	// reasonTemplate is replaced by the reason a player is added in the commands of a player list.
+	reasonTemplate string = "{{ .reason }}"
+
+	// defaultPlayerPattern is the regular expression player names must match when a schema does not declare one.
+	defaultPlayerPattern string = `^[A-Za-z0-9_.\-]{1,64}$`
+)
+
+// Players are the lists of players a game keeps, such as its allow list, operators and bans, and how they are changed.
+type Players struct {
+	Pattern string       `yaml:"pattern"` // Regular expression player names must match, so they can not change the commands they are passed to.
+	Lists   []PlayerList `yaml:"lists"`   // Lists of players servers may manage.
+}
+
+// PlayerList is a list of players a game keeps. Players are added and removed with commands while the
+// server is running, such as through its console, and by changing its file while the server is stopped.
+type PlayerList struct {
+	Name   string         `yaml:"name"`                     // Name the list is managed by e.g. bans.
+	Add    []string       `yaml:"add"`                      // Command which adds a player to the list of a running server, with {{ .player }} and {{ .reason }} replaced.
+	Remove []string       `yaml:"remove"`                   // Command which removes a player from the list of a running server, with {{ .player }} replaced.
+	File   string         `yaml:"file"`                     // File the game keeps the list in, which is read to list it.
+	Format string         `yaml:"format" enum:"json,lines"` // Format of the file, json or lines.
+	Fields map[string]any `yaml:"fields"`                   // Fields added to the entries of json files for new players, such as the level of operators.
+}
+
+// FindPlayerList returns the player list of a game schema with a name, or an error if it has none.
+func FindPlayerList(g Schema, name string) (PlayerList, error) {
+	var names []string
+	for _, list := range g.Players.Lists {
+		if list.Name == name {
+			return list, nil
+		}
+		names = append(names, list.Name)
+	}
+	if len(names) == 0 {
+		return PlayerList{}, fmt.Errorf("%v has no player lists", g.Name)
+	}
+	return PlayerList{}, fmt.Errorf("player list %q of %v does not exist, choose one of %v", name, g.Name, strings.Join(names, ", "))
+}
+
+// CheckPlayer returns an error unless the name of a player matches the pattern of a game schema,
+// and the reason it is added is a single line of printable text.
+func CheckPlayer(g Schema, player types.Player) error {
+	pattern := g.Players.Pattern
+	if pattern == "" {
+		pattern = defaultPlayerPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("CheckPlayer() player pattern of %v is not valid: %v", g.Name, err)
+	}
+	if !re.MatchString(player.Name) {
+		return fmt.Errorf("player name %q of %v must match %v", player.Name, g.Name, pattern)
+	}
+	for _, r := range player.Reason {
+		if !unicode.IsPrint(r) {
+			return fmt.Errorf("reason %q must be a single line of text", player.Reason)
+		}
+	}
+	return nil
+}
+
+// PlayerCommand returns a command of a player list with the name of a player and the reason it is added
+// in place of their templates. Arguments which are only a template for a reason which is not given are left out.
+func PlayerCommand(command []string, player types.Player) []string {
+	replaced := []string{}
+	for _, arg := range command {
+		if arg == reasonTemplate && player.Reason == "" {
+			continue
+		}
+		arg = strings.ReplaceAll(arg, playerTemplate, player.Name)
+		replaced = append(replaced, strings.ReplaceAll(arg, reasonTemplate, player.Reason))
+	}
+	return replaced
+}
@@ -0,0 +1,47 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestCheckPlayer calls CheckPlayer with valid and invalid players,
+// checking names must match the pattern of the schema and reasons must be a single line.
+func TestCheckPlayer(t *testing.T) {
+	g := Schema{Name: "minecraft_java", Players: Players{Pattern: `^[A-Za-z0-9_]{3,16}$`}}
+
+	tests := []struct {
+		player  types.Player
+		wantErr bool
+	}{
+		{types.Player{Name: "Notch"}, false},
+		{types.Player{Name: "griefer", Reason: "Griefing, twice"}, false},
+		{types.Player{Name: "a b"}, true},
+		{types.Player{Name: "me; stop"}, true},
+		{types.Player{Name: "griefer", Reason: "Griefing\nstop"}, true},
+	}
+
+	for _, test := range tests {
+		if err := CheckPlayer(g, test.player); (err != nil) != test.wantErr {
+			t.Fatalf("CheckPlayer(%+v) returned error %v, want error %v", test.player, err, test.wantErr)
+		}
+	}
+}
+
+// TestPlayerCommand calls PlayerCommand with and without a reason,
+// checking the templates are replaced and a reason which is not given is left out.
+func TestPlayerCommand(t *testing.T) {
+	command := []string{"rcon-cli", "ban", "{{ .player }}", "{{ .reason }}"}
+
+	want := []string{"rcon-cli", "ban", "griefer", "Griefing"}
+	if diff := cmp.Diff(want, PlayerCommand(command, types.Player{Name: "griefer", Reason: "Griefing"})); diff != "" {
+		t.Fatalf("PlayerCommand() mismatch (-want +got):\n%s", diff)
+	}
+	want = []string{"rcon-cli", "ban", "griefer"}
+	if diff := cmp.Diff(want, PlayerCommand(command, types.Player{Name: "griefer"})); diff != "" {
+		t.Fatalf("PlayerCommand() without a reason mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,213 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/RicochetStudios/aurora/types"
+)
+
+const (
+	// PropertyString is the type of properties which may be any text.
+	PropertyString string = "string"
+
+	// PropertyBool is the type of properties which are true or false.
+	PropertyBool string = "bool"
+
+	// PropertyInt is the type of properties which are whole numbers, optionally between a minimum and maximum.
+	PropertyInt string = "int"
+
+	// PropertyEnum is the type of properties which are one of a list of values.
+	PropertyEnum string = "enum"
+)
+
+// Property is a setting of the game which servers may change, and where the game reads it from.
+// It is passed to the server as an environment variable, or written to a key of a properties file.
+type Property struct {
+	Name        string   `yaml:"name"`                             // Name servers change the property by e.g. view-distance.
+	Type        string   `yaml:"type" enum:"string,bool,int,enum"` // Type of the value, one of string, bool, int or enum.
+	Description string   `yaml:"description"`                      // What the property changes in the game.
+	Default     string   `yaml:"default"`                          // Value the game uses when the server has not changed it.
+	Values      []string `yaml:"values"`                           // Values an enum property may have.
+	Min         *int     `yaml:"min"`                              // Smallest value of an int property, if it has one.
+	Max         *int     `yaml:"max"`                              // Largest value of an int property, if it has one.
+	Env         string   `yaml:"env"`                              // Environment variable the property is passed as.
+	File        string   `yaml:"file"`                             // Properties file the property is written to, instead of an environment variable.
+	Key         string   `yaml:"key"`                              // Key of the property in its properties file.
+}
+
+// ChangeProperties checks changes to the properties of a server against the declarations of its game schema,
+// returning the properties the server has after them. Values may be given as JSON values or strings,
+// and are stored as strings. A null value resets a property to its default. Properties the schema
+// no longer declares are left out.
+func ChangeProperties(g Schema, current map[string]string, changes map[string]any) (map[string]string, error) {
+	next := map[string]string{}
+	for name, value := range current {
+		if _, ok := findProperty(g, name); ok {
+			next[name] = value
+		}
+	}
+
+	// Changes are checked in order of their names, so the same request always returns the same error.
+	names := make([]string, 0, len(changes))
+	for name := range changes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property, ok := findProperty(g, name)
+		if !ok {
+			return nil, fmt.Errorf("property %q of %v does not exist", name, g.Name)
+		}
+		if changes[name] == nil {
+			delete(next, name)
+			continue
+		}
+		value, err := property.parse(changes[name])
+		if err != nil {
+			return nil, fmt.Errorf("property %q of %v %v", name, g.Name, err)
+		}
+		next[name] = value
+	}
+	return next, nil
+}
+
+// Properties returns the properties of a game schema with the values a server has chosen,
+// or their defaults, typed as the schema declares them.
+func Properties(g Schema, s types.Server) []types.Property {
+	properties := make([]types.Property, len(g.Properties))
+	for i, property := range g.Properties {
+		value, ok := s.Properties[property.Name]
+		if !ok {
+			value = property.Default
+		}
+		properties[i] = types.Property{
+			Name:        property.Name,
+			Type:        property.Type,
+			Description: property.Description,
+			Default:     property.typed(property.Default),
+			Values:      property.Values,
+			Min:         property.Min,
+			Max:         property.Max,
+			Value:       property.typed(value),
+			Changed:     ok,
+		}
+	}
+	return properties
+}
+
+// PropertyFiles returns the keys and values of the properties a server has chosen which are written to
+// properties files, by the path of each file. Properties left at their default are not written.
+func PropertyFiles(g Schema, s types.Server) map[string]map[string]string {
+	files := map[string]map[string]string{}
+	for _, property := range g.Properties {
+		value, ok := s.Properties[property.Name]
+		if !ok || property.File == "" {
+			continue
+		}
+		if files[property.File] == nil {
+			files[property.File] = map[string]string{}
+		}
+		files[property.File][property.Key] = value
+	}
+	return files
+}
+
+// propertySettings returns the properties a server has chosen which are passed as environment variables.
+// Properties left at their default are not passed, so the image chooses their value.
+func propertySettings(g Schema, s types.Server) []Setting {
+	settings := []Setting{}
+	for _, property := range g.Properties {
+		if value, ok := s.Properties[property.Name]; ok && property.Env != "" {
+			settings = append(settings, Setting{Name: property.Env, Value: value})
+		}
+	}
+	return settings
+}
+
+// findProperty returns the property of a game schema with a name.
+func findProperty(g Schema, name string) (Property, bool) {
+	for _, property := range g.Properties {
+		if property.Name == name {
+			return property, true
+		}
+	}
+	return Property{}, false
+}
+
+// parse checks a JSON value or string is a valid value of the property, returning it as it is stored.
+func (p Property) parse(v any) (string, error) {
+	var text string
+	switch value := v.(type) {
+	case string:
+		text = value
+	case bool:
+		text = strconv.FormatBool(value)
+	case float64:
+		text = strconv.FormatFloat(value, 'f', -1, 64)
+	case json.Number:
+		text = value.String()
+	default:
+		return "", fmt.Errorf("must be a %v, not %v", p.Type, v)
+	}
+
+	switch p.Type {
+	case PropertyBool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return "", fmt.Errorf("must be true or false, not %q", text)
+		}
+		return strconv.FormatBool(b), nil
+
+	case PropertyInt:
+		i, err := strconv.Atoi(text)
+		if err != nil {
+			return "", fmt.Errorf("must be a whole number, not %q", text)
+		}
+		if p.Min != nil && i < *p.Min {
+			return "", fmt.Errorf("must be at least %v, not %v", *p.Min, i)
+		}
+		if p.Max != nil && i > *p.Max {
+			return "", fmt.Errorf("must be at most %v, not %v", *p.Max, i)
+		}
+		return strconv.Itoa(i), nil
+
+	case PropertyEnum:
+		// Values are matched regardless of case, and stored as the schema spells them.
+		for _, allowed := range p.Values {
+			if strings.EqualFold(text, allowed) {
+				return allowed, nil
+			}
+		}
+		return "", fmt.Errorf("must be one of %v, not %q", strings.Join(p.Values, ", "), text)
+
+	case PropertyString:
+		// Values are written to a single line of environment variables and properties files.
+		if strings.ContainsAny(text, "\r\n") {
+			return "", fmt.Errorf("must be a single line")
+		}
+		return text, nil
+	}
+
+	return "", fmt.Errorf("has unknown type %q", p.Type)
+}
+
+// typed returns a stored value of the property as the JSON value of its type,
+// or the value as it is if it is not valid for the type.
+func (p Property) typed(value string) any {
+	switch p.Type {
+	case PropertyBool:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case PropertyInt:
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+	}
+	return value
+}
@@ -0,0 +1,84 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testProperties is a schema declaring a property of each type, passed as environment variables or written to a file.
+var testProperties Schema = Schema{
+	Name:     "minecraft_java",
+	Settings: []Setting{{Name: "EULA", Value: "TRUE"}, {Name: "PVP", Value: "false"}},
+	Properties: []Property{
+		{Name: "difficulty", Type: PropertyEnum, Default: "easy", Values: []string{"peaceful", "easy", "normal", "hard"}, Env: "DIFFICULTY"},
+		{Name: "pvp", Type: PropertyBool, Default: "true", Env: "PVP"},
+		{Name: "view-distance", Type: PropertyInt, Default: "10", Min: intPtr(3), Max: intPtr(32), Env: "VIEW_DISTANCE"},
+		{Name: "motd", Type: PropertyString, File: "/data/server.properties", Key: "motd"},
+	},
+}
+
+// intPtr returns a pointer to an int, for the bounds of int properties.
+func intPtr(i int) *int {
+	return &i
+}
+
+// TestChangeProperties calls ChangeProperties with valid and invalid changes,
+// checking values are stored as strings the schema spells, nulls reset properties and invalid values return an error.
+func TestChangeProperties(t *testing.T) {
+	current := map[string]string{"pvp": "false", "removed": "1", "view-distance": "12"}
+
+	tests := []struct {
+		changes map[string]any
+		want    map[string]string
+		wantErr bool
+	}{
+		{nil, map[string]string{"pvp": "false", "view-distance": "12"}, false},
+		{map[string]any{"difficulty": "Hard", "pvp": true, "view-distance": float64(20)}, map[string]string{"difficulty": "hard", "pvp": "true", "view-distance": "20"}, false},
+		{map[string]any{"view-distance": "8", "pvp": nil, "motd": "hello"}, map[string]string{"motd": "hello", "view-distance": "8"}, false},
+		{map[string]any{"difficulty": "impossible"}, nil, true},
+		{map[string]any{"view-distance": float64(64)}, nil, true},
+		{map[string]any{"view-distance": 10.5}, nil, true},
+		{map[string]any{"pvp": "sometimes"}, nil, true},
+		{map[string]any{"motd": "two\nlines"}, nil, true},
+		{map[string]any{"gamemode": "creative"}, nil, true},
+	}
+
+	for _, test := range tests {
+		got, err := ChangeProperties(testProperties, current, test.changes)
+		if (err != nil) != test.wantErr {
+			t.Fatalf("ChangeProperties(%v) returned error %v, want error %v", test.changes, err, test.wantErr)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Fatalf("ChangeProperties(%v) mismatch (-want +got):\n%s", test.changes, diff)
+		}
+	}
+}
+
+// TestProperties calls Properties, Environment and PropertyFiles for a server which has changed some properties,
+// checking changed values are typed and passed where the schema declares, replacing settings of the same name.
+func TestProperties(t *testing.T) {
+	server := types.Server{Properties: map[string]string{"pvp": "true", "view-distance": "16", "motd": "hello"}}
+
+	want := []types.Property{
+		{Name: "difficulty", Type: PropertyEnum, Default: "easy", Values: []string{"peaceful", "easy", "normal", "hard"}, Value: "easy"},
+		{Name: "pvp", Type: PropertyBool, Default: true, Value: true, Changed: true},
+		{Name: "view-distance", Type: PropertyInt, Default: 10, Min: intPtr(3), Max: intPtr(32), Value: 16, Changed: true},
+		{Name: "motd", Type: PropertyString, Default: "", Value: "hello", Changed: true},
+	}
+	if diff := cmp.Diff(want, Properties(testProperties, server)); diff != "" {
+		t.Fatalf("Properties() mismatch (-want +got):\n%s", diff)
+	}
+
+	wantSettings := []Setting{{Name: "EULA", Value: "TRUE"}, {Name: "PVP", Value: "true"}, {Name: "VIEW_DISTANCE", Value: "16"}}
+	if diff := cmp.Diff(wantSettings, Environment(testProperties, server)); diff != "" {
+		t.Fatalf("Environment() mismatch (-want +got):\n%s", diff)
+	}
+
+	wantFiles := map[string]map[string]string{"/data/server.properties": {"motd": "hello"}}
+	if diff := cmp.Diff(wantFiles, PropertyFiles(testProperties, server)); diff != "" {
+		t.Fatalf("PropertyFiles() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -1,12 +1,30 @@
 package schema
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 
-	"gopkg.in/yaml.v3"
+	"github.com/RicochetStudios/aurora/types"
 )
 
+const (
+	// gameRegex is a regular expression to validate game names, which are also directory names.
+	gameRegex string = `^[a-z0-9_]+$`
+
+	// versionTemplate is replaced by the version of a server in the image of its version.
+	versionTemplate string = "{{ .version }}"
+
+	// templateRegex is a regular expression to validate templates.
+	templateRegex string = `^{{ (?P<tpl>(\.\w+)*) }}$`
+)
+
+// Sizes are the resources and players of each size a server may be.
 type Sizes struct {
 	XS Size `yaml:"xs"`
 	S  Size `yaml:"s"`
@@ -15,90 +33,316 @@ type Sizes struct {
 	XL Size `yaml:"xl"`
 }
 
+// Size is a scale a server may run at, which servers choose by its name e.g. xs.
 type Size struct {
-	Resources Resources `yaml:"resources"`
-	Players   int       `yaml:"players"`
+	Resources Resources `yaml:"resources"` // Resources reserved for a server of the size.
+	Players   int       `yaml:"players"`   // Most players a server of the size allows at once.
 }
 
+// Resources are the compute a server is given.
 type Resources struct {
-	CPU    string `yaml:"cpu"`
-	Memory string `yaml:"memory"`
+	CPU    string `yaml:"cpu"`    // CPU as a Kubernetes quantity e.g. 1500m.
+	Memory string `yaml:"memory"` // Memory as a Kubernetes quantity e.g. 4000Mi.
 }
 
+// Network is a port the game listens on.
 type Network struct {
-	Name     string `yaml:"name"`
-	Port     int    `yaml:"port"`
-	Protocol string `yaml:"protocol"`
+	Name     string `yaml:"name"`                    // Name of the port e.g. game.
+	Port     int    `yaml:"port"`                    // Port number, between 1 and 65535.
+	Protocol string `yaml:"protocol" enum:"tcp,udp"` // Protocol of the port.
 }
 
+// Setting is an environment variable passed to the server.
 type Setting struct {
-	Name  string `yaml:"name"`
-	Value string `yaml:"value"`
+	Name  string `yaml:"name"`  // Name of the environment variable, or a template.
+	Value string `yaml:"value"` // Value of the environment variable, or a template such as {{ .players }}.
 }
 
+// Volume is a directory of the server kept when it is stopped or updated.
 type Volume struct {
-	Name  string `yaml:"name"`
-	Path  string `yaml:"path"`
-	Class string `yaml:"class"`
-	Size  string `yaml:"size"`
+	Name  string `yaml:"name"`  // Name of the volume e.g. data.
+	Path  string `yaml:"path"`  // Absolute path the volume is mounted at.
+	Class string `yaml:"class"` // Kubernetes storage class of the volume.
+	Size  string `yaml:"size"`  // Size of the volume as a Kubernetes quantity e.g. 10Gi.
 }
 
+// Probes check the health of the server by running a command in it.
 type Probes struct {
-	Command        []string `yaml:"command"`
-	StartupProbe   Probe    `yaml:"startupProbe"`
-	ReadynessProbe Probe    `yaml:"readynessProbe"`
-	LivenessProbe  Probe    `yaml:"livenessProbe"`
+	Command        []string `yaml:"command"`        // Command which exits successfully when the server is healthy.
+	StartupProbe   Probe    `yaml:"startupProbe"`   // How long the server may take to start.
+	ReadynessProbe Probe    `yaml:"readynessProbe"` // When the server is ready for players.
+	LivenessProbe  Probe    `yaml:"livenessProbe"`  // When the server is restarted for being unhealthy.
 }
 
+// Probe is how often the health of the server is checked, and how many checks change it.
 type Probe struct {
-	InitialDelaySeconds int `yaml:"initialDelaySeconds"`
-	PeriodSeconds       int `yaml:"periodSeconds"`
-	FailureThreshold    int `yaml:"failureThreshold"`
-	SuccessThreshold    int `yaml:"successThreshold"`
-	TimeoutSeconds      int `yaml:"timeoutSeconds"`
+	InitialDelaySeconds int `yaml:"initialDelaySeconds"` // Seconds to wait before the first check.
+	PeriodSeconds       int `yaml:"periodSeconds"`       // Seconds between checks.
+	FailureThreshold    int `yaml:"failureThreshold"`    // Failed checks in a row before the server is unhealthy.
+	SuccessThreshold    int `yaml:"successThreshold"`    // Successful checks in a row before the server is ready.
+	TimeoutSeconds      int `yaml:"timeoutSeconds"`      // Seconds a check may take.
 }
 
+// Versions are the versions of a game a server can choose, and how the chosen version is applied.
+// A version is passed to the server with a {{ .version }} setting, such as VERSION for
+// itzg/minecraft-server, or by an image with a {{ .version }} tag.
+type Versions struct {
+	Default   string   `yaml:"default"`   // Version of servers which do not choose one.
+	Supported []string `yaml:"supported"` // Versions which may be chosen, such as aliases for the newest release.
+	Pattern   string   `yaml:"pattern"`   // Regular expression matching any other version which may be chosen.
+	Image     string   `yaml:"image"`     // Image a version runs, with {{ .version }} replaced by the version, instead of the schema image.
+}
+
+// Schema describes a game, and how servers of it are run.
 type Schema struct {
-	Name     string          `yaml:"name"`
-	Image    string          `yaml:"image"`
-	URL      string          `yaml:"url"`
-	Ratio    string          `yaml:"ratio"`
-	Sizes    map[string]Size `yaml:"sizes"`
-	Network  []Network       `yaml:"network"`
-	Settings []Setting       `yaml:"settings"`
-	Volumes  []Volume        `yaml:"volumes"`
-	Probes   Probes          `yaml:"probes"`
+	Name       string          `yaml:"name"`       // Name of the game, which is the name of the directory of the schema e.g. minecraft_java.
+	Extends    string          `yaml:"extends"`    // Base the schema is merged over, another game or a file of schema/base e.g. source.
+	Image      string          `yaml:"image"`      // Container image servers run, unless their version or modloader runs another.
+	URL        string          `yaml:"url"`        // Documentation of the image.
+	Ratio      string          `yaml:"ratio"`      // Ratio of CPU to memory of the sizes.
+	Sizes      map[string]Size `yaml:"sizes"`      // Sizes servers may choose, by name.
+	Network    []Network       `yaml:"network"`    // Ports the game listens on.
+	Settings   []Setting       `yaml:"settings"`   // Environment variables passed to every server.
+	Properties []Property      `yaml:"properties"` // Settings of the game servers may change.
+	Volumes    []Volume        `yaml:"volumes"`    // Directories kept when a server is stopped or updated, the first holding its data.
+	World      string          `yaml:"world"`      // Directory the server keeps its world in.
+	Files      Files           `yaml:"files"`      // Rules the file manager follows.
+	Players    Players         `yaml:"players"`    // Lists of players the game keeps.
+	Probes     Probes          `yaml:"probes"`     // Health checks of the server.
+	Versions   Versions        `yaml:"versions"`   // Versions of the game servers may choose.
+	Modloaders []Modloader     `yaml:"modloaders"` // Modloaders servers may choose, the first being the default.
 }
 
 // GetSchema gets a game schema from a yaml file and stores it as a Schema.
+// Schemas are validated as they are read, so a schema which could not be deployed is never used.
 func GetSchema(game string) (Schema, error) {
-	// We need to correct the directory path when testing.
-	var dir string = "/"
-	wd, err := os.Getwd()
+	// The game is used in the file path, so it must not be able to escape the schema directory.
+	if !regexp.MustCompile(gameRegex).MatchString(game) {
+		return Schema{}, fmt.Errorf("game name %q is not valid", game)
+	}
+
+	dir, err := Dir()
 	if err != nil {
 		return Schema{}, err
 	}
-	matched, err := regexp.MatchString(`/schema$`, wd)
+
+	// Load the file; returns []byte.
+	f, err := os.ReadFile(filepath.Join(dir, game, "schema.yaml"))
 	if err != nil {
 		return Schema{}, err
 	}
-	if !matched {
-		dir = "/schema/"
+
+	schema, err := Validate(f)
+	if err != nil {
+		return Schema{}, fmt.Errorf("schema of %v is not valid:\n%v", game, err)
+	}
+	if schema.Name != game {
+		return Schema{}, fmt.Errorf("schema of %v is named %q", game, schema.Name)
 	}
 
-	// Load the file; returns []byte.
-	f, err := os.ReadFile(wd + dir + game + "/schema.yaml")
+	return schema, nil
+}
+
+// Dir returns the directory holding a directory of each game schema.
+func Dir() (string, error) {
+	// We need to correct the directory path when testing.
+	wd, err := os.Getwd()
 	if err != nil {
-		return Schema{}, err
+		return "", err
 	}
+	if filepath.Base(wd) == "schema" {
+		return wd, nil
+	}
+	return filepath.Join(wd, "schema"), nil
+}
 
-	// Create an empty Schema to be are target of unmarshalling.
-	var schema Schema
+// Games returns the names of the games with a schema, in order.
+func Games() ([]string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Games() error reading schema directory: %v", err)
+	}
 
-	// Unmarshal the YAML file into empty Schema.
-	if err := yaml.Unmarshal(f, &schema); err != nil {
-		return Schema{}, err
+	games := []string{}
+	for _, entry := range entries {
+		if _, err := os.Stat(filepath.Join(dir, entry.Name(), "schema.yaml")); entry.IsDir() && err == nil {
+			games = append(games, entry.Name())
+		}
 	}
+	return games, nil
+}
 
-	return schema, nil
+// Template takes a value and resolves its template if it is a template,
+// using details of the game schema and server.
+func Template(v string, g Schema, s types.Server) string {
+	// Template the value if needed.
+	re := regexp.MustCompile(templateRegex)
+	if re.MatchString(v) {
+		// Get the template to target.
+		matches := re.FindStringSubmatch(v)
+		tplIndex := re.SubexpIndex("tpl")
+		tpl := matches[tplIndex]
+
+		// Resolve the templates.
+		switch tpl {
+		case ".name":
+			return s.Name
+		case ".modloader":
+			return s.Game.Modloader
+		case ".players":
+			return fmt.Sprint(g.Sizes[s.Size].Players)
+		case ".version":
+			return Version(g, s)
+		case ".modloaderVersion":
+			return s.Game.ModloaderVersion
+		}
+	}
+	// If it is not a template, return the original value.
+	return v
+}
+
+// Image returns the container image a server runs, which is the image it was pinned to
+// when it was deployed, or the image of its modloader and version.
+func Image(g Schema, s types.Server) string {
+	if s.Image != "" {
+		return s.Image
+	}
+	modloader, _ := findModloader(g, s.Game.Modloader)
+	if modloader.Image != "" {
+		return strings.ReplaceAll(modloader.Image, versionTemplate, Version(g, s))
+	}
+	if versions := versionsFor(g, s.Game.Modloader); versions.Image != "" {
+		return strings.ReplaceAll(versions.Image, versionTemplate, Version(g, s))
+	}
+	return g.Image
+}
+
+// Version returns the version of the game a server runs, or the default version of its modloader
+// if the server has not chosen one.
+func Version(g Schema, s types.Server) string {
+	if s.Game.Version != "" {
+		return s.Game.Version
+	}
+	return versionsFor(g, s.Game.Modloader).Default
+}
+
+// ChooseVersion checks a requested version is supported by a game schema with a modloader,
+// returning it as the schema spells it. The default version is returned if none is requested.
+// Schemas which declare no versions accept any version.
+func ChooseVersion(g Schema, modloader string, requested string) (string, error) {
+	versions := versionsFor(g, modloader)
+	if requested == "" {
+		return versions.Default, nil
+	}
+	if len(versions.Supported) == 0 && versions.Pattern == "" {
+		return requested, nil
+	}
+
+	// Aliases such as latest are matched regardless of case.
+	for _, supported := range versions.Supported {
+		if strings.EqualFold(requested, supported) {
+			return supported, nil
+		}
+	}
+	if versions.Pattern != "" {
+		re, err := regexp.Compile(versions.Pattern)
+		if err != nil {
+			return "", fmt.Errorf("ChooseVersion() version pattern of %v is not valid: %v", g.Name, err)
+		}
+		if re.MatchString(requested) {
+			return requested, nil
+		}
+	}
+
+	return "", fmt.Errorf("version %q of %v with %v is not supported, choose one of %v or a version matching %v",
+		requested, g.Name, modloader, strings.Join(versions.Supported, ", "), versions.Pattern)
+}
+
+// Environment returns the settings of a game schema with their names and values templated for a server.
+// The settings of the server's modloader are added, replacing schema settings of the same name,
+// followed by the properties the server has chosen, replacing either.
+// Settings whose template resolves to nothing are left out, so the image chooses their value.
+func Environment(g Schema, s types.Server) []Setting {
+	modloader, _ := findModloader(g, s.Game.Modloader)
+	properties := propertySettings(g, s)
+	overrides := map[string]bool{}
+	for _, setting := range properties {
+		overrides[setting.Name] = true
+	}
+	modloaderOverrides := map[string]bool{}
+	for _, setting := range modloader.Settings {
+		modloaderOverrides[setting.Name] = true
+	}
+
+	settings := []Setting{}
+	add := func(setting Setting) {
+		value := Template(setting.Value, g, s)
+		if value == "" && value != setting.Value {
+			return
+		}
+		settings = append(settings, Setting{Name: Template(setting.Name, g, s), Value: value})
+	}
+	for _, setting := range g.Settings {
+		if !overrides[setting.Name] && !modloaderOverrides[setting.Name] {
+			add(setting)
+		}
+	}
+	for _, setting := range modloader.Settings {
+		if !overrides[setting.Name] {
+			add(setting)
+		}
+	}
+	// Chosen values are never templates, so are added as they are.
+	return append(settings, properties...)
+}
+
+// DataDir returns the directory of the volume holding the data of a server, which is its first volume.
+func DataDir(g Schema) (string, error) {
+	if len(g.Volumes) == 0 {
+		return "", fmt.Errorf("%v has no data volume", g.Name)
+	}
+	return g.Volumes[0].Path, nil
+}
+
+// WorldDir returns the directory a server keeps its world in, which uploaded worlds replace.
+func WorldDir(g Schema) (string, error) {
+	if g.World == "" {
+		return "", fmt.Errorf("%v has no world directory", g.Name)
+	}
+	return g.World, nil
+}
+
+// Revision returns a short hash identifying the configuration a server is deployed with.
+// Runtimes label workloads with it, so drift from the stored server can be detected.
+// Only what the workload is made from is hashed: its image, environment, ports, volumes, resources
+// and probes. Other fields of the schema and server, such as its status or the descriptions of its properties,
+// are left out, so changing them does not recreate running servers.
+func Revision(g Schema, s types.Server) (string, error) {
+	content, err := json.Marshal(struct {
+		Image       string
+		Environment []Setting
+		Network     []Network
+		Exposure    string
+		Volumes     []Volume
+		Resources   Resources
+		Probes      Probes
+	}{
+		Image:       Image(g, s),
+		Environment: Environment(g, s),
+		Network:     g.Network,
+		Exposure:    s.Network.Type,
+		Volumes:     g.Volumes,
+		Resources:   g.Sizes[s.Size].Resources,
+		Probes:      g.Probes,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error encoding workload: \n%v", err)
+	}
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])[:16], nil
 }
@@ -3,6 +3,8 @@ package schema
 import (
 	"testing"
 
+	"github.com/RicochetStudios/aurora/types"
+
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -62,10 +64,6 @@ func TestGetSchema(t *testing.T) {
 				Name:  "EULA",
 				Value: "TRUE",
 			},
-			{
-				Name:  "TYPE",
-				Value: "{{ .modLoader }}",
-			},
 			{
 				Name:  "MAX_PLAYERS",
 				Value: "{{ .players }}",
@@ -74,6 +72,16 @@ func TestGetSchema(t *testing.T) {
 				Name:  "MOTD",
 				Value: "{{ .name }}",
 			},
+			{
+				Name:  "VERSION",
+				Value: "{{ .version }}",
+			},
+		},
+		Properties: []Property{
+			{Name: "difficulty", Type: PropertyEnum, Description: "How hard the game is, from peaceful with no hostile mobs to hard.", Default: "easy", Values: []string{"peaceful", "easy", "normal", "hard"}, Env: "DIFFICULTY"},
+			{Name: "pvp", Type: PropertyBool, Description: "Whether players can damage each other.", Default: "true", Env: "PVP"},
+			{Name: "view-distance", Type: PropertyInt, Description: "How many chunks around each player the server sends.", Default: "10", Min: intPtr(3), Max: intPtr(32), Env: "VIEW_DISTANCE"},
+			{Name: "spawn-protection", Type: PropertyInt, Description: "Radius of blocks around the spawn point only operators can change, or 0 for none.", Default: "16", Min: intPtr(0), File: "/data/server.properties", Key: "spawn-protection"},
 		},
 		Volumes: []Volume{
 			{
@@ -83,6 +91,24 @@ func TestGetSchema(t *testing.T) {
 				Size:  "10Gi",
 			},
 		},
+		World: "/data/world",
+		Files: Files{
+			Writable: []string{
+				"/data/server.properties",
+				"/data/*.json",
+				"/data/*.yml",
+				"/data/config/**",
+				"/data/plugins/*/**",
+			},
+		},
+		Players: Players{
+			Pattern: `^[A-Za-z0-9_]{3,16}$`,
+			Lists: []PlayerList{
+				{Name: "allowlist", Add: []string{"rcon-cli", "whitelist", "add", "{{ .player }}"}, Remove: []string{"rcon-cli", "whitelist", "remove", "{{ .player }}"}, File: "/data/whitelist.json", Format: PlayersJSON},
+				{Name: "ops", Add: []string{"rcon-cli", "op", "{{ .player }}"}, Remove: []string{"rcon-cli", "deop", "{{ .player }}"}, File: "/data/ops.json", Format: PlayersJSON, Fields: map[string]any{"level": 4, "bypassesPlayerLimit": false}},
+				{Name: "bans", Add: []string{"rcon-cli", "ban", "{{ .player }}", "{{ .reason }}"}, Remove: []string{"rcon-cli", "pardon", "{{ .player }}"}, File: "/data/banned-players.json", Format: PlayersJSON, Fields: map[string]any{"source": "Aurora", "expires": "forever"}},
+			},
+		},
 		Probes: Probes{
 			Command: []string{"mc-health"},
 			StartupProbe: Probe{
@@ -104,6 +130,17 @@ func TestGetSchema(t *testing.T) {
 				TimeoutSeconds:      1,
 			},
 		},
+		Versions: Versions{
+			Default:   "LATEST",
+			Supported: []string{"LATEST", "SNAPSHOT"},
+			Pattern:   `^1\.[0-9]+(\.[0-9]+)?$`,
+		},
+		Modloaders: []Modloader{
+			{Name: "vanilla", Settings: []Setting{{Name: "TYPE", Value: "VANILLA"}}},
+			{Name: "forge", Settings: []Setting{{Name: "TYPE", Value: "FORGE"}, {Name: "FORGE_VERSION", Value: "{{ .modloaderVersion }}"}}, Versions: Versions{Default: "1.20.1", Supported: []string{"LATEST"}}, Mods: "/data/mods"},
+			{Name: "fabric", Settings: []Setting{{Name: "TYPE", Value: "FABRIC"}, {Name: "FABRIC_LOADER_VERSION", Value: "{{ .modloaderVersion }}"}}, Mods: "/data/mods"},
+			{Name: "paper", Settings: []Setting{{Name: "TYPE", Value: "PAPER"}}, Versions: Versions{Supported: []string{"LATEST"}}, Mods: "/data/plugins"},
+		},
 	}
 
 	// Call the function to test.
@@ -117,3 +154,139 @@ func TestGetSchema(t *testing.T) {
 		t.Fatalf("GetSchema() mismatch (-want +got):\n%s", diff)
 	}
 }
+
+// TestRevision calls Revision with servers and schemas which differ in what the workload is made from,
+// and in fields it is not made from, checking only the first change the revision.
+func TestRevision(t *testing.T) {
+	g := Schema{
+		Name:  "minecraft_java",
+		Image: "itzg/minecraft-server:latest",
+		Sizes: map[string]Size{
+			"xs": {Resources: Resources{CPU: "1000m", Memory: "2000Mi"}, Players: 8},
+			"s":  {Resources: Resources{CPU: "1500m", Memory: "4000Mi"}, Players: 16},
+		},
+		Network:    []Network{{Name: "game", Port: 25565, Protocol: "tcp"}},
+		Settings:   []Setting{{Name: "MAX_PLAYERS", Value: "{{ .players }}"}},
+		Properties: []Property{{Name: "pvp", Type: PropertyBool, Default: "true", Env: "PVP"}},
+		Volumes:    []Volume{{Name: "data", Path: "/data", Size: "10Gi"}},
+	}
+	running := types.Server{Name: "mytest", Size: "xs", Status: "running"}
+	want := revision(t, g, running)
+
+	unchanged := map[string]func(g *Schema, s *types.Server){
+		"status":               func(g *Schema, s *types.Server) { s.Status = "stopped" },
+		"url":                  func(g *Schema, s *types.Server) { g.URL = "https://example.com" },
+		"ratio":                func(g *Schema, s *types.Server) { g.Ratio = "1-4" },
+		"other sizes":          func(g *Schema, s *types.Server) { g.Sizes["xl"] = Size{Players: 128} },
+		"property description": func(g *Schema, s *types.Server) { g.Properties[0].Description = "Whether players can fight." },
+		"players":              func(g *Schema, s *types.Server) { g.Players = Players{Pattern: "^[a-z]+$"} },
+		"files":                func(g *Schema, s *types.Server) { g.Files = Files{Writable: []string{"/data/*"}} },
+		"world":                func(g *Schema, s *types.Server) { g.World = "/data/world" },
+		"extends":              func(g *Schema, s *types.Server) { g.Extends = "base" },
+	}
+	changed := map[string]func(g *Schema, s *types.Server){
+		"size":     func(g *Schema, s *types.Server) { s.Size = "s" },
+		"image":    func(g *Schema, s *types.Server) { s.Image = "itzg/minecraft-server:java17" },
+		"property": func(g *Schema, s *types.Server) { s.Properties = map[string]string{"pvp": "false"} },
+		"port":     func(g *Schema, s *types.Server) { g.Network[0].Port = 25566 },
+		"volume":   func(g *Schema, s *types.Server) { g.Volumes[0].Size = "20Gi" },
+		"probes":   func(g *Schema, s *types.Server) { g.Probes.Command = []string{"mc-health"} },
+		"exposure": func(g *Schema, s *types.Server) { s.Network.Type = "public" },
+	}
+
+	for name, change := range unchanged {
+		g, s := copySchema(g), running
+		change(&g, &s)
+		if revision(t, g, s) != want {
+			t.Fatalf("Revision() changed with the %v", name)
+		}
+	}
+	for name, change := range changed {
+		g, s := copySchema(g), running
+		change(&g, &s)
+		if revision(t, g, s) == want {
+			t.Fatalf("Revision() did not change with the %v", name)
+		}
+	}
+}
+
+// revision returns the revision of a server, failing the test if it cannot be computed.
+func revision(t *testing.T, g Schema, s types.Server) string {
+	t.Helper()
+	revision, err := Revision(g, s)
+	if err != nil {
+		t.Fatalf("Revision() error = %v", err)
+	}
+	return revision
+}
+
+// copySchema returns a copy of a schema whose sizes, network, properties and volumes can be changed
+// without changing the original.
+func copySchema(g Schema) Schema {
+	sizes := map[string]Size{}
+	for name, size := range g.Sizes {
+		sizes[name] = size
+	}
+	g.Sizes = sizes
+	g.Network = append([]Network{}, g.Network...)
+	g.Properties = append([]Property{}, g.Properties...)
+	g.Volumes = append([]Volume{}, g.Volumes...)
+	return g
+}
+
+// TestImage calls Image with a pinned and an unpinned server,
+// checking the pinned image is preferred over the image of the schema.
+func TestImage(t *testing.T) {
+	g := Schema{Name: "minecraft_java", Image: "itzg/minecraft-server:latest"}
+	pinned := types.Server{Name: "mytest", Image: "itzg/minecraft-server@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"}
+
+	if got := Image(g, types.Server{Name: "mytest"}); got != g.Image {
+		t.Fatalf("Image() (unpinned) = %v, want %v", got, g.Image)
+	}
+	if got := Image(g, pinned); got != pinned.Image {
+		t.Fatalf("Image() (pinned) = %v, want %v", got, pinned.Image)
+	}
+}
+
+// TestChooseVersion calls ChooseVersion with supported, unsupported and missing versions,
+// checking the version is returned as the schema spells it or an error is returned.
+func TestChooseVersion(t *testing.T) {
+	g := Schema{Name: "minecraft_java", Versions: Versions{Default: "LATEST", Supported: []string{"LATEST", "SNAPSHOT"}, Pattern: `^1\.[0-9]+(\.[0-9]+)?$`}}
+
+	tests := []struct {
+		requested string
+		want      string
+		wantErr   bool
+	}{
+		{"", "LATEST", false},
+		{"latest", "LATEST", false},
+		{"1.20.1", "1.20.1", false},
+		{"1.8", "1.8", false},
+		{"2.0", "", true},
+		{"1.20.1; rm -rf /", "", true},
+	}
+
+	for _, test := range tests {
+		got, err := ChooseVersion(g, "vanilla", test.requested)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Fatalf("ChooseVersion(%q) = %q, %v, want %q and error %v", test.requested, got, err, test.want, test.wantErr)
+		}
+	}
+
+	if got, err := ChooseVersion(Schema{Name: "any"}, "", "3.1"); err != nil || got != "3.1" {
+		t.Fatalf("ChooseVersion() without versions = %q, %v, want any version", got, err)
+	}
+}
+
+// TestImageVersion calls Image for a schema with an image for each version,
+// checking the version is substituted into the image.
+func TestImageVersion(t *testing.T) {
+	g := Schema{Name: "terraria", Image: "ryshe/terraria:latest", Versions: Versions{Default: "1.4.4.9", Image: "ryshe/terraria:tshock-{{ .version }}"}}
+
+	if got, want := Image(g, types.Server{Game: types.Game{Version: "1.4.4.8"}}), "ryshe/terraria:tshock-1.4.4.8"; got != want {
+		t.Fatalf("Image() = %v, want %v", got, want)
+	}
+	if got, want := Image(g, types.Server{}), "ryshe/terraria:tshock-1.4.4.9"; got != want {
+		t.Fatalf("Image() (default version) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,488 @@
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// templates are the templates setting values may be, which Template resolves.
+var templates map[string]bool = map[string]bool{
+	"{{ .name }}":             true,
+	"{{ .modloader }}":        true,
+	"{{ .players }}":          true,
+	"{{ .version }}":          true,
+	"{{ .modloaderVersion }}": true,
+}
+
+// ValidationError is a problem with a game schema, at a line of its yaml file or of a base it extends.
+type ValidationError struct {
+	File    string // Base file the problem is in relative to the schema directory e.g. base/source.yaml, or empty for the schema itself.
+	Line    int    // Line of the yaml file the problem is on, or 0 if it is not known.
+	Field   string // Path of the field with the problem e.g. network[0].port.
+	Message string // What is wrong with the field.
+}
+
+// Error returns the problem with its file, line and field.
+func (e ValidationError) Error() string {
+	var message string
+	switch {
+	case e.Field == "":
+		message = fmt.Sprintf("line %v: %v", e.Line, e.Message)
+	case e.Line == 0:
+		message = fmt.Sprintf("%v: %v", e.Field, e.Message)
+	default:
+		message = fmt.Sprintf("line %v: %v: %v", e.Line, e.Field, e.Message)
+	}
+	if e.File != "" {
+		return e.File + ": " + message
+	}
+	return message
+}
+
+// ValidationErrors are every problem with a game schema, in the order of their files and lines.
+type ValidationErrors []ValidationError
+
+// Error returns the problems, one per line.
+func (e ValidationErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate decodes the yaml of a game schema, checking it has no unknown fields and that the schema could be deployed.
+// It returns ValidationErrors holding every problem found with the line it is on, rather than stopping at the first.
+// A schema which extends a base is merged with it first, reading bases from the schema directory.
+func Validate(content []byte) (Schema, error) {
+	return validate(content, readBase)
+}
+
+// validate validates a game schema as Validate does, reading the bases it extends with load,
+// which returns a base with the file it was read from.
+func validate(content []byte, load func(name string) ([]byte, string, error)) (Schema, error) {
+	root, g, errs, err := decode(content)
+	if err != nil {
+		return Schema{}, ValidationErrors{yamlError(err)}
+	}
+	v := validator{root: root, files: map[*yaml.Node]string{}, errs: errs}
+
+	if g.Extends != "" {
+		merged, err := extend(root, g.Extends, load, v.files, map[string]bool{})
+		// The rest of the schema is not checked, as it is missing the fields of its base.
+		var problems ValidationErrors
+		if errors.As(err, &problems) {
+			v.errs = append(v.errs, problems...)
+			return Schema{}, v.sorted()
+		} else if err != nil {
+			v.fail([]any{"extends"}, "%v", err)
+			return Schema{}, v.sorted()
+		}
+		// Type errors of the merged schema were found when the schema and its bases were decoded.
+		g = Schema{}
+		if err := merged.Decode(&g); err != nil && !errors.As(err, new(*yaml.TypeError)) {
+			return Schema{}, ValidationErrors{yamlError(err)}
+		}
+		v.root = merged
+	}
+
+	v.check(g)
+	if len(v.errs) > 0 {
+		return Schema{}, v.sorted()
+	}
+	return g, nil
+}
+
+// decode decodes the yaml of a game schema, rejecting unknown fields. Fields which decode are returned
+// along with the problems of those which do not, and the error is only set for yaml which can not be parsed.
+func decode(content []byte) (*yaml.Node, Schema, ValidationErrors, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, Schema{}, nil, err
+	}
+
+	var g Schema
+	var errs ValidationErrors
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&g); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, Schema{}, nil, err
+		}
+		// Fields which decode are still checked, as type errors leave the rest of the schema decoded.
+		for _, message := range typeErr.Errors {
+			errs = append(errs, yamlError(errors.New(message)))
+		}
+	}
+	return &root, g, errs, nil
+}
+
+// yamlLineRegex matches the line yaml errors start with.
+var yamlLineRegex *regexp.Regexp = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// yamlError converts an error of the yaml decoder into a ValidationError, taking the line from its message.
+func yamlError(err error) ValidationError {
+	matches := yamlLineRegex.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return ValidationError{Message: strings.TrimPrefix(err.Error(), "yaml: ")}
+	}
+	line, _ := strconv.Atoi(matches[1])
+	return ValidationError{Line: line, Message: matches[2]}
+}
+
+// validator collects the problems with a decoded schema, finding their lines in the yaml it was decoded from.
+type validator struct {
+	root  *yaml.Node
+	files map[*yaml.Node]string // Files of the nodes merged from bases.
+	errs  ValidationErrors
+}
+
+// fail records a problem with the field at a path of mapping keys and sequence indices.
+func (v *validator) fail(fieldPath []any, format string, args ...any) {
+	node := nodeOf(v.root, fieldPath)
+	v.errs = append(v.errs, ValidationError{
+		File:    v.files[node],
+		Line:    node.Line,
+		Field:   fieldName(fieldPath),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// sorted returns the problems found in the order of their files and lines, those of the schema itself first.
+func (v *validator) sorted() ValidationErrors {
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		if v.errs[i].File != v.errs[j].File {
+			return v.errs[i].File < v.errs[j].File
+		}
+		return v.errs[i].Line < v.errs[j].Line
+	})
+	return v.errs
+}
+
+// check checks the fields of a schema which could not be deployed, or would fail when they are used.
+func (v *validator) check(g Schema) {
+	if !regexp.MustCompile(gameRegex).MatchString(g.Name) {
+		v.fail([]any{"name"}, "must be lowercase letters, numbers and underscores, not %q", g.Name)
+	}
+	if g.Image == "" {
+		v.fail([]any{"image"}, "is required")
+	}
+	v.checkSizes(g)
+	v.checkNetwork(g)
+
+	for i, setting := range g.Settings {
+		v.checkSetting([]any{"settings", i}, setting)
+	}
+	v.checkVolumes(g)
+	if g.World != "" {
+		v.checkPath(g, []any{"world"}, g.World)
+	}
+	for i, pattern := range g.Files.Writable {
+		if _, err := path.Match(pattern, "/"); err != nil || !path.IsAbs(pattern) {
+			v.fail([]any{"files", "writable", i}, "must be an absolute path pattern, not %q", pattern)
+		}
+	}
+	v.checkProbes(g.Probes)
+	v.checkVersions([]any{"versions"}, g.Versions)
+	v.checkModloaders(g)
+	v.checkProperties(g)
+	v.checkPlayers(g)
+}
+
+// checkSizes checks the schema has sizes, each with valid resources and room for players.
+func (v *validator) checkSizes(g Schema) {
+	if len(g.Sizes) == 0 {
+		v.fail([]any{"sizes"}, "at least one size is required")
+	}
+	for name, size := range g.Sizes {
+		v.checkQuantity([]any{"sizes", name, "resources", "cpu"}, size.Resources.CPU)
+		v.checkQuantity([]any{"sizes", name, "resources", "memory"}, size.Resources.Memory)
+		if size.Players < 1 {
+			v.fail([]any{"sizes", name, "players"}, "must be at least 1, not %v", size.Players)
+		}
+	}
+}
+
+// checkNetwork checks each port is named, in range and only used once with its protocol.
+func (v *validator) checkNetwork(g Schema) {
+	names := map[string]bool{}
+	ports := map[string]bool{}
+	for i, network := range g.Network {
+		if network.Name == "" {
+			v.fail([]any{"network", i, "name"}, "is required")
+		} else if names[network.Name] {
+			v.fail([]any{"network", i, "name"}, "%q is used by another port", network.Name)
+		}
+		names[network.Name] = true
+
+		if network.Port < 1 || network.Port > 65535 {
+			v.fail([]any{"network", i, "port"}, "must be between 1 and 65535, not %v", network.Port)
+		}
+		if network.Protocol != "tcp" && network.Protocol != "udp" {
+			v.fail([]any{"network", i, "protocol"}, "must be tcp or udp, not %q", network.Protocol)
+		}
+		port := fmt.Sprintf("%v/%v", network.Port, network.Protocol)
+		if ports[port] {
+			v.fail([]any{"network", i, "port"}, "%v is used by another port", port)
+		}
+		ports[port] = true
+	}
+}
+
+// checkSetting checks a setting is named, and any template it uses can be resolved.
+func (v *validator) checkSetting(fieldPath []any, setting Setting) {
+	if setting.Name == "" {
+		v.fail(append(fieldPath, "name"), "is required")
+	}
+	for field, value := range map[string]string{"name": setting.Name, "value": setting.Value} {
+		if strings.Contains(value, "{{") && !templates[value] {
+			v.fail(append(fieldPath, field), "template %q must be the whole value and one of %v", value, strings.Join(templateNames(), ", "))
+		}
+	}
+}
+
+// checkVolumes checks each volume is named once and has an absolute path and a valid size.
+func (v *validator) checkVolumes(g Schema) {
+	names := map[string]bool{}
+	for i, volume := range g.Volumes {
+		if volume.Name == "" {
+			v.fail([]any{"volumes", i, "name"}, "is required")
+		} else if names[volume.Name] {
+			v.fail([]any{"volumes", i, "name"}, "%q is used by another volume", volume.Name)
+		}
+		names[volume.Name] = true
+
+		if !path.IsAbs(volume.Path) {
+			v.fail([]any{"volumes", i, "path"}, "must be absolute, not %q", volume.Path)
+		}
+		v.checkQuantity([]any{"volumes", i, "size"}, volume.Size)
+	}
+}
+
+// checkProbes checks the probes have a command to run, and no negative values.
+func (v *validator) checkProbes(probes Probes) {
+	for name, probe := range map[string]Probe{"startupProbe": probes.StartupProbe, "readynessProbe": probes.ReadynessProbe, "livenessProbe": probes.LivenessProbe} {
+		if probe != (Probe{}) && len(probes.Command) == 0 {
+			v.fail([]any{"probes", name}, "requires probes.command")
+		}
+		for field, value := range map[string]int{
+			"initialDelaySeconds": probe.InitialDelaySeconds,
+			"periodSeconds":       probe.PeriodSeconds,
+			"failureThreshold":    probe.FailureThreshold,
+			"successThreshold":    probe.SuccessThreshold,
+			"timeoutSeconds":      probe.TimeoutSeconds,
+		} {
+			if value < 0 {
+				v.fail([]any{"probes", name, field}, "must not be negative, not %v", value)
+			}
+		}
+	}
+}
+
+// checkVersions checks the version pattern compiles and the image only uses the version template.
+func (v *validator) checkVersions(fieldPath []any, versions Versions) {
+	if versions.Pattern != "" {
+		if _, err := regexp.Compile(versions.Pattern); err != nil {
+			v.fail(append(fieldPath, "pattern"), "is not a valid regular expression: %v", err)
+		}
+	}
+	v.checkImage(append(fieldPath, "image"), versions.Image)
+}
+
+// checkModloaders checks each modloader is named once, and its settings, versions and mods directory.
+func (v *validator) checkModloaders(g Schema) {
+	names := map[string]bool{}
+	for i, modloader := range g.Modloaders {
+		fieldPath := []any{"modloaders", i}
+		if modloader.Name == "" {
+			v.fail(append(fieldPath, "name"), "is required")
+		} else if names[strings.ToLower(modloader.Name)] {
+			v.fail(append(fieldPath, "name"), "%q is used by another modloader", modloader.Name)
+		}
+		names[strings.ToLower(modloader.Name)] = true
+
+		v.checkImage(append(fieldPath, "image"), modloader.Image)
+		for j, setting := range modloader.Settings {
+			v.checkSetting(append(fieldPath, "settings", j), setting)
+		}
+		v.checkVersions(append(fieldPath, "versions"), modloader.Versions)
+		if modloader.Mods != "" {
+			v.checkPath(g, append(fieldPath, "mods"), modloader.Mods)
+		}
+	}
+}
+
+// checkProperties checks each property is named once, has a valid type and default, and one place it is passed.
+func (v *validator) checkProperties(g Schema) {
+	names := map[string]bool{}
+	for i, property := range g.Properties {
+		fieldPath := []any{"properties", i}
+		if property.Name == "" {
+			v.fail(append(fieldPath, "name"), "is required")
+		} else if names[property.Name] {
+			v.fail(append(fieldPath, "name"), "%q is used by another property", property.Name)
+		}
+		names[property.Name] = true
+
+		switch property.Type {
+		case PropertyString, PropertyBool, PropertyInt:
+		case PropertyEnum:
+			if len(property.Values) == 0 {
+				v.fail(append(fieldPath, "values"), "an enum requires at least one value")
+			}
+		default:
+			v.fail(append(fieldPath, "type"), "must be one of %v, %v, %v or %v, not %q", PropertyString, PropertyBool, PropertyInt, PropertyEnum, property.Type)
+			continue
+		}
+		if property.Min != nil && property.Max != nil && *property.Min > *property.Max {
+			v.fail(append(fieldPath, "min"), "must not be more than max")
+		}
+		if property.Default != "" {
+			if _, err := property.parse(property.Default); err != nil {
+				v.fail(append(fieldPath, "default"), "%v", err)
+			}
+		}
+
+		switch {
+		case (property.Env == "") == (property.File == ""):
+			v.fail(fieldPath, "requires exactly one of env or file")
+		case property.File != "" && property.Key == "":
+			v.fail(append(fieldPath, "key"), "is required with file")
+		case property.File != "":
+			v.checkPath(g, append(fieldPath, "file"), property.File)
+		}
+	}
+}
+
+// checkPlayers checks the player pattern compiles, and each player list is named once with a file of a known format.
+func (v *validator) checkPlayers(g Schema) {
+	if g.Players.Pattern != "" {
+		if _, err := regexp.Compile(g.Players.Pattern); err != nil {
+			v.fail([]any{"players", "pattern"}, "is not a valid regular expression: %v", err)
+		}
+	}
+	names := map[string]bool{}
+	for i, list := range g.Players.Lists {
+		fieldPath := []any{"players", "lists", i}
+		if list.Name == "" {
+			v.fail(append(fieldPath, "name"), "is required")
+		} else if names[list.Name] {
+			v.fail(append(fieldPath, "name"), "%q is used by another list", list.Name)
+		}
+		names[list.Name] = true
+
+		v.checkPath(g, append(fieldPath, "file"), list.File)
+		if list.Format != PlayersJSON && list.Format != PlayersLines {
+			v.fail(append(fieldPath, "format"), "must be %v or %v, not %q", PlayersJSON, PlayersLines, list.Format)
+		}
+		for field, command := range map[string][]string{"add": list.Add, "remove": list.Remove} {
+			for j, arg := range command {
+				if strings.Contains(strings.NewReplacer(playerTemplate, "", reasonTemplate, "").Replace(arg), "{{") {
+					v.fail(append(fieldPath, field, j), "may only use the %v and %v templates", playerTemplate, reasonTemplate)
+				}
+			}
+		}
+	}
+}
+
+// checkImage checks an image only uses the version template.
+func (v *validator) checkImage(fieldPath []any, image string) {
+	if strings.Contains(strings.ReplaceAll(image, versionTemplate, ""), "{{") {
+		v.fail(fieldPath, "may only use the %v template", versionTemplate)
+	}
+}
+
+// checkPath checks a path is inside a volume of the schema.
+func (v *validator) checkPath(g Schema, fieldPath []any, p string) {
+	if _, err := VolumePath(g, p); err != nil {
+		v.fail(fieldPath, "%v", err)
+	}
+}
+
+// checkQuantity checks a resource quantity, such as 1500m or 4Gi, is valid and more than nothing.
+func (v *validator) checkQuantity(fieldPath []any, quantity string) {
+	parsed, err := resource.ParseQuantity(quantity)
+	if err != nil {
+		v.fail(fieldPath, "%q is not a valid quantity", quantity)
+		return
+	}
+	if parsed.Sign() <= 0 {
+		v.fail(fieldPath, "must be more than 0, not %q", quantity)
+	}
+}
+
+// templateNames returns the templates setting values may be, in order.
+func templateNames() []string {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// nodeOf returns the node of the field at a path of mapping keys and sequence indices in a yaml document,
+// or of the deepest field of the path in the document if the field itself is missing.
+// The node of a mapping key is its key, which is on the line the field starts.
+func nodeOf(root *yaml.Node, fieldPath []any) *yaml.Node {
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	at := node
+	for _, part := range fieldPath {
+		var next *yaml.Node
+		switch key := part.(type) {
+		case string:
+			if node.Kind != yaml.MappingNode {
+				return at
+			}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == key {
+					at = node.Content[i]
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case int:
+			if node.Kind != yaml.SequenceNode || key >= len(node.Content) {
+				return at
+			}
+			next = node.Content[key]
+			at = next
+		}
+		if next == nil {
+			return at
+		}
+		node = next
+	}
+	return at
+}
+
+// fieldName returns a path of mapping keys and sequence indices as it is written in errors e.g. network[0].port.
+func fieldName(fieldPath []any) string {
+	var name strings.Builder
+	for _, part := range fieldPath {
+		switch key := part.(type) {
+		case int:
+			fmt.Fprintf(&name, "[%v]", key)
+		default:
+			if name.Len() > 0 {
+				name.WriteString(".")
+			}
+			fmt.Fprint(&name, key)
+		}
+	}
+	return name.String()
+}
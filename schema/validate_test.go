@@ -0,0 +1,73 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestValidate calls Validate with a schema holding many problems,
+// checking every problem is returned in order with the line it is on.
+func TestValidate(t *testing.T) {
+	content := `name: minecraft_java
+sizes:
+  xs:
+    resources:
+      cpu: lots
+      memory: 2000Mi
+    players: 8
+network:
+  - name: game
+    port: 25565
+    protocol: tcp
+  - name: query
+    port: 25565
+    protocol: tcp
+  - name: rcon
+    port: 70000
+    protocol: sctp
+settings:
+  - name: MOTD
+    value: "{{ .motd }}"
+volumes:
+  - name: data
+    path: /data
+    size: 10Gi
+probes:
+  livenessProbe:
+    periodSeconds: -5
+unknown: true
+`
+
+	want := ValidationErrors{
+		{Line: 1, Field: "image", Message: "is required"},
+		{Line: 5, Field: "sizes.xs.resources.cpu", Message: `"lots" is not a valid quantity`},
+		{Line: 13, Field: "network[1].port", Message: "25565/tcp is used by another port"},
+		{Line: 16, Field: "network[2].port", Message: "must be between 1 and 65535, not 70000"},
+		{Line: 17, Field: "network[2].protocol", Message: `must be tcp or udp, not "sctp"`},
+		{Line: 20, Field: "settings[0].value", Message: `template "{{ .motd }}" must be the whole value and one of {{ .modloader }}, {{ .modloaderVersion }}, {{ .name }}, {{ .players }}, {{ .version }}`},
+		{Line: 26, Field: "probes.livenessProbe", Message: "requires probes.command"},
+		{Line: 27, Field: "probes.livenessProbe.periodSeconds", Message: "must not be negative, not -5"},
+		{Line: 28, Message: "field unknown not found in type schema.Schema"},
+	}
+
+	_, err := Validate([]byte(content))
+	var got ValidationErrors
+	if !errors.As(err, &got) {
+		t.Fatalf("Validate() returned %v, want ValidationErrors", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Validate() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestValidateSyntax calls Validate with yaml which can not be parsed,
+// checking the error has the line of the problem.
+func TestValidateSyntax(t *testing.T) {
+	_, err := Validate([]byte("name: minecraft_java\nimage: [itzg\n"))
+	var got ValidationErrors
+	if !errors.As(err, &got) || len(got) != 1 || got[0].Line == 0 {
+		t.Fatalf("Validate() returned %v, want one error with a line", err)
+	}
+}
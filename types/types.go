@@ -1,9 +1,13 @@
 package types
 
+import "time"
+
 // Game is details about the video game that the server is hosting.
 type Game struct {
-	Name      string `json:"name" yaml:"name" xml:"name" form:"name"`                     // Name of the video game.
-	Modloader string `json:"modloader" yaml:"modloader" xml:"modloader" form:"modloader"` // Software used to load mods into the game, or vanilla (no modloader).
+	Name             string `json:"name" yaml:"name" xml:"name" form:"name"`                                                 // Name of the video game.
+	Modloader        string `json:"modloader" yaml:"modloader" xml:"modloader" form:"modloader"`                             // Software used to load mods into the game, or vanilla (no modloader).
+	Version          string `json:"version" yaml:"version" xml:"version" form:"version"`                                     // Version of the game e.g. 1.20.1, or the default of the game schema when empty.
+	ModloaderVersion string `json:"modloaderVersion" yaml:"modloaderVersion" xml:"modloaderVersion" form:"modloaderVersion"` // Version of the modloader e.g. 47.2.0, or the one chosen by the image when empty.
 }
 
 // Network contains details about connecting to the server.
@@ -14,11 +18,19 @@ type Network struct {
 
 // Server is a set of useful details about a game server instance.
 type Server struct {
-	Name    string  `json:"name" yaml:"name" xml:"name" form:"name"`             // In game name of the server. Useful if the server is public.
-	Size    string  `json:"size" yaml:"size" xml:"size" form:"size"`             // Scale of the server. Effects the resources allocated.
-	Game    Game    `json:"game" yaml:"game" xml:"game" form:"game"`             // Details about the video game that the server is hosting.
-	Network Network `json:"network" yaml:"network" xml:"network" form:"network"` // Networking configuration of the server.
-	Status  string  `json:"status" yaml:"status" xml:"status" form:"status"`     // Condition of the server.
+	Name       string            `json:"name" yaml:"name" xml:"name" form:"name"`                                           // In game name of the server. Useful if the server is public.
+	Size       string            `json:"size" yaml:"size" xml:"size" form:"size"`                                           // Scale of the server. Effects the resources allocated.
+	Game       Game              `json:"game" yaml:"game" xml:"game" form:"game"`                                           // Details about the video game that the server is hosting.
+	Network    Network           `json:"network" yaml:"network" xml:"network" form:"network"`                               // Networking configuration of the server.
+	Image      string            `json:"image" yaml:"image" xml:"image" form:"image"`                                       // Container image the server runs, pinned to a digest when it is deployed.
+	Status     string            `json:"status" yaml:"status" xml:"status" form:"status"`                                   // Condition of the server.
+	Properties map[string]string `json:"properties" yaml:"properties" xml:"properties" form:"properties"`                   // Game properties the server has changed from their defaults, by name.
+	Pending    *Pending          `json:"pending,omitempty" yaml:"pending,omitempty" xml:"pending,omitempty" form:"pending"` // Changes saved to be applied when the server is next started or restarted.
+}
+
+// Pending is a set of changes to a server which are applied when it is next started or restarted.
+type Pending struct {
+	Properties map[string]string `json:"properties" yaml:"properties" xml:"properties" form:"properties"` // Game properties the server will have changed from their defaults, by name.
 }
 
 // Instance is a single item of a game server and an id.
@@ -26,3 +38,227 @@ type Instance struct {
 	ID     string `json:"id" yaml:"id" xml:"id" form:"id"`                 // The identifier of the instance.
 	Server Server `json:"server" yaml:"server" xml:"server" form:"server"` // Details about a game server instance.
 }
+
+// AuditChange is a single field that was modified by an operation.
+type AuditChange struct {
+	Field string `json:"field" yaml:"field" xml:"field" form:"field"` // Dot separated path to the modified field e.g. "game.modloader".
+	Old   string `json:"old" yaml:"old" xml:"old" form:"old"`         // Value of the field before the operation.
+	New   string `json:"new" yaml:"new" xml:"new" form:"new"`         // Value of the field after the operation.
+}
+
+// AuditEvent is a record of a single mutating operation and who performed it.
+type AuditEvent struct {
+	ID         string        `json:"id" yaml:"id" xml:"id" form:"id"`                                 // The identifier of the event.
+	Actor      string        `json:"actor" yaml:"actor" xml:"actor" form:"actor"`                     // The user who performed the operation, or "anonymous".
+	AuthMethod string        `json:"authMethod" yaml:"authMethod" xml:"authMethod" form:"authMethod"` // How the actor was authenticated e.g. "firebase".
+	Action     string        `json:"action" yaml:"action" xml:"action" form:"action"`                 // The operation performed e.g. "server.update".
+	InstanceID string        `json:"instanceId" yaml:"instanceId" xml:"instanceId" form:"instanceId"` // The instance affected by the operation, if any.
+	JobID      string        `json:"jobId" yaml:"jobId" xml:"jobId" form:"jobId"`                     // The job carrying out the operation, if it runs in the background.
+	Changes    []AuditChange `json:"changes" yaml:"changes" xml:"changes" form:"changes"`             // The fields modified by the operation.
+	Result     string        `json:"result" yaml:"result" xml:"result" form:"result"`                 // Whether the operation succeeded ("success") or not ("failure").
+	Error      string        `json:"error" yaml:"error" xml:"error" form:"error"`                     // The error returned by the operation, if it failed.
+	Timestamp  time.Time     `json:"timestamp" yaml:"timestamp" xml:"timestamp" form:"timestamp"`     // When the operation was performed.
+	SourceIP   string        `json:"sourceIp" yaml:"sourceIp" xml:"sourceIp" form:"sourceIp"`         // The IP address the request came from.
+}
+
+// Resources is an amount of compute, either available on a host or required by a server.
+type Resources struct {
+	MilliCPU int64 `json:"milliCpu" yaml:"milliCpu" xml:"milliCpu" form:"milliCpu"` // Thousandths of a CPU core.
+	Memory   int64 `json:"memory" yaml:"memory" xml:"memory" form:"memory"`         // Bytes of memory.
+}
+
+// Node is a machine running Aurora, registered to a cluster.
+type Node struct {
+	ID            string            `json:"id" yaml:"id" xml:"id" form:"id"`                                             // The identifier of the node.
+	Hostname      string            `json:"hostname" yaml:"hostname" xml:"hostname" form:"hostname"`                     // The hostname of the machine.
+	Version       string            `json:"version" yaml:"version" xml:"version" form:"version"`                         // The version of Aurora running on the node.
+	Address       string            `json:"address" yaml:"address" xml:"address" form:"address"`                         // The URL of the node's API.
+	Labels        map[string]string `json:"labels" yaml:"labels" xml:"labels" form:"labels"`                             // Labels describing the node e.g. region or hardware class.
+	Capacity      Resources         `json:"capacity" yaml:"capacity" xml:"capacity" form:"capacity"`                     // The total resources of the machine.
+	Allocated     Resources         `json:"allocated" yaml:"allocated" xml:"allocated" form:"allocated"`                 // The resources requested by the instances on the node.
+	Instances     []string          `json:"instances" yaml:"instances" xml:"instances" form:"instances"`                 // The identifiers of the instances running on the node.
+	Status        string            `json:"status" yaml:"status" xml:"status" form:"status"`                             // Whether the node is "online" or has stopped sending heartbeats and is "offline".
+	RegisteredAt  time.Time         `json:"registeredAt" yaml:"registeredAt" xml:"registeredAt" form:"registeredAt"`     // When the node first registered.
+	LastHeartbeat time.Time         `json:"lastHeartbeat" yaml:"lastHeartbeat" xml:"lastHeartbeat" form:"lastHeartbeat"` // When the node last reported to the cluster.
+}
+
+// Cluster is a group of nodes and the instances running on them.
+type Cluster struct {
+	ID          string     `json:"id" yaml:"id" xml:"id" form:"id"`                                     // The identifier of the cluster.
+	Environment string     `json:"environment" yaml:"environment" xml:"environment" form:"environment"` // The environment the cluster is deployed to.
+	Nodes       []Node     `json:"nodes" yaml:"nodes" xml:"nodes" form:"nodes"`                         // Every node registered to the cluster.
+	Instances   []Instance `json:"instances" yaml:"instances" xml:"instances" form:"instances"`         // Every instance registered to the cluster.
+}
+
+// Placement constrains which nodes a server may be scheduled onto.
+type Placement struct {
+	Affinity     map[string]string `json:"affinity" yaml:"affinity" xml:"affinity" form:"affinity"`                 // Labels a node must have.
+	AntiAffinity map[string]string `json:"antiAffinity" yaml:"antiAffinity" xml:"antiAffinity" form:"antiAffinity"` // Labels a node must not have.
+}
+
+// ScheduleRequest is a request to create a server on whichever node of the cluster suits it best.
+type ScheduleRequest struct {
+	Server    Server    `json:"server" yaml:"server" xml:"server" form:"server"`             // Details of the server to create.
+	Placement Placement `json:"placement" yaml:"placement" xml:"placement" form:"placement"` // Constraints on the node chosen.
+}
+
+// ScheduleResult is the node a server was scheduled onto and the instance being created there.
+type ScheduleResult struct {
+	NodeID   string   `json:"nodeId" yaml:"nodeId" xml:"nodeId" form:"nodeId"`         // The node the server was placed on.
+	Instance Instance `json:"instance" yaml:"instance" xml:"instance" form:"instance"` // The instance being created on the node.
+	Job      Job      `json:"job" yaml:"job" xml:"job" form:"job"`                     // The job creating the instance, followed on the chosen node.
+}
+
+// States of an instance's workload.
+const (
+	StateRunning  string = "running"  // The workload is up and ready.
+	StateStarting string = "starting" // The workload is up but not yet ready.
+	StateStopped  string = "stopped"  // The workload exists but is not running.
+	StateMissing  string = "missing"  // The runtime has no workload for the instance.
+)
+
+// RuntimeStatus is the state of an instance's workload, as reported by its runtime.
+type RuntimeStatus struct {
+	State     string    `json:"state" yaml:"state" xml:"state" form:"state"`                 // Whether the workload is "running", "starting", "stopped" or "missing".
+	Message   string    `json:"message" yaml:"message" xml:"message" form:"message"`         // Detail from the runtime e.g. the reason a container exited.
+	StartedAt time.Time `json:"startedAt" yaml:"startedAt" xml:"startedAt" form:"startedAt"` // When the workload last started.
+	Revision  string    `json:"revision" yaml:"revision" xml:"revision" form:"revision"`     // Revision of the configuration the workload was deployed with, if known.
+}
+
+// Stats is the resource usage of an instance's workload.
+type Stats struct {
+	MilliCPU    int64 `json:"milliCpu" yaml:"milliCpu" xml:"milliCpu" form:"milliCpu"`             // Thousandths of a CPU core in use.
+	Memory      int64 `json:"memory" yaml:"memory" xml:"memory" form:"memory"`                     // Bytes of memory in use.
+	MemoryLimit int64 `json:"memoryLimit" yaml:"memoryLimit" xml:"memoryLimit" form:"memoryLimit"` // Bytes of memory the workload may use, or 0 if unlimited.
+}
+
+// ExecResult is the outcome of a command run inside an instance's workload.
+type ExecResult struct {
+	ExitCode int    `json:"exitCode" yaml:"exitCode" xml:"exitCode" form:"exitCode"` // Exit code of the command.
+	Output   string `json:"output" yaml:"output" xml:"output" form:"output"`         // Combined stdout and stderr of the command.
+}
+
+// Job is a long running operation, executed in the background after its request has returned.
+type Job struct {
+	ID         string    `json:"id" yaml:"id" xml:"id" form:"id"`                                 // The identifier of the job.
+	Action     string    `json:"action" yaml:"action" xml:"action" form:"action"`                 // The operation performed e.g. "server.create".
+	InstanceID string    `json:"instanceId" yaml:"instanceId" xml:"instanceId" form:"instanceId"` // The instance the operation acts on.
+	Status     string    `json:"status" yaml:"status" xml:"status" form:"status"`                 // Whether the job is "pending", "running", "succeeded" or "failed".
+	Step       string    `json:"step" yaml:"step" xml:"step" form:"step"`                         // The step being executed e.g. "pulling".
+	Progress   int       `json:"progress" yaml:"progress" xml:"progress" form:"progress"`         // Percentage of the current step completed.
+	Error      string    `json:"error" yaml:"error" xml:"error" form:"error"`                     // The error the job failed with, if any.
+	CreatedAt  time.Time `json:"createdAt" yaml:"createdAt" xml:"createdAt" form:"createdAt"`     // When the job was submitted.
+	UpdatedAt  time.Time `json:"updatedAt" yaml:"updatedAt" xml:"updatedAt" form:"updatedAt"`     // When the job last reported progress.
+}
+
+// Mod is a mod or plugin jar file added to the data volume of an instance.
+type Mod struct {
+	Name    string    `json:"name" yaml:"name" xml:"name" form:"name"`             // File name of the jar e.g. "jei-1.20.1.jar".
+	SHA256  string    `json:"sha256" yaml:"sha256" xml:"sha256" form:"sha256"`     // Hex encoded SHA-256 hash of the jar.
+	Size    int64     `json:"size" yaml:"size" xml:"size" form:"size"`             // Size of the jar in bytes.
+	Enabled bool      `json:"enabled" yaml:"enabled" xml:"enabled" form:"enabled"` // Whether the jar is loaded by the server.
+	AddedAt time.Time `json:"addedAt" yaml:"addedAt" xml:"addedAt" form:"addedAt"` // When the jar was added.
+}
+
+// ModManifest is every mod added to an instance.
+type ModManifest struct {
+	InstanceID string    `json:"instanceId" yaml:"instanceId" xml:"instanceId" form:"instanceId"` // The instance the mods belong to.
+	Mods       []Mod     `json:"mods" yaml:"mods" xml:"mods" form:"mods"`                         // The mods, in the order they were added.
+	UpdatedAt  time.Time `json:"updatedAt" yaml:"updatedAt" xml:"updatedAt" form:"updatedAt"`     // When the mods last changed.
+}
+
+// Modpack is a summary of a modpack archive a server was imported from.
+type Modpack struct {
+	Format    string            `json:"format" yaml:"format" xml:"format" form:"format"`             // Format of the archive, "modrinth" or "curseforge".
+	Name      string            `json:"name" yaml:"name" xml:"name" form:"name"`                     // Name of the modpack.
+	Version   string            `json:"version" yaml:"version" xml:"version" form:"version"`         // Version of the modpack itself.
+	Game      Game              `json:"game" yaml:"game" xml:"game" form:"game"`                     // The game, modloader and versions the modpack runs on.
+	Files     []string          `json:"files" yaml:"files" xml:"files" form:"files"`                 // Paths of the files the archive installs into the data volume.
+	Downloads []ModpackDownload `json:"downloads" yaml:"downloads" xml:"downloads" form:"downloads"` // Server side files the archive refers to without containing them, which are not installed.
+}
+
+// ModpackDownload is a file a modpack refers to by where it can be downloaded from.
+type ModpackDownload struct {
+	Path      string   `json:"path" yaml:"path" xml:"path" form:"path"`                     // Path of the file in the data volume, if the modpack gives one.
+	URLs      []string `json:"urls" yaml:"urls" xml:"urls" form:"urls"`                     // URLs the file can be downloaded from.
+	SHA512    string   `json:"sha512" yaml:"sha512" xml:"sha512" form:"sha512"`             // Hex encoded SHA-512 hash of the file, if the modpack gives one.
+	Size      int64    `json:"size" yaml:"size" xml:"size" form:"size"`                     // Size of the file in bytes, if the modpack gives one.
+	ProjectID int      `json:"projectId" yaml:"projectId" xml:"projectId" form:"projectId"` // CurseForge project of the file, for CurseForge modpacks.
+	FileID    int      `json:"fileId" yaml:"fileId" xml:"fileId" form:"fileId"`             // CurseForge file id of the file, for CurseForge modpacks.
+}
+
+// File is a file or directory in the volumes of an instance, as shown by the file manager.
+type File struct {
+	Name       string    `json:"name" yaml:"name" xml:"name" form:"name"`                         // Base name of the file e.g. "server.properties".
+	Path       string    `json:"path" yaml:"path" xml:"path" form:"path"`                         // Absolute path of the file in the workload e.g. "/data/server.properties".
+	Dir        bool      `json:"dir" yaml:"dir" xml:"dir" form:"dir"`                             // Whether the file is a directory.
+	Size       int64     `json:"size" yaml:"size" xml:"size" form:"size"`                         // Size of the file in bytes.
+	ModifiedAt time.Time `json:"modifiedAt" yaml:"modifiedAt" xml:"modifiedAt" form:"modifiedAt"` // When the file was last changed.
+	Writable   bool      `json:"writable" yaml:"writable" xml:"writable" form:"writable"`         // Whether the file manager may change the file.
+}
+
+// RenameRequest is a request to move a file or directory of an instance with the file manager.
+type RenameRequest struct {
+	From string `json:"from" yaml:"from" xml:"from" form:"from"` // Current path of the file.
+	To   string `json:"to" yaml:"to" xml:"to" form:"to"`         // New path of the file, which must not exist.
+}
+
+// Property is a game setting of an instance declared by its game schema, with the value it has.
+type Property struct {
+	Name        string   `json:"name" yaml:"name" xml:"name" form:"name"`                             // Name of the property e.g. "view-distance".
+	Type        string   `json:"type" yaml:"type" xml:"type" form:"type"`                             // Type of the value, one of "string", "bool", "int" or "enum".
+	Description string   `json:"description" yaml:"description" xml:"description" form:"description"` // What the property changes in the game.
+	Default     any      `json:"default" yaml:"default" xml:"default" form:"default"`                 // Value the game uses when the property has not been changed.
+	Values      []string `json:"values" yaml:"values" xml:"values" form:"values"`                     // Values an enum property may have.
+	Min         *int     `json:"min" yaml:"min" xml:"min" form:"min"`                                 // Smallest value of an int property, or null.
+	Max         *int     `json:"max" yaml:"max" xml:"max" form:"max"`                                 // Largest value of an int property, or null.
+	Value       any      `json:"value" yaml:"value" xml:"value" form:"value"`                         // Value the instance has chosen, or the default.
+	Changed     bool     `json:"changed" yaml:"changed" xml:"changed" form:"changed"`                 // Whether the instance has changed the property from its default.
+}
+
+// Player is a player on a list an instance keeps, such as its allow list, operators or bans.
+type Player struct {
+	Name   string `json:"name" yaml:"name" xml:"name" form:"name"`         // In game name of the player.
+	UUID   string `json:"uuid" yaml:"uuid" xml:"uuid" form:"uuid"`         // Unique identifier of the player, for games which record one.
+	Reason string `json:"reason" yaml:"reason" xml:"reason" form:"reason"` // Why the player was added, such as the reason for a ban.
+}
+
+// GameSchema is a summary of a game schema for clients, with the choices a server of the game can make.
+type GameSchema struct {
+	Name       string          `json:"name" yaml:"name" xml:"name" form:"name"`                         // Name of the game e.g. "minecraft_java".
+	URL        string          `json:"url" yaml:"url" xml:"url" form:"url"`                             // Documentation of the image servers of the game run.
+	Sizes      []GameSize      `json:"sizes" yaml:"sizes" xml:"sizes" form:"sizes"`                     // Sizes a server may be, from smallest to largest.
+	Ports      []GamePort      `json:"ports" yaml:"ports" xml:"ports" form:"ports"`                     // Ports servers of the game listen on.
+	Versions   GameVersions    `json:"versions" yaml:"versions" xml:"versions" form:"versions"`         // Versions of the game, which each modloader may narrow.
+	Modloaders []GameModloader `json:"modloaders" yaml:"modloaders" xml:"modloaders" form:"modloaders"` // Modloaders a server may choose, the first being the default.
+	Properties []Property      `json:"properties" yaml:"properties" xml:"properties" form:"properties"` // Game properties a server may change, with their defaults.
+}
+
+// GameSize is a size a server of a game may be.
+type GameSize struct {
+	Name    string `json:"name" yaml:"name" xml:"name" form:"name"`             // Name the size is chosen by e.g. "xs".
+	Players int    `json:"players" yaml:"players" xml:"players" form:"players"` // Most players a server of the size allows at once.
+	CPU     string `json:"cpu" yaml:"cpu" xml:"cpu" form:"cpu"`                 // CPU reserved for a server of the size e.g. "1500m".
+	Memory  string `json:"memory" yaml:"memory" xml:"memory" form:"memory"`     // Memory reserved for a server of the size e.g. "4000Mi".
+}
+
+// GamePort is a port servers of a game listen on.
+type GamePort struct {
+	Name     string `json:"name" yaml:"name" xml:"name" form:"name"`                 // Name of the port e.g. "game".
+	Port     int    `json:"port" yaml:"port" xml:"port" form:"port"`                 // Port number.
+	Protocol string `json:"protocol" yaml:"protocol" xml:"protocol" form:"protocol"` // Protocol of the port, "tcp" or "udp".
+}
+
+// GameVersions are the versions of a game a server may choose.
+type GameVersions struct {
+	Default   string   `json:"default" yaml:"default" xml:"default" form:"default"`         // Version of servers which do not choose one.
+	Supported []string `json:"supported" yaml:"supported" xml:"supported" form:"supported"` // Versions which may be chosen, such as "LATEST".
+	Pattern   string   `json:"pattern" yaml:"pattern" xml:"pattern" form:"pattern"`         // Regular expression matching any other version which may be chosen.
+}
+
+// GameModloader is a modloader a server of a game may choose.
+type GameModloader struct {
+	Name     string       `json:"name" yaml:"name" xml:"name" form:"name"`                 // Name the modloader is chosen by e.g. "forge".
+	Versions GameVersions `json:"versions" yaml:"versions" xml:"versions" form:"versions"` // Versions of the game a server may choose with the modloader.
+	Mods     bool         `json:"mods" yaml:"mods" xml:"mods" form:"mods"`                 // Whether mods can be added to servers with the modloader.
+}
@@ -0,0 +1,362 @@
+package world
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/RicochetStudios/aurora/engine"
+	"github.com/RicochetStudios/aurora/jobs"
+	"github.com/RicochetStudios/aurora/lifecycle"
+	"github.com/RicochetStudios/aurora/types"
+)
+
+const (
+	// FormatZip is the format of zip archives, as single player worlds are usually shared.
+	FormatZip string = "zip"
+
+	// FormatTarGz is the format of gzipped tar archives.
+	FormatTarGz string = "tar.gz"
+)
+
+// ErrTooLarge is returned for archives which would extract to more than their limit.
+var ErrTooLarge error = errors.New("world is larger than the limit")
+
+// ignoredDir is a directory of metadata macOS adds to zip archives, which is not part of the world.
+const ignoredDir string = "__MACOSX"
+
+// Archive is an uploaded world, spooled to a temporary file and checked before it is extracted into a server.
+type Archive struct {
+	Format string // Format of the archive, FormatZip or FormatTarGz.
+	Size   int64  // Bytes the world extracts to.
+	file   *os.File
+	prefix string // Directory every entry is inside, which is left out when extracting.
+}
+
+// entry is a file or directory of an archive.
+type entry struct {
+	name    string // Clean path of the entry, relative to the root of the archive.
+	dir     bool
+	size    int64
+	modTime time.Time
+}
+
+// Read spools a zip or gzipped tar archive of a world to a temporary file, checking it could be extracted safely.
+// Only files and directories are allowed, none of which may leave the world directory, and together they may
+// extract to at most limit bytes. A directory holding every entry, as single player worlds are usually archived
+// in, is left out. The archive must be closed to remove its file.
+func Read(r io.Reader, limit int64) (*Archive, error) {
+	file, err := os.CreateTemp("", "aurora-world-*")
+	if err != nil {
+		return nil, fmt.Errorf("Read() error creating temporary file: %v", err)
+	}
+	archive := &Archive{file: file}
+
+	if err := archive.read(r, limit); err != nil {
+		archive.Close()
+		return nil, fmt.Errorf("Read() %w", err)
+	}
+	return archive, nil
+}
+
+// read spools an archive to the file of the archive, checks its entries and finds the directory holding them.
+func (a *Archive) read(r io.Reader, limit int64) error {
+	if _, err := io.Copy(a.file, r); err != nil {
+		return fmt.Errorf("error reading archive: %v", err)
+	}
+
+	magic := make([]byte, 4)
+	if _, err := a.file.ReadAt(magic, 0); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("error reading archive: %v", err)
+	}
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")):
+		a.Format = FormatZip
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		a.Format = FormatTarGz
+	default:
+		return errors.New("archive must be a zip or tar.gz archive")
+	}
+
+	tops := map[string]bool{}
+	var topFile bool
+	var files int
+	err := a.walk(func(e entry, content io.Reader) error {
+		if a.Size += e.size; a.Size > limit {
+			return fmt.Errorf("%w of %v bytes", ErrTooLarge, limit)
+		}
+		top, rest, _ := strings.Cut(e.name, "/")
+		tops[top] = true
+		if !e.dir {
+			files++
+			topFile = topFile || rest == ""
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if files == 0 {
+		return errors.New("archive holds no files")
+	}
+
+	if len(tops) == 1 && !topFile {
+		for top := range tops {
+			a.prefix = top
+		}
+	}
+	return nil
+}
+
+// WriteTar writes the world as a tar archive, to be extracted into the world directory of a server.
+func (a *Archive) WriteTar(w io.Writer) error {
+	tw := tar.NewWriter(w)
+	err := a.walk(func(e entry, content io.Reader) error {
+		name := e.name
+		if a.prefix != "" {
+			name = strings.TrimPrefix(strings.TrimPrefix(name, a.prefix), "/")
+		}
+		if name == "" {
+			return nil
+		}
+
+		header := &tar.Header{Name: name, Mode: 0644, Size: e.size, ModTime: e.modTime, Typeflag: tar.TypeReg}
+		if e.dir {
+			header = &tar.Header{Name: name + "/", Mode: 0755, ModTime: e.modTime, Typeflag: tar.TypeDir}
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("error writing archive header: %v", err)
+		}
+		if e.dir {
+			return nil
+		}
+		// Sizes recorded in zip archives may not match their content, so exactly the checked size is copied.
+		if _, err := io.CopyN(tw, content, e.size); err != nil {
+			return fmt.Errorf("error writing %v: %v", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("WriteTar() %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("WriteTar() error closing archive: %v", err)
+	}
+	return nil
+}
+
+// Close removes the temporary file of the archive.
+func (a *Archive) Close() error {
+	a.file.Close()
+	return os.Remove(a.file.Name())
+}
+
+// walk visits every entry of the archive with its content, in the order they are archived.
+// It returns an error for entries which are not files or directories, or would leave the world directory.
+func (a *Archive) walk(visit func(e entry, content io.Reader) error) error {
+	if _, err := a.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("error reading archive: %v", err)
+	}
+
+	if a.Format == FormatZip {
+		info, err := a.file.Stat()
+		if err != nil {
+			return fmt.Errorf("error reading archive: %v", err)
+		}
+		reader, err := zip.NewReader(a.file, info.Size())
+		if err != nil {
+			return fmt.Errorf("error reading archive: %v", err)
+		}
+		for _, file := range reader.File {
+			mode := file.Mode()
+			if !mode.IsDir() && !mode.IsRegular() {
+				return fmt.Errorf("%v is not a file or directory", file.Name)
+			}
+			e, ok, err := newEntry(file.Name, mode.IsDir(), int64(file.UncompressedSize64), file.Modified)
+			if err != nil {
+				return err
+			} else if !ok {
+				continue
+			}
+
+			content, err := file.Open()
+			if err != nil {
+				return fmt.Errorf("error opening %v: %v", file.Name, err)
+			}
+			err = visit(e, content)
+			content.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	gz, err := gzip.NewReader(a.file)
+	if err != nil {
+		return fmt.Errorf("error reading archive: %v", err)
+	}
+	defer gz.Close()
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("error reading archive: %v", err)
+		}
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeDir {
+			return fmt.Errorf("%v is not a file or directory", header.Name)
+		}
+		e, ok, err := newEntry(header.Name, header.Typeflag == tar.TypeDir, header.Size, header.ModTime)
+		if err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		if err := visit(e, reader); err != nil {
+			return err
+		}
+	}
+}
+
+// newEntry returns the entry of an archive with a name, and whether it is part of the world.
+// It returns an error if the entry would leave the world directory.
+func newEntry(name string, dir bool, size int64, modTime time.Time) (entry, bool, error) {
+	clean := path.Clean(strings.TrimPrefix(name, "./"))
+	if strings.Contains(name, "\\") || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return entry{}, false, fmt.Errorf("path %q is outside the world directory", name)
+	}
+	if clean == "." || clean == ignoredDir || strings.HasPrefix(clean, ignoredDir+"/") {
+		return entry{}, false, nil
+	}
+	if dir {
+		size = 0
+	}
+	return entry{name: clean, dir: dir, size: size, modTime: modTime}, true, nil
+}
+
+// Upload returns the steps which replace the world directory of an instance with the world of an archive.
+// The server is stopped first, so it can not save over the new world, and started again if it was running.
+// The previous world is deleted once the new one has been extracted, so can not be restored.
+func Upload(runtime engine.Runtime, id string, dir string, archive *Archive) []jobs.Step {
+	var wasRunning bool
+
+	return []jobs.Step{
+		{
+			Name: "stopping",
+			Do: func(ctx context.Context) error {
+				status, err := runtime.Status(ctx, id)
+				if err != nil {
+					return err
+				}
+				wasRunning = status.State == types.StateRunning || status.State == types.StateStarting
+				if !wasRunning {
+					return nil
+				}
+				return runtime.Stop(ctx, id)
+			},
+			Undo: func(ctx context.Context) error {
+				if !wasRunning {
+					return nil
+				}
+				return runtime.Start(ctx, id)
+			},
+		},
+		{
+			Name: "uploading",
+			Do: func(ctx context.Context) error {
+				reader, writer := io.Pipe()
+				go func() {
+					writer.CloseWithError(archive.WriteTar(writer))
+				}()
+				// Closing the reader stops the writer, if the runtime gives up before reading all of it.
+				defer reader.Close()
+				return runtime.Replace(ctx, id, dir, reader)
+			},
+		},
+		{
+			Name: "starting",
+			Do: func(ctx context.Context) error {
+				if !wasRunning {
+					return nil
+				}
+				return runtime.Start(ctx, id)
+			},
+		},
+		{
+			Name: "waiting for healthy",
+			Do: func(ctx context.Context) error {
+				if !wasRunning {
+					return nil
+				}
+				return lifecycle.WaitHealthy(ctx, runtime, id)
+			},
+		},
+	}
+}
+
+// Export converts a tar archive of a world directory, as copied from a workload, to an archive of a format.
+// The world directory is kept as the top level directory of the archive.
+func Export(archive io.Reader, w io.Writer, format string) error {
+	switch format {
+	case FormatTarGz:
+		gz := gzip.NewWriter(w)
+		if _, err := io.Copy(gz, archive); err != nil {
+			return fmt.Errorf("Export() error compressing archive: %v", err)
+		}
+		if err := gz.Close(); err != nil {
+			return fmt.Errorf("Export() error closing archive: %v", err)
+		}
+		return nil
+	case FormatZip:
+	default:
+		return fmt.Errorf("Export() format %q is not %v or %v", format, FormatZip, FormatTarGz)
+	}
+
+	zw := zip.NewWriter(w)
+	reader := tar.NewReader(archive)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("Export() error reading archive: %v", err)
+		}
+
+		// Links and other special files have no place in a zip archive.
+		var out io.Writer
+		switch header.Typeflag {
+		case tar.TypeDir:
+			out, err = zw.CreateHeader(&zip.FileHeader{Name: strings.TrimSuffix(header.Name, "/") + "/", Modified: header.ModTime})
+		case tar.TypeReg:
+			out, err = zw.CreateHeader(&zip.FileHeader{Name: header.Name, Method: zip.Deflate, Modified: header.ModTime})
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("Export() error writing %v: %v", header.Name, err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := io.Copy(out, reader); err != nil {
+				return fmt.Errorf("Export() error writing %v: %v", header.Name, err)
+			}
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("Export() error closing archive: %v", err)
+	}
+	return nil
+}
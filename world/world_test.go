@@ -0,0 +1,236 @@
+package world
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/RicochetStudios/aurora/engine/enginetest"
+	"github.com/RicochetStudios/aurora/jobs"
+	"github.com/RicochetStudios/aurora/schema"
+	"github.com/RicochetStudios/aurora/types"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// zipArchive returns a zip archive of files, by name, in order of their names.
+func zipArchive(t *testing.T, names []string, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zipArchive() error creating %v: %v", name, err)
+		}
+		if _, err := w.Write([]byte(files[name])); err != nil {
+			t.Fatalf("zipArchive() error writing %v: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zipArchive() error closing: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// tarGzArchive returns a gzipped tar archive of headers, with content for the regular files.
+func tarGzArchive(t *testing.T, headers []*tar.Header, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, header := range headers {
+		header.Size = int64(len(files[header.Name]))
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("tarGzArchive() error writing header %v: %v", header.Name, err)
+		}
+		if _, err := tw.Write([]byte(files[header.Name])); err != nil {
+			t.Fatalf("tarGzArchive() error writing %v: %v", header.Name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tarGzArchive() error closing: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("tarGzArchive() error closing: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// extracted reads a world archive the way it would be extracted into a server, as contents by path.
+func extracted(t *testing.T, archive *Archive) map[string]string {
+	var buf bytes.Buffer
+	if err := archive.WriteTar(&buf); err != nil {
+		t.Fatalf("WriteTar() returned an error: \n%v", err)
+	}
+
+	files := map[string]string{}
+	reader := tar.NewReader(&buf)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return files
+		} else if err != nil {
+			t.Fatalf("WriteTar() wrote an invalid archive: %v", err)
+		}
+		content, _ := io.ReadAll(reader)
+		files[header.Name] = string(content)
+	}
+}
+
+// TestRead calls Read with archives of single player worlds,
+// checking the directory holding the world and macOS metadata are left out.
+func TestRead(t *testing.T) {
+	files := map[string]string{
+		"My World/level.dat":            "level",
+		"My World/region/r.0.0.mca":     "region",
+		"__MACOSX/My World/._level.dat": "metadata",
+	}
+	tests := map[string][]byte{
+		FormatZip: zipArchive(t, []string{"My World/level.dat", "My World/region/r.0.0.mca", "__MACOSX/My World/._level.dat"}, files),
+		FormatTarGz: tarGzArchive(t, []*tar.Header{
+			{Name: "./My World/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "My World/level.dat", Typeflag: tar.TypeReg, Mode: 0644},
+			{Name: "My World/region/r.0.0.mca", Typeflag: tar.TypeReg, Mode: 0644},
+		}, files),
+	}
+
+	for format, content := range tests {
+		archive, err := Read(bytes.NewReader(content), 1024)
+		if err != nil {
+			t.Fatalf("Read() (%v) returned an error: \n%v", format, err)
+		}
+		defer archive.Close()
+
+		if archive.Format != format || archive.Size != 11 {
+			t.Fatalf("Read() (%v) = %v archive of %v bytes, want %v of 11 bytes", format, archive.Format, archive.Size, format)
+		}
+		want := map[string]string{"level.dat": "level", "region/r.0.0.mca": "region"}
+		if diff := cmp.Diff(want, extracted(t, archive)); diff != "" {
+			t.Fatalf("WriteTar() (%v) mismatch (-want +got):\n%s", format, diff)
+		}
+	}
+}
+
+// TestReadRoot calls Read with an archive of the files of a world without a directory holding them,
+// checking they are kept as they are.
+func TestReadRoot(t *testing.T) {
+	content := zipArchive(t, []string{"level.dat", "region/r.0.0.mca"}, map[string]string{"level.dat": "level", "region/r.0.0.mca": "region"})
+	archive, err := Read(bytes.NewReader(content), 1024)
+	if err != nil {
+		t.Fatalf("Read() returned an error: \n%v", err)
+	}
+	defer archive.Close()
+
+	want := map[string]string{"level.dat": "level", "region/r.0.0.mca": "region"}
+	if diff := cmp.Diff(want, extracted(t, archive)); diff != "" {
+		t.Fatalf("WriteTar() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestReadInvalid calls Read with archives which are not worlds, are too large or would write outside the world,
+// checking each is rejected and its temporary file is removed.
+func TestReadInvalid(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	tests := map[string][]byte{
+		"not an archive": []byte("level"),
+		"empty":          zipArchive(t, nil, nil),
+		"too large":      zipArchive(t, []string{"level.dat"}, map[string]string{"level.dat": strings.Repeat("x", 2048)}),
+		"escaping":       zipArchive(t, []string{"../level.dat"}, map[string]string{"../level.dat": "level"}),
+		"absolute": tarGzArchive(t, []*tar.Header{
+			{Name: "/etc/passwd", Typeflag: tar.TypeReg, Mode: 0644},
+		}, nil),
+		"symlink": tarGzArchive(t, []*tar.Header{
+			{Name: "world/level.dat", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+		}, nil),
+	}
+
+	for name, content := range tests {
+		if archive, err := Read(bytes.NewReader(content), 1024); err == nil {
+			archive.Close()
+			t.Fatalf("Read() (%v) expected an error, got %v", name, err)
+		}
+	}
+	if _, err := Read(bytes.NewReader(tests["too large"]), 1024); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("Read() of a large world returned %v, want %v", err, ErrTooLarge)
+	}
+
+	if left, _ := os.ReadDir(tmp); len(left) != 0 {
+		t.Fatalf("Read() left %v temporary files behind", len(left))
+	}
+}
+
+// TestUpload calls Upload on a running server,
+// checking the world is replaced while it is stopped, the rest of the data volume is kept and it is started again.
+func TestUpload(t *testing.T) {
+	ctx := context.Background()
+	runtime := enginetest.NewFake()
+	if err := runtime.Deploy(ctx, "00000001", schema.Schema{}, types.Server{Name: "myserver"}); err != nil {
+		t.Fatalf("Deploy() returned an error: \n%v", err)
+	}
+	runtime.WriteFile("00000001", "/data/world/level.dat", []byte("old"))
+	runtime.WriteFile("00000001", "/data/world/region/r.0.0.mca", []byte("old"))
+	runtime.WriteFile("00000001", "/data/server.properties", []byte("properties"))
+	runtime.Calls()
+
+	archive, err := Read(bytes.NewReader(zipArchive(t, []string{"world/level.dat"}, map[string]string{"world/level.dat": "new"})), 1024)
+	if err != nil {
+		t.Fatalf("Read() returned an error: \n%v", err)
+	}
+	defer archive.Close()
+
+	if err := jobs.RunSteps(ctx, Upload(runtime, "00000001", "/data/world", archive)); err != nil {
+		t.Fatalf("Upload() returned an error: \n%v", err)
+	}
+
+	if diff := cmp.Diff([]string{"Stop 00000001", "Replace 00000001", "Start 00000001"}, runtime.Calls()); diff != "" {
+		t.Fatalf("Upload() calls mismatch (-want +got):\n%s", diff)
+	}
+	if content, _ := runtime.File("00000001", "/data/world/level.dat"); string(content) != "new" {
+		t.Fatalf("Upload() level.dat = %q, want the uploaded world", content)
+	}
+	if _, ok := runtime.File("00000001", "/data/world/region/r.0.0.mca"); ok {
+		t.Fatalf("Upload() kept a file of the previous world")
+	}
+	if _, ok := runtime.File("00000001", "/data/server.properties"); !ok {
+		t.Fatalf("Upload() deleted a file outside the world")
+	}
+	if _, running, _ := runtime.Workload("00000001"); !running {
+		t.Fatalf("Upload() did not start the server again")
+	}
+}
+
+// TestExport calls Export with a world copied from a workload,
+// checking the zip archive keeps the world directory and its files.
+func TestExport(t *testing.T) {
+	runtime := enginetest.NewFake()
+	runtime.WriteFile("00000001", "/data/world/level.dat", []byte("level"))
+	copied, err := runtime.CopyFrom(context.Background(), "00000001", "/data/world")
+	if err != nil {
+		t.Fatalf("CopyFrom() returned an error: \n%v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Export(copied, &buf, FormatZip); err != nil {
+		t.Fatalf("Export() returned an error: \n%v", err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Export() wrote an invalid archive: %v", err)
+	}
+	if len(reader.File) != 1 || reader.File[0].Name != "world/level.dat" {
+		t.Fatalf("Export() archived %v files, want world/level.dat", len(reader.File))
+	}
+
+	if err := Export(strings.NewReader(""), io.Discard, "rar"); err == nil {
+		t.Fatalf("Export() of an unknown format expected an error, got %v", err)
+	}
+}